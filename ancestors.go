@@ -0,0 +1,99 @@
+package location
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// Ancestry is the geo level of a location with the geo_ids of all its ancestors
+type Ancestry struct {
+	GeoID     string   `json:"geo_id"`
+	GeoLevel  string   `json:"geo_level"`
+	Ancestors []string `json:"ancestors"` // nearest first
+}
+
+// CommonAncestor returns the lowest location containing all the given locations: their
+// nearest shared ancestor, or one of them if it contains the others. With a geo level, the
+// nearest shared ancestor of that level is returned instead, e.g. the STATE of two districts.
+func (service *ServiceOnPostgres) CommonAncestor(ctx context.Context, geoLevel *string, geoIDs ...string) (*Location, error) {
+	ids := make([]uuid.UUID, 0, len(geoIDs))
+	for _, geoID := range geoIDs {
+		id, err := uuidFromString(geoID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	ancestor, err := service.db.CommonAncestor(ctx, ids, geoLevel)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetLocation(ctx, ancestor.Id.String())
+}
+
+// TreeDistance returns the number of relations on the shortest path between two locations
+// through a common ancestor: 0 for the same location, 1 for a parent and its child and
+// 2 for siblings
+func (service *ServiceOnPostgres) TreeDistance(ctx context.Context, geoID string, otherGeoID string) (int, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return 0, err
+	}
+	otherID, err := uuidFromString(otherGeoID)
+	if err != nil {
+		return 0, err
+	}
+	return service.db.TreeDistance(ctx, id, otherID)
+}
+
+// GetAncestry returns the geo level and the ancestors of each location, by the geo_id as
+// given, in two queries whatever the number of locations. Invalid and unknown geo_ids are
+// left out.
+func (service *ServiceOnPostgres) GetAncestry(ctx context.Context, geoIDs []string) (map[string]Ancestry, error) {
+	ids := make([]uuid.UUID, 0, len(geoIDs))
+	for _, geoID := range geoIDs {
+		if id, err := uuid.Parse(geoID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	locations, err := service.db.FindLocations(ctx, postgres.LocationQuery{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	depths, err := service.db.AncestorDepths(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]Ancestry, len(locations))
+	for _, loc := range locations {
+		ancestorIDs := make([]uuid.UUID, 0, len(depths[loc.Id]))
+		for ancestorID := range depths[loc.Id] {
+			if ancestorID != loc.Id {
+				ancestorIDs = append(ancestorIDs, ancestorID)
+			}
+		}
+		slices.SortFunc(ancestorIDs, func(a, b uuid.UUID) int {
+			return cmp.Or(cmp.Compare(depths[loc.Id][a], depths[loc.Id][b]), cmp.Compare(a.String(), b.String()))
+		})
+		ancestry := Ancestry{GeoID: loc.Id.String(), GeoLevel: loc.GeoLevel.Name, Ancestors: make([]string, 0, len(ancestorIDs))}
+		for _, ancestorID := range ancestorIDs {
+			ancestry.Ancestors = append(ancestry.Ancestors, ancestorID.String())
+		}
+		byID[loc.Id] = ancestry
+	}
+
+	out := make(map[string]Ancestry, len(byID))
+	for _, geoID := range geoIDs {
+		if id, err := uuid.Parse(geoID); err == nil {
+			if ancestry, ok := byID[id]; ok {
+				out[geoID] = ancestry
+			}
+		}
+	}
+	return out, nil
+}
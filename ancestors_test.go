@@ -0,0 +1,59 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_CommonAncestor(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	kakkanad := createTestLocation(t, service, "CITY", "Kakkanad")
+	require.NoError(t, service.AddParent(ctx, kakkanad.GeoID, locs["Ernakulam"].GeoID))
+	thrissur := createTestLocation(t, service, "DISTRICT", "Thrissur")
+	require.NoError(t, service.AddParent(ctx, thrissur.GeoID, locs["Kerala"].GeoID))
+
+	ancestor, err := service.CommonAncestor(ctx, nil, locs["Kochi"].GeoID, kakkanad.GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, locs["Ernakulam"].GeoID, ancestor.GeoID)
+	assert.Equal(t, "DISTRICT", ancestor.GeoLevel)
+	assert.Equal(t, "Ernakulam", ancestor.Name)
+
+	ancestor, err = service.CommonAncestor(ctx, stringPtr("COUNTRY"), locs["Kochi"].GeoID, thrissur.GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, locs["India"].GeoID, ancestor.GeoID)
+
+	distance, err := service.TreeDistance(ctx, locs["Kochi"].GeoID, thrissur.GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, distance)
+
+	lonely := createTestLocation(t, service, "CITY", "Atlantis")
+	_, err = service.CommonAncestor(ctx, nil, locs["Kochi"].GeoID, lonely.GeoID)
+	assert.ErrorIs(t, err, postgres.ErrNoCommonAncestor)
+	_, err = service.TreeDistance(ctx, "not-a-uuid", lonely.GeoID)
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+}
+
+func TestServiceOnPostgres_GetAncestry(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	lonely := createTestLocation(t, service, "CITY", "Atlantis")
+
+	ancestry, err := service.GetAncestry(ctx, []string{locs["Kochi"].GeoID, lonely.GeoID, "not-a-uuid", uuid.NewString()})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Ancestry{
+		locs["Kochi"].GeoID: {
+			GeoID:     locs["Kochi"].GeoID,
+			GeoLevel:  "CITY",
+			Ancestors: []string{locs["Ernakulam"].GeoID, locs["Kerala"].GeoID, locs["India"].GeoID},
+		},
+		lonely.GeoID: {GeoID: lonely.GeoID, GeoLevel: "CITY", Ancestors: []string{}},
+	}, ancestry)
+}
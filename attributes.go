@@ -0,0 +1,135 @@
+package location
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// AttributeOp is the comparison of an attribute filter
+type AttributeOp string
+
+const (
+	AttributeEq     AttributeOp = "="
+	AttributeNe     AttributeOp = "!="
+	AttributeGt     AttributeOp = ">"
+	AttributeGte    AttributeOp = ">="
+	AttributeLt     AttributeOp = "<"
+	AttributeLte    AttributeOp = "<="
+	AttributeExists AttributeOp = "exists"
+)
+
+// AttributeFilter keeps the locations whose attribute compares to the value. Only values
+// of the same JSON type compare: a number attribute is never greater than a string.
+// Locations without the attribute pass no filter.
+type AttributeFilter struct {
+	Key   string
+	Op    AttributeOp
+	Value any // unused by AttributeExists
+}
+
+// ParseAttributeFilter parses a filter such as "population > 1e6", "serviceable = true",
+// `currency = "INR"` or "timezone exists". Values are JSON, or strings if they are not.
+func ParseAttributeFilter(expr string) (AttributeFilter, error) {
+	filter, err := postgres.ParseAttributeFilter(expr)
+	if err != nil {
+		return AttributeFilter{}, err
+	}
+	return AttributeFilter{Key: filter.Key, Op: AttributeOp(filter.Op), Value: filter.Value}, nil
+}
+
+// ListOptions selects the locations returned by ListLocations
+type ListOptions struct {
+	GeoLevel *string // only locations of this geo level, all if nil
+	// Attributes keeps the locations passing all the filters, see ParseAttributeFilter
+	Attributes []AttributeFilter
+}
+
+// ListLocations returns the live locations selected by the options, oldest first. Attribute
+// filters are checked on the database: equality uses the index of all attributes, and range
+// filters use the index of their attribute if IndexAttribute created it.
+func (service *ServiceOnPostgres) ListLocations(ctx context.Context, opts ListOptions) ([]Location, error) {
+	found, err := service.db.FindLocations(ctx, postgres.LocationQuery{
+		GeoLevelName: opts.GeoLevel,
+		Attributes:   toStoreFilters(opts.Attributes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, 0, len(found))
+	for _, loc := range found {
+		ids = append(ids, loc.Id)
+	}
+	named, err := service.db.GetLocationsWithNames(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	locs := make([]Location, 0, len(found))
+	for _, id := range ids {
+		if loc, ok := named[id]; ok {
+			locs = append(locs, fromLocationWithNames(loc))
+		}
+	}
+	return locs, nil
+}
+
+// GetAttributes returns the attributes of a location, empty if it has none
+func (service *ServiceOnPostgres) GetAttributes(ctx context.Context, geoID string) (map[string]any, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := service.db.GetAttributes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// SetAttributes replaces all attributes of a location and returns them. Keys must be
+// lowercase identifiers such as "census_code" (see postgres.ValidateAttributeKey).
+func (service *ServiceOnPostgres) SetAttributes(ctx context.Context, geoID string, attributes map[string]any) (map[string]any, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		out, err = store.SetAttributes(ctx, id, attributes)
+		return err
+	})
+	return out, err
+}
+
+// PatchAttributes merges the patch into the attributes of a location and returns the result.
+// A nil value removes an attribute and nested objects are merged (JSON merge patch, RFC 7396).
+func (service *ServiceOnPostgres) PatchAttributes(ctx context.Context, geoID string, patch map[string]any) (map[string]any, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		out, err = store.PatchAttributes(ctx, id, patch)
+		return err
+	})
+	return out, err
+}
+
+// IndexAttribute indexes an attribute for the range filters of ListLocations and SearchLocations.
+// The index is built without blocking writes, outside of any transaction (not within InTx).
+func (service *ServiceOnPostgres) IndexAttribute(ctx context.Context, key string) error {
+	return service.db.IndexAttribute(ctx, key)
+}
+
+func toStoreFilters(filters []AttributeFilter) []postgres.AttributeFilter {
+	if filters == nil {
+		return nil
+	}
+	out := make([]postgres.AttributeFilter, 0, len(filters))
+	for _, filter := range filters {
+		out = append(out, postgres.AttributeFilter{Key: filter.Key, Op: postgres.AttributeOp(filter.Op), Value: filter.Value})
+	}
+	return out
+}
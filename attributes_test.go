@@ -0,0 +1,82 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_Attributes(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	kochi := locs["Kochi"].GeoID
+
+	attributes, err := service.SetAttributes(ctx, kochi, map[string]any{"population": 2.1e6, "timezone": "Asia/Kolkata"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"population": 2.1e6, "timezone": "Asia/Kolkata"}, attributes)
+
+	attributes, err = service.PatchAttributes(ctx, kochi, map[string]any{"timezone": nil, "serviceable": true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"population": 2.1e6, "serviceable": true}, attributes)
+
+	loc, err := service.GetLocation(ctx, kochi)
+	require.NoError(t, err)
+	assert.Equal(t, attributes, loc.Attributes)
+	stored, err := service.GetAttributes(ctx, kochi)
+	require.NoError(t, err)
+	assert.Equal(t, attributes, stored)
+
+	_, err = service.SetAttributes(ctx, kochi, map[string]any{"Population": 1})
+	assert.ErrorIs(t, err, postgres.ErrInvalidAttributeKey)
+	_, err = service.GetAttributes(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+}
+
+func TestServiceOnPostgres_ListLocations(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	_, err := service.SetAttributes(ctx, locs["Kochi"].GeoID, map[string]any{"population": 2.1e6})
+	require.NoError(t, err)
+	_, err = service.SetAttributes(ctx, locs["Ernakulam"].GeoID, map[string]any{"population": 3.3e6})
+	require.NoError(t, err)
+	_, err = service.SetAttributes(ctx, locs["Kerala"].GeoID, map[string]any{"population": 3.5e7})
+	require.NoError(t, err)
+	require.NoError(t, service.IndexAttribute(ctx, "population"))
+	names := func(listed []Location) []string {
+		out := []string{}
+		for _, loc := range listed {
+			out = append(out, loc.Name)
+		}
+		return out
+	}
+
+	filter, err := ParseAttributeFilter("population > 1e6")
+	require.NoError(t, err)
+	below, err := ParseAttributeFilter("population < 1e7")
+	require.NoError(t, err)
+
+	listed, err := service.ListLocations(ctx, ListOptions{Attributes: []AttributeFilter{filter, below}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Ernakulam", "Kochi"}, names(listed))
+
+	listed, err = service.ListLocations(ctx, ListOptions{GeoLevel: stringPtr("CITY"), Attributes: []AttributeFilter{filter}})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, map[string]any{"population": 2.1e6}, listed[0].Attributes)
+
+	listed, err = service.ListLocations(ctx, ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, listed, 4)
+
+	results, err := service.SearchLocations(ctx, "k", SearchOptions{Attributes: []AttributeFilter{filter, below}})
+	require.NoError(t, err)
+	require.Len(t, results, 2, "Kerala is above the range")
+	assert.ElementsMatch(t, []string{"Ernakulam", "Kochi"}, []string{results[0].Name, results[1].Name})
+
+	assert.ErrorIs(t, service.IndexAttribute(ctx, "bad key"), postgres.ErrInvalidAttributeKey)
+}
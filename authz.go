@@ -0,0 +1,858 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// ErrPermissionDenied is returned when the policy denies the principal a call
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Access is the kind of access a call needs
+type Access string
+
+const (
+	AccessRead  Access = "read"
+	AccessWrite Access = "write"
+)
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the identity of the caller for AuthorizedService
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by the context, or "" if none
+func PrincipalFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if principal, ok := ctx.Value(principalKey{}).(string); ok {
+			return principal
+		}
+	}
+	return ""
+}
+
+// Resource is a location affected by a call
+type Resource struct {
+	// GeoID is empty for a location being created and for a geo level
+	GeoID    string
+	GeoLevel string
+	// Ancestors are the geo_ids of all ancestors of the location, for a location being created
+	// those of its parent and the parent itself
+	Ancestors []string
+}
+
+// AuthorizationRequest describes a call to authorize
+type AuthorizationRequest struct {
+	Principal string
+	Method    string
+	Access    Access
+	// Resources are the affected locations or geo levels; empty for calls on the whole hierarchy
+	Resources []Resource
+}
+
+// Policy decides whether a call is allowed. It returns nil to allow the call, or an error
+// wrapping ErrPermissionDenied to deny it.
+type Policy interface {
+	Authorize(ctx context.Context, request AuthorizationRequest) error
+}
+
+// Grant gives a principal access to the subtree of a location, optionally restricted
+// to some geo levels. Write access includes read access.
+type Grant struct {
+	Principal string
+	Access    Access
+	// RootGeoID is the root of the granted subtree, including the root itself;
+	// empty grants the whole hierarchy
+	RootGeoID string
+	// GeoLevels restricts the grant to locations of these geo levels; empty allows all
+	GeoLevels []string
+}
+
+// GrantPolicy is a Policy allowing a call if every affected resource is covered by a grant
+// of the principal with enough access. Resources without a geo_id or ancestors (new locations
+// without a parent and geo levels) and calls on the whole hierarchy are covered only by grants
+// of the whole hierarchy.
+type GrantPolicy struct {
+	Grants []Grant
+}
+
+// Authorize implements Policy
+func (policy GrantPolicy) Authorize(_ context.Context, request AuthorizationRequest) error {
+	if len(request.Resources) == 0 {
+		for _, grant := range policy.Grants {
+			if grant.allows(request.Principal, request.Access) && grant.RootGeoID == "" && len(grant.GeoLevels) == 0 {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s cannot %s the hierarchy", ErrPermissionDenied, request.Principal, request.Method)
+	}
+
+	for _, resource := range request.Resources {
+		covered := slices.ContainsFunc(policy.Grants, func(grant Grant) bool {
+			return grant.allows(request.Principal, request.Access) && grant.covers(resource)
+		})
+		if !covered {
+			target := resource.GeoID
+			if target == "" {
+				target = resource.GeoLevel
+			}
+			return fmt.Errorf("%w: %s cannot %s %s", ErrPermissionDenied, request.Principal, request.Method, target)
+		}
+	}
+	return nil
+}
+
+func (grant Grant) allows(principal string, access Access) bool {
+	return grant.Principal == principal && (grant.Access == access || grant.Access == AccessWrite)
+}
+
+func (grant Grant) covers(resource Resource) bool {
+	if len(grant.GeoLevels) > 0 && !slices.ContainsFunc(grant.GeoLevels, sameAs(resource.GeoLevel)) {
+		return false
+	}
+	if grant.RootGeoID == "" {
+		return true
+	}
+	return (resource.GeoID != "" && strings.EqualFold(resource.GeoID, grant.RootGeoID)) ||
+		slices.ContainsFunc(resource.Ancestors, sameAs(grant.RootGeoID))
+}
+
+// sameAs returns a case-insensitive match of want, for geo_ids and geo level names
+func sameAs(want string) func(string) bool {
+	return func(got string) bool { return strings.EqualFold(got, want) }
+}
+
+// AuthorizedService is a LocationService asking a policy before every call of the wrapped
+// service, for the principal of the call context and the affected locations with their
+// ancestors. Denied calls return an error wrapping ErrPermissionDenied. Searches return
+// only the locations the principal can read.
+type AuthorizedService struct {
+	next   LocationService
+	policy Policy
+}
+
+var _ LocationService = (*AuthorizedService)(nil)
+
+// NewAuthorizedService wraps the service with the authorization policy
+func NewAuthorizedService(next LocationService, policy Policy) *AuthorizedService {
+	return &AuthorizedService{next: next, policy: policy}
+}
+
+// authorize asks the policy for the call on the given resources
+func (service *AuthorizedService) authorize(ctx context.Context, method string, access Access, resources ...Resource) error {
+	return service.policy.Authorize(ctx, AuthorizationRequest{
+		Principal: PrincipalFromContext(ctx),
+		Method:    method,
+		Access:    access,
+		Resources: resources,
+	})
+}
+
+// authorizeLocations asks the policy for the call on the given locations
+func (service *AuthorizedService) authorizeLocations(ctx context.Context, method string, access Access, geoIDs ...string) error {
+	resources, err := service.resources(ctx, geoIDs...)
+	if err != nil {
+		return err
+	}
+	return service.authorize(ctx, method, access, resources...)
+}
+
+// resource resolves the geo level and ancestors of a location
+func (service *AuthorizedService) resource(ctx context.Context, geoID string) (Resource, error) {
+	resources, err := service.resources(ctx, geoID)
+	if err != nil {
+		return Resource{}, err
+	}
+	return resources[0], nil
+}
+
+// resources resolves the geo levels and ancestors of the locations in one call of the
+// wrapped service, in the order given. A location that cannot be found is returned with its
+// geo_id only, so that only grants of the whole hierarchy cover it.
+func (service *AuthorizedService) resources(ctx context.Context, geoIDs ...string) ([]Resource, error) {
+	ancestry, err := service.next.GetAncestry(ctx, geoIDs)
+	if err != nil {
+		return nil, err
+	}
+	return resourcesOf(geoIDs, ancestry), nil
+}
+
+func resourcesOf(geoIDs []string, ancestry map[string]Ancestry) []Resource {
+	resources := make([]Resource, 0, len(geoIDs))
+	for _, geoID := range geoIDs {
+		found, ok := ancestry[geoID]
+		if !ok {
+			resources = append(resources, Resource{GeoID: geoID})
+			continue
+		}
+		resources = append(resources, Resource{GeoID: found.GeoID, GeoLevel: found.GeoLevel, Ancestors: found.Ancestors})
+	}
+	return resources
+}
+
+// readable keeps the items of the locations the principal can read, resolving the
+// ancestors of all of them at once
+func readable[T any](ctx context.Context, service *AuthorizedService, method string, items []T, geoID func(T) string) ([]T, error) {
+	geoIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		geoIDs = append(geoIDs, geoID(item))
+	}
+	resources, err := service.resources(ctx, geoIDs...)
+	if err != nil {
+		return nil, err
+	}
+	return readableOf(ctx, service, method, items, resources)
+}
+
+// readableOf keeps the items whose resolved resources the principal can read
+func readableOf[T any](ctx context.Context, service *AuthorizedService, method string, items []T, resources []Resource) ([]T, error) {
+	out := make([]T, 0, len(items))
+	for i, item := range items {
+		err := service.authorize(ctx, method, AccessRead, resources[i])
+		if errors.Is(err, ErrPermissionDenied) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// InTx runs fn in a transaction of the wrapped service; the calls made through tx are authorized
+func (service *AuthorizedService) InTx(ctx context.Context, fn func(tx LocationService) error) error {
+	return service.next.InTx(ctx, func(tx LocationService) error {
+		return fn(&AuthorizedService{next: tx, policy: service.policy})
+	})
+}
+
+func (service *AuthorizedService) AddLocation(ctx context.Context, geoID string, geoLevel string, name string) (Location, error) {
+	if err := service.authorize(ctx, "AddLocation", AccessWrite, Resource{GeoLevel: geoLevel}); err != nil {
+		return Location{}, err
+	}
+	return service.next.AddLocation(ctx, geoID, geoLevel, name)
+}
+
+func (service *AuthorizedService) AddLocationWithAttributes(ctx context.Context, geoID string, geoLevel string, name string, attributes map[string]any) (Location, error) {
+	if err := service.authorize(ctx, "AddLocationWithAttributes", AccessWrite, Resource{GeoLevel: geoLevel}); err != nil {
+		return Location{}, err
+	}
+	return service.next.AddLocationWithAttributes(ctx, geoID, geoLevel, name, attributes)
+}
+
+// AddChildLocation needs access to the parent and to a location of the geo level below it
+func (service *AuthorizedService) AddChildLocation(ctx context.Context, parentGeoID string, geoLevel string, name string, attributes map[string]any) (Location, error) {
+	parent, err := service.resource(ctx, parentGeoID)
+	if err != nil {
+		return Location{}, err
+	}
+	child := Resource{GeoLevel: geoLevel, Ancestors: append([]string{parent.GeoID}, parent.Ancestors...)}
+	if err := service.authorize(ctx, "AddChildLocation", AccessWrite, parent, child); err != nil {
+		return Location{}, err
+	}
+	return service.next.AddChildLocation(ctx, parentGeoID, geoLevel, name, attributes)
+}
+
+func (service *AuthorizedService) UpdateLocation(ctx context.Context, geoID string, name *string, geoLevel *string) (Location, error) {
+	resource, err := service.resource(ctx, geoID)
+	if err != nil {
+		return Location{}, err
+	}
+	resources := []Resource{resource}
+	if geoLevel != nil {
+		moved := resource
+		moved.GeoLevel = *geoLevel
+		resources = append(resources, moved)
+	}
+	if err := service.authorize(ctx, "UpdateLocation", AccessWrite, resources...); err != nil {
+		return Location{}, err
+	}
+	return service.next.UpdateLocation(ctx, geoID, name, geoLevel)
+}
+
+// Changes to geo levels and level rules redefine the hierarchy for every location and need a
+// global grant, whatever geo levels a grant is restricted to
+func (service *AuthorizedService) AddGeoLevel(ctx context.Context, name string, rank *float64) error {
+	if err := service.authorize(ctx, "AddGeoLevel", AccessWrite); err != nil {
+		return err
+	}
+	return service.next.AddGeoLevel(ctx, name, rank)
+}
+
+func (service *AuthorizedService) UpdateGeoLevel(ctx context.Context, name string, newName *string, newRank *float64) error {
+	if err := service.authorize(ctx, "UpdateGeoLevel", AccessWrite); err != nil {
+		return err
+	}
+	return service.next.UpdateGeoLevel(ctx, name, newName, newRank)
+}
+
+func (service *AuthorizedService) PreviewGeoLevelUpdate(ctx context.Context, name string, newRank *float64) ([]RelationViolation, error) {
+	if err := service.authorize(ctx, "PreviewGeoLevelUpdate", AccessRead, Resource{GeoLevel: name}); err != nil {
+		return nil, err
+	}
+	return service.next.PreviewGeoLevelUpdate(ctx, name, newRank)
+}
+
+func (service *AuthorizedService) PreviewLocationUpdate(ctx context.Context, geoID string, geoLevel string) ([]RelationViolation, error) {
+	if err := service.authorizeLocations(ctx, "PreviewLocationUpdate", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.PreviewLocationUpdate(ctx, geoID, geoLevel)
+}
+
+func (service *AuthorizedService) GetGeoLevel(ctx context.Context, name string) (*GeoLevel, error) {
+	if err := service.authorize(ctx, "GetGeoLevel", AccessRead, Resource{GeoLevel: name}); err != nil {
+		return nil, err
+	}
+	return service.next.GetGeoLevel(ctx, name)
+}
+
+func (service *AuthorizedService) ListGeoLevels(ctx context.Context) ([]GeoLevel, error) {
+	if err := service.authorize(ctx, "ListGeoLevels", AccessRead); err != nil {
+		return nil, err
+	}
+	return service.next.ListGeoLevels(ctx)
+}
+
+func (service *AuthorizedService) GetGeoLevelsByPattern(ctx context.Context, name string) ([]GeoLevel, error) {
+	if err := service.authorize(ctx, "GetGeoLevelsByPattern", AccessRead); err != nil {
+		return nil, err
+	}
+	return service.next.GetGeoLevelsByPattern(ctx, name)
+}
+
+func (service *AuthorizedService) DeleteGeoLevel(ctx context.Context, name string, reassignTo *string) error {
+	if err := service.authorize(ctx, "DeleteGeoLevel", AccessWrite); err != nil {
+		return err
+	}
+	return service.next.DeleteGeoLevel(ctx, name, reassignTo)
+}
+
+func (service *AuthorizedService) AddLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevels []string) ([]RelationViolation, error) {
+	if err := service.authorize(ctx, "AddLevelRule", AccessWrite); err != nil {
+		return nil, err
+	}
+	return service.next.AddLevelRule(ctx, childGeoLevel, parentGeoLevels)
+}
+
+func (service *AuthorizedService) RemoveLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevel string) error {
+	if err := service.authorize(ctx, "RemoveLevelRule", AccessWrite); err != nil {
+		return err
+	}
+	return service.next.RemoveLevelRule(ctx, childGeoLevel, parentGeoLevel)
+}
+
+func (service *AuthorizedService) ListLevelRules(ctx context.Context) ([]LevelRule, error) {
+	if err := service.authorize(ctx, "ListLevelRules", AccessRead); err != nil {
+		return nil, err
+	}
+	return service.next.ListLevelRules(ctx)
+}
+
+func (service *AuthorizedService) GetLevelRuleViolations(ctx context.Context) ([]RelationViolation, error) {
+	if err := service.authorize(ctx, "GetLevelRuleViolations", AccessRead); err != nil {
+		return nil, err
+	}
+	return service.next.GetLevelRuleViolations(ctx)
+}
+
+func (service *AuthorizedService) AddAliasToLocation(ctx context.Context, geoID string, name string) error {
+	if err := service.authorizeLocations(ctx, "AddAliasToLocation", AccessWrite, geoID); err != nil {
+		return err
+	}
+	return service.next.AddAliasToLocation(ctx, geoID, name)
+}
+
+func (service *AuthorizedService) RemoveAlias(ctx context.Context, geoID string, name string) error {
+	if err := service.authorizeLocations(ctx, "RemoveAlias", AccessWrite, geoID); err != nil {
+		return err
+	}
+	return service.next.RemoveAlias(ctx, geoID, name)
+}
+
+func (service *AuthorizedService) AddParent(ctx context.Context, geoID string, parentGeoID string) error {
+	if err := service.authorizeLocations(ctx, "AddParent", AccessWrite, geoID, parentGeoID); err != nil {
+		return err
+	}
+	return service.next.AddParent(ctx, geoID, parentGeoID)
+}
+
+func (service *AuthorizedService) RemoveParent(ctx context.Context, geoID string, parentGeoID string) error {
+	if err := service.authorizeLocations(ctx, "RemoveParent", AccessWrite, geoID, parentGeoID); err != nil {
+		return err
+	}
+	return service.next.RemoveParent(ctx, geoID, parentGeoID)
+}
+
+// MoveLocation needs write access to the location, its new parent and the parent it replaces
+func (service *AuthorizedService) MoveLocation(ctx context.Context, geoID string, newParentGeoID string, checkDescendants bool) ([]RelationViolation, error) {
+	resources, err := service.resources(ctx, geoID, newParentGeoID)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorize(ctx, "MoveLocation", AccessWrite, resources...); err != nil {
+		return nil, err
+	}
+	parents, err := service.parentResources(ctx, geoID)
+	if err != nil {
+		return nil, err
+	}
+	var replaced []Resource
+	for _, parent := range parents {
+		if parent.GeoLevel == resources[1].GeoLevel && parent.GeoID != newParentGeoID {
+			replaced = append(replaced, parent)
+		}
+	}
+	if len(replaced) > 0 {
+		if err := service.authorize(ctx, "MoveLocation", AccessWrite, replaced...); err != nil {
+			return nil, err
+		}
+	}
+	return service.next.MoveLocation(ctx, geoID, newParentGeoID, checkDescendants)
+}
+
+func (service *AuthorizedService) AddChildren(ctx context.Context, geoID string, childGeoIDs []string) error {
+	if err := service.authorizeLocations(ctx, "AddChildren", AccessWrite, append([]string{geoID}, childGeoIDs...)...); err != nil {
+		return err
+	}
+	return service.next.AddChildren(ctx, geoID, childGeoIDs)
+}
+
+func (service *AuthorizedService) RemoveChildren(ctx context.Context, geoID string, childGeoIDs []string) error {
+	if err := service.authorizeLocations(ctx, "RemoveChildren", AccessWrite, append([]string{geoID}, childGeoIDs...)...); err != nil {
+		return err
+	}
+	return service.next.RemoveChildren(ctx, geoID, childGeoIDs)
+}
+
+func (service *AuthorizedService) DeleteLocation(ctx context.Context, geoID string) error {
+	if err := service.authorizeLocations(ctx, "DeleteLocation", AccessWrite, geoID); err != nil {
+		return err
+	}
+	return service.next.DeleteLocation(ctx, geoID)
+}
+
+func (service *AuthorizedService) MergeLocations(ctx context.Context, survivorGeoID string, duplicateGeoIDs []string) (*Location, []RelationViolation, error) {
+	if err := service.authorizeLocations(ctx, "MergeLocations", AccessWrite, append([]string{survivorGeoID}, duplicateGeoIDs...)...); err != nil {
+		return nil, nil, err
+	}
+	return service.next.MergeLocations(ctx, survivorGeoID, duplicateGeoIDs)
+}
+
+// SplitLocation needs write access to the location and its parents, whose children change
+func (service *AuthorizedService) SplitLocation(ctx context.Context, geoID string, successorNames []string, childAssignments map[string]string) ([]Location, error) {
+	if err := service.authorizeLocations(ctx, "SplitLocation", AccessWrite, geoID); err != nil {
+		return nil, err
+	}
+	parents, err := service.parentResources(ctx, geoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) > 0 {
+		if err := service.authorize(ctx, "SplitLocation", AccessWrite, parents...); err != nil {
+			return nil, err
+		}
+	}
+	return service.next.SplitLocation(ctx, geoID, successorNames, childAssignments)
+}
+
+// parentResources resolves the direct parents of a location
+func (service *AuthorizedService) parentResources(ctx context.Context, geoID string) ([]Resource, error) {
+	parents, err := service.next.GetAllParents(ctx, geoID)
+	if err != nil {
+		return nil, err
+	}
+	geoIDs := make([]string, 0, len(parents))
+	for _, parent := range parents {
+		geoIDs = append(geoIDs, parent.GeoID)
+	}
+	return service.resources(ctx, geoIDs...)
+}
+
+// RestoreLocation needs a grant of the whole hierarchy, as a deleted location has no ancestors
+func (service *AuthorizedService) RestoreLocation(ctx context.Context, geoID string) (*Location, []RelationViolation, error) {
+	if err := service.authorize(ctx, "RestoreLocation", AccessWrite); err != nil {
+		return nil, nil, err
+	}
+	return service.next.RestoreLocation(ctx, geoID)
+}
+
+func (service *AuthorizedService) ListDeletedLocations(ctx context.Context) ([]DeletedLocation, error) {
+	if err := service.authorize(ctx, "ListDeletedLocations", AccessRead); err != nil {
+		return nil, err
+	}
+	return service.next.ListDeletedLocations(ctx)
+}
+
+func (service *AuthorizedService) PurgeDeleted(ctx context.Context, retention time.Duration) (PurgeResult, error) {
+	if err := service.authorize(ctx, "PurgeDeleted", AccessWrite); err != nil {
+		return PurgeResult{}, err
+	}
+	return service.next.PurgeDeleted(ctx, retention)
+}
+
+func (service *AuthorizedService) Check(ctx context.Context, repair bool) (CheckReport, error) {
+	access := AccessRead
+	if repair {
+		access = AccessWrite
+	}
+	if err := service.authorize(ctx, "Check", access); err != nil {
+		return CheckReport{}, err
+	}
+	return service.next.Check(ctx, repair)
+}
+
+func (service *AuthorizedService) ExportTree(ctx context.Context, rootGeoID *string) (*Tree, error) {
+	var err error
+	if rootGeoID != nil {
+		err = service.authorizeLocations(ctx, "ExportTree", AccessRead, *rootGeoID)
+	} else {
+		err = service.authorize(ctx, "ExportTree", AccessRead)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return service.next.ExportTree(ctx, rootGeoID)
+}
+
+func (service *AuthorizedService) ImportTree(ctx context.Context, tree Tree) (ImportResult, error) {
+	if err := service.authorize(ctx, "ImportTree", AccessWrite); err != nil {
+		return ImportResult{}, err
+	}
+	return service.next.ImportTree(ctx, tree)
+}
+
+func (service *AuthorizedService) DiffLive(ctx context.Context, tree Tree, rootGeoID *string) (ChangeSet, error) {
+	var err error
+	if rootGeoID != nil {
+		err = service.authorizeLocations(ctx, "DiffLive", AccessRead, *rootGeoID)
+	} else {
+		err = service.authorize(ctx, "DiffLive", AccessRead)
+	}
+	if err != nil {
+		return ChangeSet{}, err
+	}
+	return service.next.DiffLive(ctx, tree, rootGeoID)
+}
+
+func (service *AuthorizedService) ApplyChangeSet(ctx context.Context, changeSet ChangeSet) error {
+	if err := service.authorize(ctx, "ApplyChangeSet", AccessWrite); err != nil {
+		return err
+	}
+	return service.next.ApplyChangeSet(ctx, changeSet)
+}
+
+func (service *AuthorizedService) GetLocation(ctx context.Context, geoID string) (*Location, error) {
+	if err := service.authorizeLocations(ctx, "GetLocation", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.GetLocation(ctx, geoID)
+}
+
+func (service *AuthorizedService) GetLocations(ctx context.Context, geoIDs []string) ([]Location, error) {
+	if err := service.authorizeLocations(ctx, "GetLocations", AccessRead, geoIDs...); err != nil {
+		return nil, err
+	}
+	return service.next.GetLocations(ctx, geoIDs)
+}
+
+// GetLocationsByPattern returns only the matching locations the principal can read
+func (service *AuthorizedService) GetLocationsByPattern(ctx context.Context, name string, geoLevel *string) ([]Location, error) {
+	locs, err := service.next.GetLocationsByPattern(ctx, name, geoLevel)
+	if err != nil {
+		return nil, err
+	}
+	return readable(ctx, service, "GetLocationsByPattern", locs, func(loc Location) string { return loc.GeoID })
+}
+
+// SearchLocations returns only the matching locations the principal can read
+func (service *AuthorizedService) SearchLocations(ctx context.Context, pattern string, opts SearchOptions) ([]SearchResult, error) {
+	results, err := service.next.SearchLocations(ctx, pattern, opts)
+	if err != nil {
+		return nil, err
+	}
+	return readable(ctx, service, "SearchLocations", results, func(result SearchResult) string { return result.GeoID })
+}
+
+// Autocomplete returns only the suggestions the principal can read
+func (service *AuthorizedService) Autocomplete(ctx context.Context, prefix string, opts AutocompleteOptions) ([]Suggestion, error) {
+	suggestions, err := service.next.Autocomplete(ctx, prefix, opts)
+	if err != nil {
+		return nil, err
+	}
+	return readable(ctx, service, "Autocomplete", suggestions, func(suggestion Suggestion) string { return suggestion.GeoID })
+}
+
+// ListLocations returns only the listed locations the principal can read
+func (service *AuthorizedService) ListLocations(ctx context.Context, opts ListOptions) ([]Location, error) {
+	locs, err := service.next.ListLocations(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return service.readableLocations(ctx, "ListLocations", locs)
+}
+
+// readableLocations keeps the locations the principal can read
+func (service *AuthorizedService) readableLocations(ctx context.Context, method string, locs []Location) ([]Location, error) {
+	return readable(ctx, service, method, locs, func(loc Location) string { return loc.GeoID })
+}
+
+func (service *AuthorizedService) GetAttributes(ctx context.Context, geoID string) (map[string]any, error) {
+	if err := service.authorizeLocations(ctx, "GetAttributes", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.GetAttributes(ctx, geoID)
+}
+
+func (service *AuthorizedService) SetAttributes(ctx context.Context, geoID string, attributes map[string]any) (map[string]any, error) {
+	if err := service.authorizeLocations(ctx, "SetAttributes", AccessWrite, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.SetAttributes(ctx, geoID, attributes)
+}
+
+func (service *AuthorizedService) PatchAttributes(ctx context.Context, geoID string, patch map[string]any) (map[string]any, error) {
+	if err := service.authorizeLocations(ctx, "PatchAttributes", AccessWrite, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.PatchAttributes(ctx, geoID, patch)
+}
+
+// IndexAttribute changes the schema of the whole hierarchy and needs a global grant
+func (service *AuthorizedService) IndexAttribute(ctx context.Context, key string) error {
+	if err := service.authorize(ctx, "IndexAttribute", AccessWrite); err != nil {
+		return err
+	}
+	return service.next.IndexAttribute(ctx, key)
+}
+
+// SetAttributeSchema redefines a geo level and needs a global grant like the other geo level changes
+func (service *AuthorizedService) SetAttributeSchema(ctx context.Context, geoLevel string, schema AttributeSchema) ([]AttributeViolation, error) {
+	if err := service.authorize(ctx, "SetAttributeSchema", AccessWrite); err != nil {
+		return nil, err
+	}
+	return service.next.SetAttributeSchema(ctx, geoLevel, schema)
+}
+
+func (service *AuthorizedService) GetAttributeViolations(ctx context.Context) ([]AttributeViolation, error) {
+	if err := service.authorize(ctx, "GetAttributeViolations", AccessRead); err != nil {
+		return nil, err
+	}
+	return service.next.GetAttributeViolations(ctx)
+}
+
+// GetEffectiveAttributes leaves out the attributes inherited from ancestors the principal
+// cannot read
+func (service *AuthorizedService) GetEffectiveAttributes(ctx context.Context, geoID string) (map[string]EffectiveAttribute, error) {
+	if err := service.authorizeLocations(ctx, "GetEffectiveAttributes", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	attributes, err := service.next.GetEffectiveAttributes(ctx, geoID)
+	if err != nil {
+		return nil, err
+	}
+	keys := slices.Sorted(maps.Keys(attributes))
+	visible, err := readable(ctx, service, "GetEffectiveAttributes", keys, func(key string) string { return attributes[key].SourceGeoID })
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]EffectiveAttribute, len(visible))
+	for _, key := range visible {
+		out[key] = attributes[key]
+	}
+	return out, nil
+}
+
+// GetAllParents returns only the parents the principal can read
+func (service *AuthorizedService) GetAllParents(ctx context.Context, geoID string) ([]Location, error) {
+	if err := service.authorizeLocations(ctx, "GetAllParents", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	parents, err := service.next.GetAllParents(ctx, geoID)
+	if err != nil {
+		return nil, err
+	}
+	return service.readableLocations(ctx, "GetAllParents", parents)
+}
+
+// GetParentAtLevel needs read access to the location and its parent
+func (service *AuthorizedService) GetParentAtLevel(ctx context.Context, geoID string, geoLevel string) (*Location, error) {
+	if err := service.authorizeLocations(ctx, "GetParentAtLevel", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	parent, err := service.next.GetParentAtLevel(ctx, geoID, geoLevel)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorizeLocations(ctx, "GetParentAtLevel", AccessRead, parent.GeoID); err != nil {
+		return nil, err
+	}
+	return parent, nil
+}
+
+func (service *AuthorizedService) GetAllChildren(ctx context.Context, geoID string) ([]Location, error) {
+	if err := service.authorizeLocations(ctx, "GetAllChildren", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.GetAllChildren(ctx, geoID)
+}
+
+func (service *AuthorizedService) GetChildrenAtLevel(ctx context.Context, geoID string, geoLevel string) ([]Location, error) {
+	if err := service.authorizeLocations(ctx, "GetChildrenAtLevel", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	return service.next.GetChildrenAtLevel(ctx, geoID, geoLevel)
+}
+
+// GetSiblings returns only the siblings the principal can read
+func (service *AuthorizedService) GetSiblings(ctx context.Context, geoID string, parentLevel string) ([]Location, error) {
+	if err := service.authorizeLocations(ctx, "GetSiblings", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	siblings, err := service.next.GetSiblings(ctx, geoID, parentLevel)
+	if err != nil {
+		return nil, err
+	}
+	return service.readableLocations(ctx, "GetSiblings", siblings)
+}
+
+// GetNeighborhood needs read access to the location and its parent, and returns only the
+// siblings and children the principal can read
+func (service *AuthorizedService) GetNeighborhood(ctx context.Context, geoID string, parentLevel string) (*Neighborhood, error) {
+	if err := service.authorizeLocations(ctx, "GetNeighborhood", AccessRead, geoID); err != nil {
+		return nil, err
+	}
+	neighborhood, err := service.next.GetNeighborhood(ctx, geoID, parentLevel)
+	if err != nil {
+		return nil, err
+	}
+	// The parent, siblings and children are resolved at once
+	around := slices.Concat([]Location{neighborhood.Parent}, neighborhood.Siblings, neighborhood.Children)
+	geoIDs := make([]string, 0, len(around))
+	for _, loc := range around {
+		geoIDs = append(geoIDs, loc.GeoID)
+	}
+	resources, err := service.resources(ctx, geoIDs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorize(ctx, "GetNeighborhood", AccessRead, resources[0]); err != nil {
+		return nil, err
+	}
+	siblings := len(neighborhood.Siblings)
+	if neighborhood.Siblings, err = readableOf(ctx, service, "GetNeighborhood", neighborhood.Siblings, resources[1:1+siblings]); err != nil {
+		return nil, err
+	}
+	if neighborhood.Children, err = readableOf(ctx, service, "GetNeighborhood", neighborhood.Children, resources[1+siblings:]); err != nil {
+		return nil, err
+	}
+	return neighborhood, nil
+}
+
+func (service *AuthorizedService) CommonAncestor(ctx context.Context, geoLevel *string, geoIDs ...string) (*Location, error) {
+	if err := service.authorizeLocations(ctx, "CommonAncestor", AccessRead, geoIDs...); err != nil {
+		return nil, err
+	}
+	ancestor, err := service.next.CommonAncestor(ctx, geoLevel, geoIDs...)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorizeLocations(ctx, "CommonAncestor", AccessRead, ancestor.GeoID); err != nil {
+		return nil, err
+	}
+	return ancestor, nil
+}
+
+func (service *AuthorizedService) TreeDistance(ctx context.Context, geoID string, otherGeoID string) (int, error) {
+	if err := service.authorizeLocations(ctx, "TreeDistance", AccessRead, geoID, otherGeoID); err != nil {
+		return 0, err
+	}
+	return service.next.TreeDistance(ctx, geoID, otherGeoID)
+}
+
+// GetAncestry needs read access to every found location
+func (service *AuthorizedService) GetAncestry(ctx context.Context, geoIDs []string) (map[string]Ancestry, error) {
+	ancestry, err := service.next.GetAncestry(ctx, geoIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorize(ctx, "GetAncestry", AccessRead, resourcesOf(geoIDs, ancestry)...); err != nil {
+		return nil, err
+	}
+	return ancestry, nil
+}
+
+func (service *AuthorizedService) AddLocalizedName(ctx context.Context, geoID string, language string, name string) error {
+	if err := service.authorizeLocations(ctx, "AddLocalizedName", AccessWrite, geoID); err != nil {
+		return err
+	}
+	return service.next.AddLocalizedName(ctx, geoID, language, name)
+}
+
+// GetPath needs read access to the location and to its ancestors of the geo levels in the path
+func (service *AuthorizedService) GetPath(ctx context.Context, geoID string, opts PathOptions) (string, error) {
+	r, err := service.resource(ctx, geoID)
+	if err != nil {
+		return "", err
+	}
+	ancestors, err := service.resources(ctx, r.Ancestors...)
+	if err != nil {
+		return "", err
+	}
+	named := []Resource{r}
+	for _, ancestor := range ancestors {
+		if len(opts.GeoLevels) == 0 || slices.Contains(opts.GeoLevels, ancestor.GeoLevel) {
+			named = append(named, ancestor)
+		}
+	}
+	if err := service.authorize(ctx, "GetPath", AccessRead, named...); err != nil {
+		return "", err
+	}
+	return service.next.GetPath(ctx, geoID, opts)
+}
+
+// ResolvePath only considers the locations the principal can read: of several candidates,
+// a single readable one is returned, and none readable is ErrLocationNotFound
+func (service *AuthorizedService) ResolvePath(ctx context.Context, path string) (*Location, error) {
+	loc, err := service.next.ResolvePath(ctx, path)
+	var ambiguousErr *AmbiguousPathError
+	if errors.As(err, &ambiguousErr) {
+		candidates, err := service.readableLocations(ctx, "ResolvePath", ambiguousErr.Candidates)
+		if err != nil {
+			return nil, err
+		}
+		switch len(candidates) {
+		case 0:
+			return nil, postgres.ErrLocationNotFound
+		case 1:
+			return &candidates[0], nil
+		}
+		return nil, &AmbiguousPathError{Path: ambiguousErr.Path, Candidates: candidates}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := service.authorizeLocations(ctx, "ResolvePath", AccessRead, loc.GeoID); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// ResolvePlace returns only the candidates the principal can read
+func (service *AuthorizedService) ResolvePlace(ctx context.Context, text string, limit int) ([]PlaceCandidate, error) {
+	candidates, err := service.next.ResolvePlace(ctx, text, limit)
+	if err != nil {
+		return nil, err
+	}
+	return readable(ctx, service, "ResolvePlace", candidates, func(candidate PlaceCandidate) string { return candidate.Location.GeoID })
+}
@@ -0,0 +1,243 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestGrantPolicy_Authorize(t *testing.T) {
+	ctx := context.Background()
+	policy := GrantPolicy{Grants: []Grant{
+		{Principal: "admin", Access: AccessWrite},
+		{Principal: "reader", Access: AccessRead},
+		{Principal: "kerala-admin", Access: AccessWrite, RootGeoID: "kerala"},
+		{Principal: "kerala-admin", Access: AccessRead, RootGeoID: "india"},
+		{Principal: "district-editor", Access: AccessWrite, RootGeoID: "india", GeoLevels: []string{"DISTRICT"}},
+	}}
+	ernakulam := Resource{GeoID: "ernakulam", GeoLevel: "DISTRICT", Ancestors: []string{"kerala", "india"}}
+	kerala := Resource{GeoID: "kerala", GeoLevel: "STATE", Ancestors: []string{"india"}}
+	tamilNadu := Resource{GeoID: "tamil-nadu", GeoLevel: "STATE", Ancestors: []string{"india"}}
+	newCity := Resource{GeoLevel: "CITY"}
+	newKeralaCity := Resource{GeoLevel: "CITY", Ancestors: []string{"ernakulam", "kerala", "india"}}
+
+	tests := []struct {
+		name      string
+		principal string
+		access    Access
+		resources []Resource
+		allowed   bool
+	}{
+		{"global write", "admin", AccessWrite, []Resource{ernakulam, tamilNadu}, true},
+		{"global write on the hierarchy", "admin", AccessWrite, nil, true},
+		{"global read", "reader", AccessRead, []Resource{tamilNadu}, true},
+		{"read grant cannot write", "reader", AccessWrite, []Resource{tamilNadu}, false},
+		{"subtree root", "kerala-admin", AccessWrite, []Resource{kerala}, true},
+		{"subtree descendant", "kerala-admin", AccessWrite, []Resource{ernakulam}, true},
+		{"outside the subtree", "kerala-admin", AccessWrite, []Resource{tamilNadu}, false},
+		{"outside the write subtree but readable", "kerala-admin", AccessRead, []Resource{tamilNadu}, true},
+		{"every resource must be covered", "kerala-admin", AccessWrite, []Resource{ernakulam, tamilNadu}, false},
+		{"new location needs a global grant", "kerala-admin", AccessWrite, []Resource{newCity}, false},
+		{"new location in the subtree", "kerala-admin", AccessWrite, []Resource{newKeralaCity}, true},
+		{"new location of a restricted level", "district-editor", AccessWrite, []Resource{newKeralaCity}, false},
+		{"subtree grant on the hierarchy", "kerala-admin", AccessRead, nil, false},
+		{"level restricted grant", "district-editor", AccessWrite, []Resource{ernakulam}, true},
+		{"level restricted grant on another level", "district-editor", AccessWrite, []Resource{kerala}, false},
+		{"unknown principal", "", AccessRead, []Resource{kerala}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(ctx, AuthorizationRequest{Principal: tt.principal, Method: "Test", Access: tt.access, Resources: tt.resources})
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrPermissionDenied)
+			}
+		})
+	}
+}
+
+func TestAuthorizedService(t *testing.T) {
+	base := setupTestDB(t)
+	locs := createTestHierarchy(t, base)
+	ctx := context.Background()
+	tamilNadu := createTestLocation(t, base, "STATE", "Tamil Nadu")
+	require.NoError(t, base.AddParent(ctx, tamilNadu.GeoID, locs["India"].GeoID))
+	chennai := createTestLocation(t, base, "DISTRICT", "Chennai")
+	require.NoError(t, base.AddParent(ctx, chennai.GeoID, tamilNadu.GeoID))
+
+	service := NewAuthorizedService(base, GrantPolicy{Grants: []Grant{
+		{Principal: "admin", Access: AccessWrite},
+		{Principal: "kerala-admin", Access: AccessWrite, RootGeoID: locs["Kerala"].GeoID},
+		{Principal: "district-editor", Access: AccessWrite, RootGeoID: locs["India"].GeoID, GeoLevels: []string{"DISTRICT"}},
+	}})
+	admin := WithPrincipal(ctx, "admin")
+	keralaAdmin := WithPrincipal(ctx, "kerala-admin")
+	districtEditor := WithPrincipal(ctx, "district-editor")
+
+	t.Run("subtree admin edits its subtree only", func(t *testing.T) {
+		assert.NoError(t, service.AddAliasToLocation(keralaAdmin, locs["Kochi"].GeoID, "Cochin"))
+		assert.ErrorIs(t, service.AddAliasToLocation(keralaAdmin, chennai.GeoID, "Madras"), ErrPermissionDenied)
+
+		// Moving a district out of the subtree needs access to the new parent too
+		_, err := service.MoveLocation(keralaAdmin, locs["Ernakulam"].GeoID, tamilNadu.GeoID, false)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		parent, err := base.GetParentAtLevel(ctx, locs["Ernakulam"].GeoID, "STATE")
+		require.NoError(t, err)
+		assert.Equal(t, locs["Kerala"].GeoID, parent.GeoID)
+	})
+
+	t.Run("changed parents need write access", func(t *testing.T) {
+		// Kumily lies in Kerala and, by mistake, in Chennai
+		kumily := createTestLocation(t, base, "CITY", "Kumily")
+		require.NoError(t, base.AddParent(ctx, kumily.GeoID, locs["Kerala"].GeoID))
+		require.NoError(t, base.AddParent(ctx, kumily.GeoID, chennai.GeoID))
+
+		_, err := service.MoveLocation(keralaAdmin, kumily.GeoID, locs["Ernakulam"].GeoID, false)
+		assert.ErrorIs(t, err, ErrPermissionDenied, "the move replaces Chennai")
+		_, err = service.SplitLocation(keralaAdmin, kumily.GeoID, []string{"Kumily North", "Kumily South"}, nil)
+		assert.ErrorIs(t, err, ErrPermissionDenied, "the split changes the children of Chennai")
+		parent, err := base.GetParentAtLevel(ctx, kumily.GeoID, "DISTRICT")
+		require.NoError(t, err)
+		assert.Equal(t, chennai.GeoID, parent.GeoID)
+
+		_, err = service.MoveLocation(admin, kumily.GeoID, locs["Ernakulam"].GeoID, false)
+		require.NoError(t, err)
+	})
+
+	t.Run("restoring needs a global grant", func(t *testing.T) {
+		kolenchery := createTestLocation(t, base, "CITY", "Kolenchery")
+		require.NoError(t, base.AddParent(ctx, kolenchery.GeoID, locs["Ernakulam"].GeoID))
+		require.NoError(t, service.DeleteLocation(keralaAdmin, kolenchery.GeoID))
+
+		_, _, err := service.RestoreLocation(keralaAdmin, kolenchery.GeoID)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		_, _, err = service.RestoreLocation(admin, kolenchery.GeoID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("geo level restricted grant", func(t *testing.T) {
+		assert.NoError(t, service.AddAliasToLocation(districtEditor, chennai.GeoID, "Madras"))
+		assert.ErrorIs(t, service.AddAliasToLocation(districtEditor, locs["Kochi"].GeoID, "Kochin"), ErrPermissionDenied)
+	})
+
+	t.Run("hierarchy-wide calls need a global grant", func(t *testing.T) {
+		_, err := service.AddLocation(keralaAdmin, "", "CITY", "Aluva")
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		assert.ErrorIs(t, service.AddGeoLevel(keralaAdmin, "WARD", float64Ptr(5)), ErrPermissionDenied)
+		_, err = service.ListGeoLevels(keralaAdmin)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+
+		_, err = service.AddLocation(admin, "", "CITY", "Aluva")
+		assert.NoError(t, err)
+	})
+
+	t.Run("subtree admin adds children in its subtree", func(t *testing.T) {
+		loc, err := service.AddChildLocation(keralaAdmin, locs["Ernakulam"].GeoID, "CITY", "Aluva", nil)
+		require.NoError(t, err)
+		parent, err := base.GetParentAtLevel(ctx, loc.GeoID, "DISTRICT")
+		require.NoError(t, err)
+		assert.Equal(t, locs["Ernakulam"].GeoID, parent.GeoID)
+
+		_, err = service.AddChildLocation(keralaAdmin, chennai.GeoID, "CITY", "Tambaram", nil)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		_, err = service.AddChildLocation(districtEditor, tamilNadu.GeoID, "DISTRICT", "Madurai", nil)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+	})
+
+	t.Run("geo level changes need an unrestricted grant", func(t *testing.T) {
+		levelAdmin := NewAuthorizedService(base, GrantPolicy{Grants: []Grant{
+			{Principal: "district-admin", Access: AccessWrite, GeoLevels: []string{"DISTRICT"}},
+		}})
+		districtAdmin := WithPrincipal(ctx, "district-admin")
+		rank := 2.5
+		assert.ErrorIs(t, levelAdmin.UpdateGeoLevel(districtAdmin, "DISTRICT", nil, &rank), ErrPermissionDenied)
+		assert.ErrorIs(t, levelAdmin.DeleteGeoLevel(districtAdmin, "DISTRICT", nil), ErrPermissionDenied)
+		_, err := levelAdmin.AddLevelRule(districtAdmin, "DISTRICT", []string{"COUNTRY"})
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		assert.ErrorIs(t, levelAdmin.RemoveLevelRule(districtAdmin, "DISTRICT", "STATE"), ErrPermissionDenied)
+		_, err = levelAdmin.SetAttributeSchema(districtAdmin, "DISTRICT", AttributeSchema{"census_code": {Required: true}})
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+
+		level, err := base.GetGeoLevel(ctx, "DISTRICT")
+		require.NoError(t, err)
+		assert.Empty(t, level.AttributeSchema)
+	})
+
+	t.Run("reads are checked and searches filtered", func(t *testing.T) {
+		_, err := service.GetLocation(keralaAdmin, chennai.GeoID)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		_, err = service.GetLocation(ctx, locs["Kochi"].GeoID)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+
+		found, err := service.GetLocationsByPattern(keralaAdmin, "a", nil)
+		require.NoError(t, err)
+		var names []string
+		for _, loc := range found {
+			names = append(names, loc.Name)
+		}
+		assert.ElementsMatch(t, []string{"Kerala", "Ernakulam"}, names)
+	})
+
+	t.Run("ancestors outside a leaf grant stay hidden", func(t *testing.T) {
+		leaf := NewAuthorizedService(base, GrantPolicy{Grants: []Grant{
+			{Principal: "kochi-reader", Access: AccessRead, RootGeoID: locs["Kochi"].GeoID},
+		}})
+		kochiReader := WithPrincipal(ctx, "kochi-reader")
+		_, err := base.SetAttributes(ctx, locs["Kerala"].GeoID, map[string]any{"language": "Malayalam"})
+		require.NoError(t, err)
+		_, err = base.SetAttributes(ctx, locs["Kochi"].GeoID, map[string]any{"pin": "682001"})
+		require.NoError(t, err)
+
+		parents, err := leaf.GetAllParents(kochiReader, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Empty(t, parents)
+		_, err = leaf.GetParentAtLevel(kochiReader, locs["Kochi"].GeoID, "DISTRICT")
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		_, err = leaf.GetPath(kochiReader, locs["Kochi"].GeoID, PathOptions{})
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+
+		attributes, err := leaf.GetEffectiveAttributes(kochiReader, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Contains(t, attributes, "pin")
+		assert.NotContains(t, attributes, "language")
+
+		_, err = service.GetPath(keralaAdmin, locs["Kochi"].GeoID, PathOptions{})
+		assert.ErrorIs(t, err, ErrPermissionDenied, "India is outside the grant")
+		path, err := service.GetPath(keralaAdmin, locs["Kochi"].GeoID, PathOptions{GeoLevels: []string{"STATE", "CITY"}})
+		require.NoError(t, err)
+		assert.Equal(t, "Kerala > Kochi", path)
+		parents, err = service.GetAllParents(admin, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Len(t, parents, 1)
+	})
+
+	t.Run("calls in a transaction are authorized", func(t *testing.T) {
+		err := service.InTx(keralaAdmin, func(tx LocationService) error {
+			if err := tx.AddAliasToLocation(keralaAdmin, locs["Ernakulam"].GeoID, "Ernakulam District"); err != nil {
+				return err
+			}
+			return tx.AddAliasToLocation(keralaAdmin, tamilNadu.GeoID, "TN")
+		})
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+
+		ernakulam, err := base.GetLocation(ctx, locs["Ernakulam"].GeoID)
+		require.NoError(t, err)
+		assert.NotContains(t, ernakulam.Aliases, "Ernakulam District")
+	})
+
+	t.Run("paths resolve among readable locations", func(t *testing.T) {
+		require.NoError(t, base.AddAliasToLocation(ctx, chennai.GeoID, "Ernakulam"))
+
+		_, err := service.ResolvePath(admin, "India/Ernakulam")
+		assert.ErrorIs(t, err, postgres.ErrAmbiguousPath)
+		loc, err := service.ResolvePath(keralaAdmin, "India/Ernakulam")
+		require.NoError(t, err)
+		assert.Equal(t, locs["Ernakulam"].GeoID, loc.GeoID)
+		_, err = service.ResolvePath(keralaAdmin, "India/Tamil Nadu")
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+	})
+}
@@ -0,0 +1,64 @@
+package location
+
+import (
+	"context"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// Limits of the number of suggestions returned by Autocomplete
+const (
+	DefaultAutocompleteLimit = 10
+	MaxAutocompleteLimit     = 100
+)
+
+// AutocompleteOptions narrows and ranks the suggestions of Autocomplete
+type AutocompleteOptions struct {
+	Limit          int                // DefaultAutocompleteLimit if not positive, at most MaxAutocompleteLimit
+	WithinGeoID    *string            // only descendants of this location, if set
+	GeoLevelBoosts map[string]float64 // added to the score of the suggestions of each geo level
+}
+
+// Suggestion is a location suggested for a typed prefix
+type Suggestion struct {
+	GeoID       string `json:"geo_id"`
+	GeoLevel    string `json:"geo_level"`
+	Name        string `json:"name"`         // primary name
+	MatchedName string `json:"matched_name"` // primary name or alias starting with the prefix
+}
+
+// Autocomplete suggests the locations whose primary name or an alias starts with the prefix,
+// regardless of case and punctuation, once per location. Suggestions are ranked by the boost
+// of their geo level, then whole name matches and primary names first, then shorter names.
+func (service *ServiceOnPostgres) Autocomplete(ctx context.Context, prefix string, opts AutocompleteOptions) ([]Suggestion, error) {
+	query := postgres.AutocompleteQuery{
+		Prefix: prefix,
+		Boosts: opts.GeoLevelBoosts,
+		Limit:  min(opts.Limit, MaxAutocompleteLimit),
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultAutocompleteLimit
+	}
+	if opts.WithinGeoID != nil {
+		id, err := uuidFromString(*opts.WithinGeoID)
+		if err != nil {
+			return nil, err
+		}
+		query.WithinID = &id
+	}
+
+	found, err := service.db.Autocomplete(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	suggestions := make([]Suggestion, 0, len(found))
+	for _, suggestion := range found {
+		suggestions = append(suggestions, Suggestion{
+			GeoID:       suggestion.LocationID.String(),
+			GeoLevel:    suggestion.GeoLevel,
+			Name:        suggestion.Name,
+			MatchedName: suggestion.MatchedName,
+		})
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,40 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceOnPostgres_Autocomplete(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	require.NoError(t, service.AddAliasToLocation(ctx, locs["Kochi"].GeoID, "Cochin"))
+	require.NoError(t, service.AddAliasToLocation(ctx, locs["Kochi"].GeoID, "Kochi City"))
+	kottayam := createTestLocation(t, service, "DISTRICT", "Kottayam")
+	require.NoError(t, service.AddParent(ctx, kottayam.GeoID, locs["Kerala"].GeoID))
+
+	suggestions, err := service.Autocomplete(ctx, "ko", AutocompleteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Suggestion{
+		{GeoID: locs["Kochi"].GeoID, GeoLevel: "CITY", Name: "Kochi", MatchedName: "Kochi"},
+		{GeoID: kottayam.GeoID, GeoLevel: "DISTRICT", Name: "Kottayam", MatchedName: "Kottayam"},
+	}, suggestions)
+
+	suggestions, err = service.Autocomplete(ctx, "co", AutocompleteOptions{WithinGeoID: stringPtr(locs["Ernakulam"].GeoID)})
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "Cochin", suggestions[0].MatchedName)
+	assert.Equal(t, "Kochi", suggestions[0].Name)
+
+	suggestions, err = service.Autocomplete(ctx, "ko", AutocompleteOptions{GeoLevelBoosts: map[string]float64{"DISTRICT": 1}, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, kottayam.GeoID, suggestions[0].GeoID)
+
+	_, err = service.Autocomplete(ctx, "ko", AutocompleteOptions{WithinGeoID: stringPtr("not-a-uuid")})
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+}
@@ -0,0 +1,41 @@
+package location
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Check scans the hierarchy for inconsistencies and reports them by category.
+// With repair, safe findings (dangling names and relations, locations without a primary
+// name that have an alias) are fixed; all findings are reported either way.
+func (service *ServiceOnPostgres) Check(ctx context.Context, repair bool) (CheckReport, error) {
+	findings, err := service.db.Check(ctx, repair)
+	if err != nil {
+		return CheckReport{}, err
+	}
+
+	report := CheckReport{Findings: make(map[string][]CheckFinding)}
+	for _, finding := range findings {
+		geoIDs := make([]string, 0, len(finding.GeoIDs))
+		for _, id := range finding.GeoIDs {
+			geoIDs = append(geoIDs, id.String())
+		}
+		var recordID string
+		if finding.RecordID != uuid.Nil {
+			recordID = finding.RecordID.String()
+		}
+		category := string(finding.Category)
+		report.Findings[category] = append(report.Findings[category], CheckFinding{
+			GeoIDs:   geoIDs,
+			RecordID: recordID,
+			Detail:   finding.Detail,
+			Repaired: finding.Repaired,
+		})
+		report.Total++
+		if finding.Repaired {
+			report.Repaired++
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,38 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_Check(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+
+	report, err := service.Check(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Total, "a clean hierarchy has no findings")
+
+	// Raising the rank of CITY above DISTRICT behind the service's back breaks Ernakulam > Kochi
+	require.NoError(t, service.db.DB.Model(&postgres.GeoLevel{}).Where("name = ?", "CITY").Update("rank", 2.5).Error)
+	orphan := createTestLocation(t, service, "DISTRICT", "Orphan")
+
+	report, err = service.Check(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 0, report.Repaired)
+
+	rank := report.Findings[string(postgres.CheckRankViolation)]
+	require.Len(t, rank, 1)
+	assert.Equal(t, []string{locs["Ernakulam"].GeoID, locs["Kochi"].GeoID}, rank[0].GeoIDs)
+
+	orphans := report.Findings[string(postgres.CheckOrphanLocation)]
+	require.Len(t, orphans, 1)
+	assert.Equal(t, []string{orphan.GeoID}, orphans[0].GeoIDs)
+}
@@ -0,0 +1,204 @@
+// Command locationctl runs maintenance tasks against a location database.
+//
+// Usage:
+//
+//	locationctl [-dsn DSN] [-tenant TENANT] <command> [flags]
+//
+// Commands:
+//
+//	migrate           create or update the schema, indexes and triggers
+//	check [-repair]   scan the hierarchy for inconsistencies, optionally repairing the safe ones
+//	export [-root ID] write the hierarchy, or the subtree of a location, as nested JSON
+//	import [FILE]     import a nested JSON document from FILE or standard input
+//	diff [-root ID] [FILE]
+//	                  print the change set turning the hierarchy into the document
+//	apply [FILE]      apply a change set in one transaction
+//
+// The DSN defaults to the LOCATION_DSN environment variable.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xaults/platform/location"
+	store "github.com/xaults/platform/location/postgres"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("LOCATION_DSN"), "postgres connection string")
+	tenant := flag.String("tenant", "", "tenant namespace (default tenant if empty)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	if *dsn == "" {
+		fatalf("a DSN is required (-dsn or LOCATION_DSN)")
+	}
+
+	db, err := gorm.Open(postgres.Open(*dsn), &gorm.Config{})
+	if err != nil {
+		fatalf("failed to connect: %v", err)
+	}
+	service, err := location.NewServiceOnPostgres(db)
+	if err != nil {
+		fatalf("failed to create service: %v", err)
+	}
+	ctx := location.WithTenant(context.Background(), *tenant)
+
+	switch cmd, args := flag.Arg(0), flag.Args()[1:]; cmd {
+	case "migrate":
+		if err := store.Migrate(db); err != nil {
+			fatalf("migrate failed: %v", err)
+		}
+	case "check":
+		os.Exit(runCheck(ctx, service, args))
+	case "export":
+		runExport(ctx, service, args)
+	case "import":
+		runImport(ctx, service, args)
+	case "diff":
+		runDiff(ctx, service, args)
+	case "apply":
+		runApply(ctx, service, args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+}
+
+// runCheck prints the check report as JSON and returns a non-zero exit code
+// if findings remain unrepaired
+func runCheck(ctx context.Context, service location.LocationService, args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "repair safe findings")
+	_ = flags.Parse(args)
+
+	report, err := service.Check(ctx, *repair)
+	if err != nil {
+		fatalf("check failed: %v", err)
+	}
+	printJSON(report)
+	if report.Total > report.Repaired {
+		return 1
+	}
+	return 0
+}
+
+// runExport prints the nested JSON document of the hierarchy or of a subtree
+func runExport(ctx context.Context, service location.LocationService, args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	root := flags.String("root", "", "geo_id of the subtree root (whole hierarchy if empty)")
+	_ = flags.Parse(args)
+
+	var rootGeoID *string
+	if *root != "" {
+		rootGeoID = root
+	}
+	tree, err := service.ExportTree(ctx, rootGeoID)
+	if err != nil {
+		fatalf("export failed: %v", err)
+	}
+	printJSON(tree)
+}
+
+// runImport imports a nested JSON document and prints what was created and matched
+func runImport(ctx context.Context, service location.LocationService, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	var tree location.Tree
+	readJSON(flags, &tree)
+	result, err := service.ImportTree(ctx, tree)
+	if err != nil {
+		fatalf("import failed: %v", err)
+	}
+	printJSON(result)
+}
+
+// runDiff prints the change set turning the hierarchy, or a subtree, into the document
+func runDiff(ctx context.Context, service location.LocationService, args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	root := flags.String("root", "", "geo_id of the subtree root (whole hierarchy if empty)")
+	_ = flags.Parse(args)
+
+	var rootGeoID *string
+	if *root != "" {
+		rootGeoID = root
+	}
+	var tree location.Tree
+	readJSON(flags, &tree)
+	changeSet, err := service.DiffLive(ctx, tree, rootGeoID)
+	if err != nil {
+		fatalf("diff failed: %v", err)
+	}
+	printJSON(changeSet)
+}
+
+// runApply applies a change set as printed by diff
+func runApply(ctx context.Context, service location.LocationService, args []string) {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	_ = flags.Parse(args)
+
+	var changeSet location.ChangeSet
+	readJSON(flags, &changeSet)
+	if err := service.ApplyChangeSet(ctx, changeSet); err != nil {
+		fatalf("apply failed: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "applied %d changes\n", len(changeSet.Changes))
+}
+
+// readJSON decodes the file named by the first argument, or standard input
+func readJSON(flags *flag.FlagSet, v any) {
+	in := os.Stdin
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fatalf("failed to open input: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	if err := json.NewDecoder(in).Decode(v); err != nil {
+		fatalf("failed to read input: %v", err)
+	}
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fatalf("failed to write output: %v", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: locationctl [-dsn DSN] [-tenant TENANT] <command> [flags]
+
+commands:
+  migrate           create or update the schema
+  check [-repair]   scan the hierarchy for inconsistencies
+  export [-root ID] write the hierarchy as nested JSON
+  import [FILE]     import a nested JSON document
+  diff [-root ID] [FILE]
+                    print the changes turning the hierarchy into the document
+  apply [FILE]      apply a change set
+
+flags:
+`)
+	flag.PrintDefaults()
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "locationctl: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,106 @@
+package location
+
+import (
+	"context"
+	"time"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// RestoreLocation restores a deleted location with the names and relations removed by its deletion.
+// Relations that no longer satisfy the hierarchy rules are not restored and are returned instead.
+func (service *ServiceOnPostgres) RestoreLocation(ctx context.Context, geoID string) (*Location, []RelationViolation, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var conflicts []postgres.RelationConflict
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		conflicts, err = store.RestoreLocation(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := service.GetLocation(ctx, geoID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loc, relationConflictViolations(conflicts), nil
+}
+
+// ListDeletedLocations lists the deleted locations that can be restored, most recently deleted first
+func (service *ServiceOnPostgres) ListDeletedLocations(ctx context.Context) ([]DeletedLocation, error) {
+	deleted, err := service.db.ListDeletedLocations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DeletedLocation, 0, len(deleted))
+	for _, loc := range deleted {
+		out = append(out, DeletedLocation{
+			Location: Location{
+				GeoID:    loc.Id.String(),
+				GeoLevel: loc.GeoLevel,
+				Name:     loc.Name,
+				Aliases:  loc.Aliases,
+			},
+			DeletedAt: loc.DeletedAt,
+		})
+	}
+	return out, nil
+}
+
+// PurgeDeleted permanently removes locations, names and relations deleted longer ago than the retention period
+func (service *ServiceOnPostgres) PurgeDeleted(ctx context.Context, retention time.Duration) (PurgeResult, error) {
+	var result postgres.PurgeResult
+	err := service.transaction(ctx, func(store *postgres.Store) error {
+		var err error
+		result, err = store.PurgeDeleted(ctx, time.Now().Add(-retention))
+		return err
+	})
+	if err != nil {
+		return PurgeResult{}, err
+	}
+	return PurgeResult{
+		Locations: result.Locations,
+		Names:     result.Names,
+		Relations: result.Relations,
+	}, nil
+}
+
+// RunPurgeJob calls PurgeDeleted with the given retention every interval until the context is done.
+// Each run's result is passed to report, if given; a failed run does not stop the job.
+func RunPurgeJob(ctx context.Context, service LocationService, interval time.Duration, retention time.Duration, report func(PurgeResult, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			result, err := service.PurgeDeleted(ctx, retention)
+			if report != nil {
+				report(result, err)
+			}
+		}
+	}
+}
+
+func relationConflictViolations(conflicts []postgres.RelationConflict) []RelationViolation {
+	violations := make([]RelationViolation, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		violation := RelationViolation{
+			ParentGeoID: conflict.Relation.ParentID.String(),
+			ChildGeoID:  conflict.Relation.ChildID.String(),
+			Reason:      conflict.Err.Error(),
+		}
+		if conflict.Relation.Parent != nil {
+			violation.ParentGeoLevel = conflict.Relation.Parent.GeoLevel.Name
+		}
+		if conflict.Relation.Child != nil {
+			violation.ChildGeoLevel = conflict.Relation.Child.GeoLevel.Name
+		}
+		violations = append(violations, violation)
+	}
+	return violations
+}
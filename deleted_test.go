@@ -0,0 +1,109 @@
+package location
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_RestoreLocation(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	country, state, district := locs["India"], locs["Kerala"], locs["Ernakulam"]
+	require.NoError(t, service.AddAliasToLocation(ctx, state.GeoID, "State Alias"))
+	require.NoError(t, service.DeleteLocation(ctx, state.GeoID))
+
+	deleted, err := service.ListDeletedLocations(ctx)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.Equal(t, state.GeoID, deleted[0].GeoID)
+	assert.Equal(t, state.Name, deleted[0].Name)
+
+	restored, violations, err := service.RestoreLocation(ctx, state.GeoID)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.Equal(t, state.Name, restored.Name)
+	assert.Equal(t, []string{"State Alias"}, restored.Aliases)
+
+	parents, err := service.GetAllParents(ctx, state.GeoID)
+	require.NoError(t, err)
+	require.Len(t, parents, 1)
+	assert.Equal(t, country.GeoID, parents[0].GeoID)
+
+	children, err := service.GetAllChildren(ctx, state.GeoID)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	assert.Equal(t, district.GeoID, children[0].GeoID)
+
+	_, _, err = service.RestoreLocation(ctx, state.GeoID)
+	assert.ErrorIs(t, err, postgres.ErrLocationNotDeleted)
+
+	_, _, err = service.RestoreLocation(ctx, "invalid-uuid")
+	assert.Error(t, err)
+}
+
+func TestServiceOnPostgres_RestoreLocationWithConflicts(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	country, state := locs["India"], locs["Kerala"]
+	require.NoError(t, service.DeleteLocation(ctx, state.GeoID))
+
+	// A parent deleted after the location cannot be related again
+	require.NoError(t, service.DeleteLocation(ctx, country.GeoID))
+
+	_, violations, err := service.RestoreLocation(ctx, state.GeoID)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, country.GeoID, violations[0].ParentGeoID)
+	assert.Equal(t, state.GeoID, violations[0].ChildGeoID)
+	assert.Contains(t, violations[0].Reason, postgres.ErrLocationNotFound.Error())
+}
+
+func TestServiceOnPostgres_PurgeDeleted(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	state := locs["Kerala"]
+	require.NoError(t, service.DeleteLocation(ctx, state.GeoID))
+
+	result, err := service.PurgeDeleted(ctx, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, PurgeResult{}, result, "recent deletions are kept")
+
+	result, err = service.PurgeDeleted(ctx, -time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Locations)
+	assert.Equal(t, int64(1), result.Names)
+	assert.Equal(t, int64(2), result.Relations)
+
+	_, _, err = service.RestoreLocation(ctx, state.GeoID)
+	assert.ErrorIs(t, err, postgres.ErrLocationNotFound)
+}
+
+func TestRunPurgeJob(t *testing.T) {
+	service := setupTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runs := make(chan error, 10)
+	go func() {
+		err := RunPurgeJob(ctx, service, 10*time.Millisecond, time.Hour, func(_ PurgeResult, err error) {
+			runs <- err
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		close(runs)
+	}()
+
+	require.NoError(t, <-runs)
+	cancel()
+	for range runs {
+	}
+}
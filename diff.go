@@ -0,0 +1,313 @@
+package location
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// DiffLive compares the live hierarchy, or the subtree of rootGeoID, with the tree and
+// returns the changes that turn the live hierarchy into the tree (see DiffTrees)
+func (service *ServiceOnPostgres) DiffLive(ctx context.Context, tree Tree, rootGeoID *string) (ChangeSet, error) {
+	live, err := service.ExportTree(ctx, rootGeoID)
+	if err != nil {
+		return ChangeSet{}, err
+	}
+	return DiffTrees(*live, tree), nil
+}
+
+// ApplyChangeSet executes the changes in order in one transaction.
+// If a change fails, nothing is applied.
+func (service *ServiceOnPostgres) ApplyChangeSet(ctx context.Context, changeSet ChangeSet) error {
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		for i, change := range changeSet.Changes {
+			if err := applyChange(ctx, store, change); err != nil {
+				return fmt.Errorf("failed to apply change %d (%s %s): %w", i, change.Type, change.GeoID, err)
+			}
+		}
+		return nil
+	})
+}
+
+func applyChange(ctx context.Context, store *postgres.Store, change Change) error {
+	if change.Type == ChangeAddGeoLevel {
+		if _, err := store.InsertGeoLevel(ctx, change.GeoLevel, change.Rank); err != nil {
+			return err
+		}
+		if len(change.AttributeSchema) > 0 {
+			_, err := store.SetAttributeSchema(ctx, change.GeoLevel, toStoreSchema(change.AttributeSchema))
+			return err
+		}
+		return nil
+	}
+
+	id, err := uuidFromString(change.GeoID)
+	if err != nil {
+		return err
+	}
+	switch change.Type {
+	case ChangeAddLocation:
+		_, err = store.InsertLocationWithAttributes(ctx, id, change.GeoLevel, change.Name, change.Attributes)
+		return err
+	case ChangeRemoveLocation:
+		return store.DeleteLocation(ctx, id)
+	case ChangeRename:
+		return store.SetPrimaryName(ctx, id, change.Name)
+	case ChangeSetAttributes:
+		_, err = store.SetAttributes(ctx, id, change.Attributes)
+		return err
+	case ChangeSetGeoLevel:
+		_, err = store.UpdateLocation(ctx, id, &change.GeoLevel, nil)
+		return err
+	case ChangeAddAlias:
+		if change.Language != "" {
+			return store.InsertLocalizedName(ctx, id, change.Language, change.Name)
+		}
+		return store.InsertNameMap(ctx, id, change.Name, false)
+	case ChangeRemoveAlias:
+		return store.DeleteNameMap(ctx, id, change.Name)
+	case ChangeAddParent:
+		parentID, err := uuidFromString(change.ParentGeoID)
+		if err != nil {
+			return err
+		}
+		_, err = store.InsertRelation(ctx, parentID, id)
+		return err
+	case ChangeReparent:
+		parentID, err := uuidFromString(change.ParentGeoID)
+		if err != nil {
+			return err
+		}
+		_, err = store.MoveLocation(ctx, id, parentID, false)
+		return err
+	case ChangeRemoveParent:
+		oldParentID, err := uuidFromString(change.OldParentGeoID)
+		if err != nil {
+			return err
+		}
+		relations, err := store.GetParents(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, rel := range relations {
+			if rel.ParentID == oldParentID {
+				return store.DeleteRelation(ctx, rel.Id)
+			}
+		}
+		return postgres.ErrRelationNotFound
+	default:
+		return fmt.Errorf("unknown change type %q", change.Type)
+	}
+}
+
+// DiffTrees returns the changes that turn the from hierarchy into the to hierarchy.
+// Locations are matched by geo_id; a node of to without geo_id is matched to the location of
+// from with the same geo level and primary name under the same parent, or added with a new
+// geo_id. The changes are ordered so that ApplyChangeSet can run them as they are: geo levels
+// and locations are added top-down first, then names change, parents are removed, attributes
+// and geo levels of locations change, parents are changed and added, and removed locations
+// are deleted bottom-up last.
+func DiffTrees(from, to Tree) ChangeSet {
+	source := flattenTree(from, nil)
+	target := flattenTree(to, source)
+
+	changes := []Change{}
+	known := make(map[string]bool, len(from.GeoLevels))
+	for _, level := range from.GeoLevels {
+		known[level.Name] = true
+	}
+	for _, level := range to.GeoLevels {
+		if !known[level.Name] {
+			changes = append(changes, Change{Type: ChangeAddGeoLevel, GeoLevel: level.Name, Rank: level.Rank, AttributeSchema: level.AttributeSchema})
+		}
+	}
+
+	for _, id := range target.order {
+		if _, ok := source.locations[id]; !ok {
+			loc := target.locations[id]
+			changes = append(changes, Change{Type: ChangeAddLocation, GeoID: id, GeoLevel: loc.geoLevel, Name: loc.name, Attributes: loc.attributes})
+		}
+	}
+
+	// Names: the old primary name stays as an alias after a rename, and an alias whose
+	// language changes is removed and added again in its new language
+	var renames, addedAliases, removedAliases, relabeledAliases []Change
+	for _, id := range target.order {
+		loc := target.locations[id]
+		old, matched := source.locations[id]
+		oldNames := map[string]bool{}
+		if matched {
+			if old.name != loc.name {
+				renames = append(renames, Change{Type: ChangeRename, GeoID: id, Name: loc.name, OldName: old.name})
+			}
+			oldNames[old.name] = true
+			for _, alias := range old.aliases {
+				oldNames[alias] = true
+			}
+		}
+		newNames := map[string]bool{loc.name: true}
+		for _, alias := range loc.aliases {
+			newNames[alias] = true
+			language := loc.aliasLanguages[alias]
+			switch {
+			case !oldNames[alias]:
+				addedAliases = append(addedAliases, Change{Type: ChangeAddAlias, GeoID: id, Name: alias, Language: language})
+			case old.aliasLanguages[alias] != language:
+				relabeledAliases = append(relabeledAliases,
+					Change{Type: ChangeRemoveAlias, GeoID: id, Name: alias},
+					Change{Type: ChangeAddAlias, GeoID: id, Name: alias, Language: language})
+			}
+		}
+		if matched {
+			for _, name := range append([]string{old.name}, old.aliases...) {
+				if !newNames[name] {
+					removedAliases = append(removedAliases, Change{Type: ChangeRemoveAlias, GeoID: id, Name: name})
+				}
+			}
+		}
+	}
+	changes = append(changes, renames...)
+	changes = append(changes, addedAliases...)
+	changes = append(changes, removedAliases...)
+	changes = append(changes, relabeledAliases...)
+
+	// Parents, keyed by the geo level of the parent
+	var removedParents, reparents, addedParents []Change
+	for _, id := range target.order {
+		loc := target.locations[id]
+		old, matched := source.locations[id]
+		if matched {
+			for _, level := range slices.Sorted(maps.Keys(old.parents)) {
+				if _, ok := loc.parents[level]; !ok {
+					removedParents = append(removedParents, Change{Type: ChangeRemoveParent, GeoID: id, OldParentGeoID: old.parents[level]})
+				}
+			}
+		}
+		for _, level := range slices.Sorted(maps.Keys(loc.parents)) {
+			parentID := loc.parents[level]
+			oldParentID, ok := "", false
+			if matched {
+				oldParentID, ok = old.parents[level]
+			}
+			switch {
+			case !ok:
+				addedParents = append(addedParents, Change{Type: ChangeAddParent, GeoID: id, ParentGeoID: parentID})
+			case oldParentID != parentID:
+				reparents = append(reparents, Change{Type: ChangeReparent, GeoID: id, ParentGeoID: parentID, OldParentGeoID: oldParentID})
+			}
+		}
+	}
+	changes = append(changes, removedParents...)
+
+	// Attributes before geo levels, so that a location can meet the schema of its new geo level
+	var attributeChanges, levelChanges []Change
+	for _, id := range target.order {
+		loc := target.locations[id]
+		old, matched := source.locations[id]
+		if !matched {
+			continue
+		}
+		if !sameAttributes(old.attributes, loc.attributes) {
+			attributeChanges = append(attributeChanges, Change{Type: ChangeSetAttributes, GeoID: id, Attributes: loc.attributes})
+		}
+		if old.geoLevel != loc.geoLevel {
+			levelChanges = append(levelChanges, Change{Type: ChangeSetGeoLevel, GeoID: id, GeoLevel: loc.geoLevel, OldGeoLevel: old.geoLevel})
+		}
+	}
+	changes = append(changes, attributeChanges...)
+	changes = append(changes, levelChanges...)
+	changes = append(changes, reparents...)
+	changes = append(changes, addedParents...)
+
+	for _, id := range slices.Backward(source.order) {
+		if _, ok := target.locations[id]; !ok {
+			loc := source.locations[id]
+			changes = append(changes, Change{Type: ChangeRemoveLocation, GeoID: id, GeoLevel: loc.geoLevel, Name: loc.name})
+		}
+	}
+
+	return ChangeSet{Changes: changes}
+}
+
+// sameAttributes reports whether two sets of attributes have the same JSON encoding, so that
+// 3 and 3.0 are equal and no attributes equal empty ones
+func sameAttributes(a, b map[string]any) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	return err == nil && bytes.Equal(x, y)
+}
+
+type flatLocation struct {
+	geoLevel       string
+	name           string
+	aliases        []string
+	aliasLanguages map[string]string // language of the localized aliases, by alias
+	attributes     map[string]any
+	parents        map[string]string // parent geo_id by parent geo level
+}
+
+// flatTree is a tree document as locations by geo_id, in top-down order
+type flatTree struct {
+	order     []string
+	locations map[string]*flatLocation
+	byName    map[[3]string]string // geo_id by parent geo_id, geo level and primary name
+}
+
+// flattenTree flattens the tree. Nodes without geo_id are matched against match, if given,
+// or get a new geo_id.
+func flattenTree(tree Tree, match *flatTree) *flatTree {
+	flat := &flatTree{
+		locations: make(map[string]*flatLocation),
+		byName:    make(map[[3]string]string),
+	}
+
+	var walk func(node TreeNode, parentID string, parentLevel string)
+	walk = func(node TreeNode, parentID string, parentLevel string) {
+		key := [3]string{parentID, node.GeoLevel, node.Name}
+		id := node.GeoID
+		if id == "" && match != nil {
+			id = match.byName[key]
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		flat.byName[key] = id
+
+		loc, ok := flat.locations[id]
+		if !ok {
+			loc = &flatLocation{
+				geoLevel:       node.GeoLevel,
+				name:           node.Name,
+				aliases:        node.Aliases,
+				aliasLanguages: node.AliasLanguages,
+				attributes:     node.Attributes,
+				parents:        make(map[string]string),
+			}
+			flat.locations[id] = loc
+			flat.order = append(flat.order, id)
+		}
+		if parentID != "" {
+			loc.parents[parentLevel] = parentID
+		}
+		for _, child := range node.Children {
+			walk(child, id, node.GeoLevel)
+		}
+	}
+	for _, root := range tree.Roots {
+		walk(root, "", "")
+	}
+
+	return flat
+}
@@ -0,0 +1,246 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTrees(t *testing.T) {
+	const (
+		india     = "00000000-0000-0000-0000-000000000001"
+		kerala    = "00000000-0000-0000-0000-000000000002"
+		tamilNadu = "00000000-0000-0000-0000-000000000003"
+		ernakulam = "00000000-0000-0000-0000-000000000004"
+		idukki    = "00000000-0000-0000-0000-000000000005"
+	)
+	levels := []GeoLevel{{Name: "COUNTRY"}, {Name: "STATE"}, {Name: "DISTRICT"}}
+	from := Tree{
+		GeoLevels: levels,
+		Roots: []TreeNode{{
+			GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat"},
+			Children: []TreeNode{
+				{GeoID: kerala, GeoLevel: "STATE", Name: "Kerala", Children: []TreeNode{
+					{GeoID: ernakulam, GeoLevel: "DISTRICT", Name: "Ernakulam", Aliases: []string{"Cochin"}},
+					{GeoID: idukki, GeoLevel: "DISTRICT", Name: "Idukki"},
+				}},
+				{GeoID: tamilNadu, GeoLevel: "STATE", Name: "Tamil Nadu"},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name string
+		to   Tree
+		want []Change
+	}{
+		{
+			name: "identical trees",
+			to:   from,
+			want: []Change{},
+		},
+		{
+			name: "rename keeps the old name as alias",
+			to: Tree{GeoLevels: levels, Roots: []TreeNode{{
+				GeoID: india, GeoLevel: "COUNTRY", Name: "Bharat", Aliases: []string{"India"},
+				Children: from.Roots[0].Children,
+			}}},
+			want: []Change{
+				{Type: ChangeRename, GeoID: india, Name: "Bharat", OldName: "India"},
+			},
+		},
+		{
+			name: "alias changes",
+			to: Tree{GeoLevels: levels, Roots: []TreeNode{{
+				GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Hindustan"},
+				Children: from.Roots[0].Children,
+			}}},
+			want: []Change{
+				{Type: ChangeAddAlias, GeoID: india, Name: "Hindustan"},
+				{Type: ChangeRemoveAlias, GeoID: india, Name: "Bharat"},
+			},
+		},
+		{
+			name: "alias languages",
+			to: Tree{GeoLevels: levels, Roots: []TreeNode{{
+				GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat", "Bhārat"},
+				AliasLanguages: map[string]string{"Bharat": "hi", "Bhārat": "sa"},
+				Children:       from.Roots[0].Children,
+			}}},
+			want: []Change{
+				{Type: ChangeAddAlias, GeoID: india, Name: "Bhārat", Language: "sa"},
+				{Type: ChangeRemoveAlias, GeoID: india, Name: "Bharat"},
+				{Type: ChangeAddAlias, GeoID: india, Name: "Bharat", Language: "hi"},
+			},
+		},
+		{
+			name: "geo level and attribute changes",
+			to: Tree{GeoLevels: levels, Roots: []TreeNode{{
+				GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat"},
+				Children: []TreeNode{
+					{GeoID: kerala, GeoLevel: "STATE", Name: "Kerala", Children: []TreeNode{
+						{GeoID: ernakulam, GeoLevel: "DISTRICT", Name: "Ernakulam", Aliases: []string{"Cochin"}, Attributes: map[string]any{"census_code": "032"}},
+					}},
+					{GeoID: tamilNadu, GeoLevel: "STATE", Name: "Tamil Nadu"},
+					{GeoID: idukki, GeoLevel: "STATE", Name: "Idukki"},
+				},
+			}}},
+			want: []Change{
+				{Type: ChangeRemoveParent, GeoID: idukki, OldParentGeoID: kerala},
+				{Type: ChangeSetAttributes, GeoID: ernakulam, Attributes: map[string]any{"census_code": "032"}},
+				{Type: ChangeSetGeoLevel, GeoID: idukki, GeoLevel: "STATE", OldGeoLevel: "DISTRICT"},
+				{Type: ChangeAddParent, GeoID: idukki, ParentGeoID: india},
+			},
+		},
+		{
+			name: "re-parent, add and remove",
+			to: Tree{GeoLevels: append(levels, GeoLevel{Name: "TALUK", AttributeSchema: AttributeSchema{"code": {Required: true}}}), Roots: []TreeNode{{
+				GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat"},
+				Children: []TreeNode{
+					{GeoID: kerala, GeoLevel: "STATE", Name: "Kerala", Children: []TreeNode{
+						{GeoID: ernakulam, GeoLevel: "DISTRICT", Name: "Ernakulam", Aliases: []string{"Cochin"}},
+					}},
+					{GeoID: tamilNadu, GeoLevel: "STATE", Name: "Tamil Nadu", Children: []TreeNode{
+						{GeoID: idukki, GeoLevel: "DISTRICT", Name: "Idukki"},
+					}},
+				},
+			}}},
+			want: []Change{
+				{Type: ChangeAddGeoLevel, GeoLevel: "TALUK", AttributeSchema: AttributeSchema{"code": {Required: true}}},
+				{Type: ChangeReparent, GeoID: idukki, ParentGeoID: tamilNadu, OldParentGeoID: kerala},
+			},
+		},
+		{
+			name: "removed subtree",
+			to: Tree{GeoLevels: levels, Roots: []TreeNode{{
+				GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat"},
+				Children: []TreeNode{from.Roots[0].Children[1]},
+			}}},
+			want: []Change{
+				{Type: ChangeRemoveLocation, GeoID: idukki, GeoLevel: "DISTRICT", Name: "Idukki"},
+				{Type: ChangeRemoveLocation, GeoID: ernakulam, GeoLevel: "DISTRICT", Name: "Ernakulam"},
+				{Type: ChangeRemoveLocation, GeoID: kerala, GeoLevel: "STATE", Name: "Kerala"},
+			},
+		},
+		{
+			name: "nodes without geo_id match by parent, level and name",
+			to: Tree{GeoLevels: levels, Roots: []TreeNode{{
+				GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat"},
+				Children: []TreeNode{
+					{GeoLevel: "STATE", Name: "Kerala", Children: []TreeNode{
+						{GeoLevel: "DISTRICT", Name: "Ernakulam", Aliases: []string{"Cochin"}},
+						{GeoLevel: "DISTRICT", Name: "Idukki"},
+					}},
+					{GeoLevel: "STATE", Name: "Tamil Nadu"},
+				},
+			}}},
+			want: []Change{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffTrees(from, tt.to)
+			assert.Equal(t, tt.want, got.Changes)
+		})
+	}
+
+	t.Run("added location gets a geo_id and its parent", func(t *testing.T) {
+		to := Tree{GeoLevels: levels, Roots: []TreeNode{{
+			GeoID: india, GeoLevel: "COUNTRY", Name: "India", Aliases: []string{"Bharat"},
+			Children: append(from.Roots[0].Children, TreeNode{GeoLevel: "STATE", Name: "Goa", Aliases: []string{"Gomantak"}, Attributes: map[string]any{"capital": "Panaji"}}),
+		}}}
+		got := DiffTrees(from, to).Changes
+		require.Len(t, got, 3)
+		assert.Equal(t, ChangeAddLocation, got[0].Type)
+		assert.Equal(t, "Goa", got[0].Name)
+		assert.Equal(t, map[string]any{"capital": "Panaji"}, got[0].Attributes)
+		assert.NotEmpty(t, got[0].GeoID)
+		assert.Equal(t, Change{Type: ChangeAddAlias, GeoID: got[0].GeoID, Name: "Gomantak"}, got[1])
+		assert.Equal(t, Change{Type: ChangeAddParent, GeoID: got[0].GeoID, ParentGeoID: india}, got[2])
+	})
+}
+
+func TestServiceOnPostgres_ApplyChangeSet(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	tamilNadu := createTestLocation(t, service, "STATE", "Tamil Nadu")
+	require.NoError(t, service.AddParent(ctx, tamilNadu.GeoID, locs["India"].GeoID))
+
+	live, err := service.ExportTree(ctx, nil)
+	require.NoError(t, err)
+
+	// The new release renames Kochi, moves Ernakulam to Tamil Nadu and adds Thrissur to Kerala
+	release := *live
+	india := release.Roots[0]
+	kerala, tn := india.Children[0], india.Children[1]
+	ernakulam := kerala.Children[0]
+	ernakulam.Children[0].Name = "Cochin"
+	ernakulam.Children[0].Aliases = []string{"Kochi"}
+	kerala.Children = []TreeNode{{GeoLevel: "DISTRICT", Name: "Thrissur"}}
+	tn.Children = []TreeNode{ernakulam}
+	india.Children = []TreeNode{kerala, tn}
+	release.Roots = []TreeNode{india}
+
+	changeSet, err := service.DiffLive(ctx, release, nil)
+	require.NoError(t, err)
+	types := make([]ChangeType, 0, len(changeSet.Changes))
+	for _, change := range changeSet.Changes {
+		types = append(types, change.Type)
+	}
+	assert.Equal(t, []ChangeType{ChangeAddLocation, ChangeRename, ChangeReparent, ChangeAddParent}, types)
+
+	require.NoError(t, service.ApplyChangeSet(ctx, changeSet))
+
+	changeSet, err = service.DiffLive(ctx, release, nil)
+	require.NoError(t, err)
+	// Thrissur now exists under Kerala and is matched by name
+	assert.Empty(t, changeSet.Changes)
+
+	kochi, err := service.GetLocation(ctx, locs["Kochi"].GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, "Cochin", kochi.Name)
+	assert.Equal(t, []string{"Kochi"}, kochi.Aliases)
+
+	parent, err := service.GetParentAtLevel(ctx, locs["Ernakulam"].GeoID, "STATE")
+	require.NoError(t, err)
+	assert.Equal(t, tamilNadu.GeoID, parent.GeoID)
+
+	t.Run("attribute and geo level changes", func(t *testing.T) {
+		createTestGeoLevel(t, service, "TALUK", float64Ptr(3.5))
+		live, err := service.ExportTree(ctx, nil)
+		require.NoError(t, err)
+		// India > Kerala > Thrissur: Thrissur becomes a taluk with a census code
+		thrissur := &live.Roots[0].Children[0].Children[0]
+		require.Equal(t, "Thrissur", thrissur.Name)
+		thrissur.GeoLevel = "TALUK"
+		thrissur.Attributes = map[string]any{"census_code": "598"}
+
+		changeSet, err := service.DiffLive(ctx, *live, nil)
+		require.NoError(t, err)
+		require.Len(t, changeSet.Changes, 2)
+		assert.Equal(t, ChangeSetAttributes, changeSet.Changes[0].Type)
+		assert.Equal(t, ChangeSetGeoLevel, changeSet.Changes[1].Type)
+		require.NoError(t, service.ApplyChangeSet(ctx, changeSet))
+
+		changeSet, err = service.DiffLive(ctx, *live, nil)
+		require.NoError(t, err)
+		assert.Empty(t, changeSet.Changes)
+	})
+
+	t.Run("failing change rolls back the change set", func(t *testing.T) {
+		err := service.ApplyChangeSet(ctx, ChangeSet{Changes: []Change{
+			{Type: ChangeRename, GeoID: locs["India"].GeoID, Name: "Bharat"},
+			{Type: ChangeAddParent, GeoID: locs["India"].GeoID, ParentGeoID: locs["Kochi"].GeoID},
+		}})
+		assert.Error(t, err)
+
+		india, err := service.GetLocation(ctx, locs["India"].GeoID)
+		require.NoError(t, err)
+		assert.Equal(t, "India", india.Name)
+	})
+}
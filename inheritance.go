@@ -0,0 +1,34 @@
+package location
+
+import "context"
+
+// EffectiveAttribute is the value of an attribute for a location with the location supplying it
+type EffectiveAttribute struct {
+	Value       any    `json:"value"`
+	SourceGeoID string `json:"source_geo_id"` // the location itself, or the ancestor it inherits from
+	Inherited   bool   `json:"inherited"`
+}
+
+// GetEffectiveAttributes returns the attributes of a location together with the attributes it
+// inherits: those the attribute schema of its geo level marks as inherited and that it does not
+// set itself, taken from its nearest ancestor that sets them. A location setting an inherited
+// attribute overrides it for itself and for the locations below it.
+func (service *ServiceOnPostgres) GetEffectiveAttributes(ctx context.Context, geoID string) (map[string]EffectiveAttribute, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := service.db.GetEffectiveAttributes(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]EffectiveAttribute, len(attributes))
+	for key, attribute := range attributes {
+		out[key] = EffectiveAttribute{
+			Value:       attribute.Value,
+			SourceGeoID: attribute.SourceID.String(),
+			Inherited:   attribute.Depth > 0,
+		}
+	}
+	return out, nil
+}
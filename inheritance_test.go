@@ -0,0 +1,45 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceOnPostgres_GetEffectiveAttributes(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	_, err := service.SetAttributes(ctx, locs["India"].GeoID, map[string]any{"timezone": "Asia/Kolkata", "locale": "en-IN"})
+	require.NoError(t, err)
+	_, err = service.SetAttributes(ctx, locs["Kerala"].GeoID, map[string]any{"locale": "ml-IN", "tax_jurisdiction": "KL"})
+	require.NoError(t, err)
+	_, err = service.SetAttributes(ctx, locs["Kochi"].GeoID, map[string]any{"population": 2.1e6})
+	require.NoError(t, err)
+	_, err = service.SetAttributeSchema(ctx, "CITY", AttributeSchema{
+		"timezone":         {Inherited: true},
+		"locale":           {Inherited: true},
+		"tax_jurisdiction": {Inherited: true},
+	})
+	require.NoError(t, err)
+
+	attributes, err := service.GetEffectiveAttributes(ctx, locs["Kochi"].GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]EffectiveAttribute{
+		"population":       {Value: 2.1e6, SourceGeoID: locs["Kochi"].GeoID},
+		"timezone":         {Value: "Asia/Kolkata", SourceGeoID: locs["India"].GeoID, Inherited: true},
+		"locale":           {Value: "ml-IN", SourceGeoID: locs["Kerala"].GeoID, Inherited: true},
+		"tax_jurisdiction": {Value: "KL", SourceGeoID: locs["Kerala"].GeoID, Inherited: true},
+	}, attributes)
+
+	_, err = service.PatchAttributes(ctx, locs["Ernakulam"].GeoID, map[string]any{"tax_jurisdiction": "KL-EKM"})
+	require.NoError(t, err)
+	attributes, err = service.GetEffectiveAttributes(ctx, locs["Kochi"].GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, EffectiveAttribute{Value: "KL-EKM", SourceGeoID: locs["Ernakulam"].GeoID, Inherited: true}, attributes["tax_jurisdiction"])
+
+	_, err = service.GetEffectiveAttributes(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+}
@@ -1,26 +1,72 @@
 package location
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type LocationService interface {
+	InTx(ctx context.Context, fn func(tx LocationService) error) error
 	AddLocation(ctx context.Context, geoID string, geoLevel string, name string) (Location, error)
+	AddLocationWithAttributes(ctx context.Context, geoID string, geoLevel string, name string, attributes map[string]any) (Location, error)
+	AddChildLocation(ctx context.Context, parentGeoID string, geoLevel string, name string, attributes map[string]any) (Location, error)
 	UpdateLocation(ctx context.Context, geoID string, name *string, geoLevel *string) (Location, error)
 	AddGeoLevel(ctx context.Context, name string, rank *float64) error
 	UpdateGeoLevel(ctx context.Context, name string, newName *string, newRank *float64) error
+	PreviewGeoLevelUpdate(ctx context.Context, name string, newRank *float64) ([]RelationViolation, error)
+	PreviewLocationUpdate(ctx context.Context, geoID string, geoLevel string) ([]RelationViolation, error)
+	GetGeoLevel(ctx context.Context, name string) (*GeoLevel, error)
+	ListGeoLevels(ctx context.Context) ([]GeoLevel, error)
+	GetGeoLevelsByPattern(ctx context.Context, name string) ([]GeoLevel, error)
+	DeleteGeoLevel(ctx context.Context, name string, reassignTo *string) error
+	AddLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevels []string) ([]RelationViolation, error)
+	RemoveLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevel string) error
+	ListLevelRules(ctx context.Context) ([]LevelRule, error)
+	GetLevelRuleViolations(ctx context.Context) ([]RelationViolation, error)
 	AddAliasToLocation(ctx context.Context, geoID string, name string) error
 	RemoveAlias(ctx context.Context, geoID string, name string) error
 	AddParent(ctx context.Context, geoID string, parentGeoID string) error
 	RemoveParent(ctx context.Context, geoID string, parentGeoID string) error
+	MoveLocation(ctx context.Context, geoID string, newParentGeoID string, checkDescendants bool) ([]RelationViolation, error)
 	AddChildren(ctx context.Context, geoID string, childGeoIDs []string) error
 	RemoveChildren(ctx context.Context, geoID string, childGeoIDs []string) error
 	DeleteLocation(ctx context.Context, geoID string) error
+	MergeLocations(ctx context.Context, survivorGeoID string, duplicateGeoIDs []string) (*Location, []RelationViolation, error)
+	SplitLocation(ctx context.Context, geoID string, successorNames []string, childAssignments map[string]string) ([]Location, error)
+	RestoreLocation(ctx context.Context, geoID string) (*Location, []RelationViolation, error)
+	ListDeletedLocations(ctx context.Context) ([]DeletedLocation, error)
+	PurgeDeleted(ctx context.Context, retention time.Duration) (PurgeResult, error)
+	Check(ctx context.Context, repair bool) (CheckReport, error)
+	ExportTree(ctx context.Context, rootGeoID *string) (*Tree, error)
+	ImportTree(ctx context.Context, tree Tree) (ImportResult, error)
+	DiffLive(ctx context.Context, tree Tree, rootGeoID *string) (ChangeSet, error)
+	ApplyChangeSet(ctx context.Context, changeSet ChangeSet) error
 	GetLocation(ctx context.Context, geoID string) (*Location, error)
 	GetLocations(ctx context.Context, geoIDs []string) ([]Location, error)
 	GetLocationsByPattern(ctx context.Context, name string, geoLevel *string) ([]Location, error)
+	SearchLocations(ctx context.Context, pattern string, opts SearchOptions) ([]SearchResult, error)
+	Autocomplete(ctx context.Context, prefix string, opts AutocompleteOptions) ([]Suggestion, error)
+	ListLocations(ctx context.Context, opts ListOptions) ([]Location, error)
+	GetAttributes(ctx context.Context, geoID string) (map[string]any, error)
+	SetAttributes(ctx context.Context, geoID string, attributes map[string]any) (map[string]any, error)
+	PatchAttributes(ctx context.Context, geoID string, patch map[string]any) (map[string]any, error)
+	IndexAttribute(ctx context.Context, key string) error
+	SetAttributeSchema(ctx context.Context, geoLevel string, schema AttributeSchema) ([]AttributeViolation, error)
+	GetAttributeViolations(ctx context.Context) ([]AttributeViolation, error)
+	GetEffectiveAttributes(ctx context.Context, geoID string) (map[string]EffectiveAttribute, error)
 	GetAllParents(ctx context.Context, geoID string) ([]Location, error)
 	GetParentAtLevel(ctx context.Context, geoID string, geoLevel string) (*Location, error)
 	GetAllChildren(ctx context.Context, geoID string) ([]Location, error)
 	GetChildrenAtLevel(ctx context.Context, geoID string, geoLevel string) ([]Location, error)
+	GetSiblings(ctx context.Context, geoID string, parentLevel string) ([]Location, error)
+	GetNeighborhood(ctx context.Context, geoID string, parentLevel string) (*Neighborhood, error)
+	CommonAncestor(ctx context.Context, geoLevel *string, geoIDs ...string) (*Location, error)
+	TreeDistance(ctx context.Context, geoID string, otherGeoID string) (int, error)
+	GetAncestry(ctx context.Context, geoIDs []string) (map[string]Ancestry, error)
+	AddLocalizedName(ctx context.Context, geoID string, language string, name string) error
+	GetPath(ctx context.Context, geoID string, opts PathOptions) (string, error)
+	ResolvePath(ctx context.Context, path string) (*Location, error)
+	ResolvePlace(ctx context.Context, text string, limit int) ([]PlaceCandidate, error)
 }
 
 type Location struct {
@@ -28,4 +74,166 @@ type Location struct {
 	GeoLevel string   `json:"geo_level"`
 	Name     string   `json:"name"`    // primary name of the location
 	Aliases  []string `json:"aliases"` // aliases of the location
+	// SupersededBy lists the geo_ids of the successors of a location that was split
+	SupersededBy []string `json:"superseded_by,omitempty"`
+	// Version is bumped by every change to the location, its names or its relations (see WithExpectedVersion)
+	Version int64 `json:"version"`
+	// Attributes are typed values such as population or timezone, set by GetLocation and ListLocations
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type GeoLevel struct {
+	Name          string   `json:"name"`
+	Rank          *float64 `json:"rank"`           // lower rank = higher in hierarchy, nil if unranked
+	LocationCount int64    `json:"location_count"` // number of locations at this geo level
+	// AttributeSchema constrains the attributes of the locations at this geo level
+	AttributeSchema AttributeSchema `json:"attribute_schema,omitempty"`
+}
+
+// AttributeType is the JSON type of an attribute value
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeBoolean AttributeType = "boolean"
+	AttributeArray   AttributeType = "array"
+	AttributeObject  AttributeType = "object"
+)
+
+// AttributeRule constrains one attribute of the locations of a geo level
+type AttributeRule struct {
+	Type     AttributeType `json:"type,omitempty"` // any type if empty
+	Required bool          `json:"required,omitempty"`
+	Enum     []any         `json:"enum,omitempty"` // allowed values, any if empty
+	// Pattern is a regular expression that string values must match as a whole, e.g. `\d{3}`
+	Pattern string `json:"pattern,omitempty"`
+	// Inherited makes locations without the attribute take it from their nearest ancestor
+	// that has it, see GetEffectiveAttributes. The other rules apply to own values only.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// AttributeSchema maps attribute keys to the rules the locations of a geo level must follow.
+// Attributes without a rule are unconstrained.
+type AttributeSchema map[string]AttributeRule
+
+// LevelRule lists the geo levels allowed as parents of locations at the child geo level
+type LevelRule struct {
+	ChildGeoLevel   string   `json:"child_geo_level"`
+	ParentGeoLevels []string `json:"parent_geo_levels"`
+}
+
+// RelationViolation describes an existing parent-child relation that breaks a hierarchy rule
+type RelationViolation struct {
+	ParentGeoID    string `json:"parent_geo_id"`
+	ParentGeoLevel string `json:"parent_geo_level"`
+	ChildGeoID     string `json:"child_geo_id"`
+	ChildGeoLevel  string `json:"child_geo_level"`
+	Reason         string `json:"reason"`
+}
+
+// AttributeViolation describes an attribute of a location that breaks the attribute schema
+// of its geo level
+type AttributeViolation struct {
+	GeoID    string `json:"geo_id"`
+	GeoLevel string `json:"geo_level"`
+	Key      string `json:"key"`
+	Reason   string `json:"reason"`
+}
+
+// DeletedLocation is a soft-deleted location that can still be restored
+type DeletedLocation struct {
+	Location
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// PurgeResult counts the soft-deleted rows permanently removed by a purge
+type PurgeResult struct {
+	Locations int64 `json:"locations"`
+	Names     int64 `json:"names"`
+	Relations int64 `json:"relations"`
+}
+
+// CheckFinding is an inconsistency found in the hierarchy data
+type CheckFinding struct {
+	GeoIDs   []string `json:"geo_ids"`             // locations involved
+	RecordID string   `json:"record_id,omitempty"` // offending name or relation, if any
+	Detail   string   `json:"detail"`
+	Repaired bool     `json:"repaired"`
+}
+
+// CheckReport groups the findings of a consistency check by category
+type CheckReport struct {
+	Findings map[string][]CheckFinding `json:"findings"`
+	Total    int                       `json:"total"`
+	Repaired int                       `json:"repaired"`
+}
+
+// TreeNode is a location with its children in a nested hierarchy document.
+// A location with parents of several geo levels appears under each of its parents.
+type TreeNode struct {
+	GeoID    string   `json:"geo_id,omitempty"` // empty for locations to be created with a new geo_id
+	GeoLevel string   `json:"geo_level"`
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	// AliasLanguages maps the aliases that are localized names to their language
+	AliasLanguages map[string]string `json:"alias_languages,omitempty"`
+	// Attributes of the location, which must follow the attribute schema of its geo level
+	Attributes map[string]any `json:"attributes,omitempty"`
+	Children   []TreeNode     `json:"children,omitempty"`
+}
+
+// Tree is a nested JSON document of a hierarchy or of a subtree
+type Tree struct {
+	GeoLevels []GeoLevel `json:"geo_levels"` // location_count counts the locations in the document
+	Roots     []TreeNode `json:"roots"`
+}
+
+// ImportResult counts what an import created and matched
+type ImportResult struct {
+	GeoLevels int `json:"geo_levels"` // geo levels created
+	Created   int `json:"created"`    // locations created
+	Matched   int `json:"matched"`    // existing locations matched by geo_id
+	Relations int `json:"relations"`  // relations created
+}
+
+// ChangeType is the kind of a change in a change set
+type ChangeType string
+
+const (
+	ChangeAddGeoLevel    ChangeType = "add_geo_level"
+	ChangeAddLocation    ChangeType = "add_location"
+	ChangeRename         ChangeType = "rename" // new primary name; the old one becomes an alias
+	ChangeAddAlias       ChangeType = "add_alias"
+	ChangeRemoveAlias    ChangeType = "remove_alias"
+	ChangeRemoveParent   ChangeType = "remove_parent"
+	ChangeReparent       ChangeType = "reparent" // new parent of the same geo level
+	ChangeAddParent      ChangeType = "add_parent"
+	ChangeSetAttributes  ChangeType = "set_attributes" // replaces all attributes
+	ChangeSetGeoLevel    ChangeType = "set_geo_level"
+	ChangeRemoveLocation ChangeType = "remove_location"
+)
+
+// Change is one step of a change set
+type Change struct {
+	Type           ChangeType `json:"type"`
+	GeoID          string     `json:"geo_id,omitempty"`
+	GeoLevel       string     `json:"geo_level,omitempty"`
+	Rank           *float64   `json:"rank,omitempty"`     // rank of an added geo level
+	Name           string     `json:"name,omitempty"`     // name of an added location, new primary name or alias
+	OldName        string     `json:"old_name,omitempty"` // primary name before a rename
+	ParentGeoID    string     `json:"parent_geo_id,omitempty"`
+	OldParentGeoID string     `json:"old_parent_geo_id,omitempty"`
+	OldGeoLevel    string     `json:"old_geo_level,omitempty"` // geo level before a geo level change
+	// Language of an added alias that is a localized name
+	Language string `json:"language,omitempty"`
+	// Attributes of an added location, or the new attributes of a location
+	Attributes map[string]any `json:"attributes,omitempty"`
+	// AttributeSchema of an added geo level
+	AttributeSchema AttributeSchema `json:"attribute_schema,omitempty"`
+}
+
+// ChangeSet is an ordered list of changes turning one hierarchy into another
+type ChangeSet struct {
+	Changes []Change `json:"changes"`
 }
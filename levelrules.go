@@ -0,0 +1,94 @@
+package location
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// AddLevelRule allows the parent geo levels for locations at the child geo level.
+// It returns the existing relations that violate the level rules of the child geo level
+// after the rules are added; those relations are reported, not removed.
+func (service *ServiceOnPostgres) AddLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevels []string) ([]RelationViolation, error) {
+	var childGeoLevelID uuid.UUID
+	err := service.transaction(ctx, func(store *postgres.Store) error {
+		for _, parentGeoLevel := range parentGeoLevels {
+			rule, err := store.InsertLevelRule(ctx, parentGeoLevel, childGeoLevel)
+			if err != nil {
+				return err
+			}
+			childGeoLevelID = rule.ChildGeoLevelID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(parentGeoLevels) == 0 {
+		return []RelationViolation{}, nil
+	}
+
+	relations, err := service.db.GetLevelRuleViolations(ctx, &childGeoLevelID)
+	if err != nil {
+		return nil, err
+	}
+	return levelRuleViolations(relations), nil
+}
+
+// RemoveLevelRule disallows the parent geo level for locations at the child geo level
+func (service *ServiceOnPostgres) RemoveLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevel string) error {
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		return store.DeleteLevelRule(ctx, parentGeoLevel, childGeoLevel)
+	})
+}
+
+// ListLevelRules returns the allowed parent geo levels grouped by child geo level
+func (service *ServiceOnPostgres) ListLevelRules(ctx context.Context) ([]LevelRule, error) {
+	rules, err := service.db.ListLevelRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []LevelRule
+	index := make(map[string]int)
+	for _, rule := range rules {
+		child := rule.ChildGeoLevel.Name
+		i, ok := index[child]
+		if !ok {
+			i = len(out)
+			index[child] = i
+			out = append(out, LevelRule{ChildGeoLevel: child, ParentGeoLevels: []string{}})
+		}
+		out[i].ParentGeoLevels = append(out[i].ParentGeoLevels, rule.ParentGeoLevel.Name)
+	}
+	return out, nil
+}
+
+// GetLevelRuleViolations reports all existing relations that are not allowed by the level rules
+func (service *ServiceOnPostgres) GetLevelRuleViolations(ctx context.Context) ([]RelationViolation, error) {
+	relations, err := service.db.GetLevelRuleViolations(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return levelRuleViolations(relations), nil
+}
+
+func levelRuleViolations(relations []postgres.Relation) []RelationViolation {
+	out := make([]RelationViolation, 0, len(relations))
+	for _, rel := range relations {
+		violation := RelationViolation{
+			ParentGeoID: rel.ParentID.String(),
+			ChildGeoID:  rel.ChildID.String(),
+			Reason:      postgres.ErrLevelRuleViolation.Error(),
+		}
+		if rel.Parent != nil {
+			violation.ParentGeoLevel = rel.Parent.GeoLevel.Name
+		}
+		if rel.Child != nil {
+			violation.ChildGeoLevel = rel.Child.GeoLevel.Name
+		}
+		out = append(out, violation)
+	}
+	return out
+}
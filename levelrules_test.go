@@ -0,0 +1,71 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_AddLevelRule(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	createTestGeoLevel(t, service, "COUNTRY", float64Ptr(1.0))
+	createTestGeoLevel(t, service, "STATE", float64Ptr(2.0))
+	createTestGeoLevel(t, service, "UNION_TERRITORY", float64Ptr(2.0))
+	createTestGeoLevel(t, service, "DISTRICT", float64Ptr(3.0))
+	country := createTestLocation(t, service, "COUNTRY", "India")
+	state := createTestLocation(t, service, "STATE", "Kerala")
+	district := createTestLocation(t, service, "DISTRICT", "Ernakulam")
+	strayDistrict := createTestLocation(t, service, "DISTRICT", "Stray")
+	require.NoError(t, service.AddParent(ctx, district.GeoID, state.GeoID))
+	require.NoError(t, service.AddParent(ctx, strayDistrict.GeoID, country.GeoID))
+
+	t.Run("existing violations are reported", func(t *testing.T) {
+		violations, err := service.AddLevelRule(ctx, "DISTRICT", []string{"STATE", "UNION_TERRITORY"})
+		require.NoError(t, err)
+		require.Len(t, violations, 1)
+		assert.Equal(t, country.GeoID, violations[0].ParentGeoID)
+		assert.Equal(t, "COUNTRY", violations[0].ParentGeoLevel)
+		assert.Equal(t, strayDistrict.GeoID, violations[0].ChildGeoID)
+		assert.Equal(t, "DISTRICT", violations[0].ChildGeoLevel)
+	})
+
+	t.Run("rules are enforced on AddParent", func(t *testing.T) {
+		other := createTestLocation(t, service, "DISTRICT", "Other")
+		err := service.AddParent(ctx, other.GeoID, country.GeoID)
+		assert.ErrorIs(t, err, postgres.ErrLevelRuleViolation)
+	})
+
+	t.Run("rules are enforced on AddChildren", func(t *testing.T) {
+		other := createTestLocation(t, service, "DISTRICT", "Another")
+		err := service.AddChildren(ctx, country.GeoID, []string{other.GeoID})
+		assert.ErrorIs(t, err, postgres.ErrLevelRuleViolation)
+	})
+
+	t.Run("failing rule rolls back the batch", func(t *testing.T) {
+		_, err := service.AddLevelRule(ctx, "STATE", []string{"COUNTRY", "MISSING"})
+		assert.ErrorIs(t, err, postgres.ErrGeoLevelNotFound)
+
+		rules, err := service.ListLevelRules(ctx)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, "DISTRICT", rules[0].ChildGeoLevel)
+		assert.ElementsMatch(t, []string{"STATE", "UNION_TERRITORY"}, rules[0].ParentGeoLevels)
+	})
+
+	t.Run("violations can be listed later", func(t *testing.T) {
+		violations, err := service.GetLevelRuleViolations(ctx)
+		require.NoError(t, err)
+		assert.Len(t, violations, 1)
+	})
+
+	t.Run("removing a rule", func(t *testing.T) {
+		require.NoError(t, service.RemoveLevelRule(ctx, "DISTRICT", "UNION_TERRITORY"))
+		err := service.RemoveLevelRule(ctx, "DISTRICT", "UNION_TERRITORY")
+		assert.ErrorIs(t, err, postgres.ErrLevelRuleNotFound)
+	})
+}
@@ -19,36 +19,76 @@ type ServiceOnPostgres struct {
 var _ LocationService = (*ServiceOnPostgres)(nil)
 
 func NewServiceOnPostgres(db *gorm.DB) (*ServiceOnPostgres, error) {
-	return &ServiceOnPostgres{db: postgres.Store{DB: db}}, nil
+	store, err := postgres.NewStore(db)
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceOnPostgres{db: *store}, nil
 }
 
 // AddLocation creates a new location
 func (service *ServiceOnPostgres) AddLocation(ctx context.Context, geoID string, geoLevel string, name string) (Location, error) {
-	loc, err := service.db.InsertLocation(ctx, geoLevel, name)
+	return service.AddLocationWithAttributes(ctx, geoID, geoLevel, name, nil)
+}
+
+// AddLocationWithAttributes creates a new location with its attributes, which must follow the
+// attribute schema of the geo level
+func (service *ServiceOnPostgres) AddLocationWithAttributes(ctx context.Context, geoID string, geoLevel string, name string, attributes map[string]any) (Location, error) {
+	var loc *postgres.Location
+	err := service.transaction(ctx, func(store *postgres.Store) error {
+		var err error
+		loc, err = store.InsertLocationWithAttributes(ctx, uuid.Nil, geoLevel, name, attributes)
+		return err
+	})
 	if err != nil {
 		return Location{}, err
 	}
 	return Location{
-		GeoID:    loc.Id.String(),
-		GeoLevel: loc.GeoLevel.Name,
-		Name:     name,
-		Aliases:  []string{},
+		GeoID:      loc.Id.String(),
+		GeoLevel:   loc.GeoLevel.Name,
+		Name:       name,
+		Aliases:    []string{},
+		Version:    loc.Version,
+		Attributes: loc.Attributes,
 	}, nil
 }
 
-// AddGeoLevel creates a new geo level
-func (service *ServiceOnPostgres) AddGeoLevel(ctx context.Context, name string, rank *float64) error {
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	store := &postgres.Store{DB: tx}
-	_, err := store.InsertGeoLevel(ctx, name, rank)
+// AddChildLocation creates a new location with its attributes as a child of an existing
+// location, in one transaction, so that callers granted only the subtree of the parent can
+// create it
+func (service *ServiceOnPostgres) AddChildLocation(ctx context.Context, parentGeoID string, geoLevel string, name string, attributes map[string]any) (Location, error) {
+	parentID, err := uuidFromString(parentGeoID)
 	if err != nil {
-		tx.Rollback()
+		return Location{}, err
+	}
+	var loc *postgres.Location
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		var err error
+		if loc, err = store.InsertLocationWithAttributes(ctx, uuid.Nil, geoLevel, name, attributes); err != nil {
+			return err
+		}
+		_, err = store.InsertRelation(ctx, parentID, loc.Id)
 		return err
+	})
+	if err != nil {
+		return Location{}, err
 	}
-	return tx.Commit().Error
+	return Location{
+		GeoID:      loc.Id.String(),
+		GeoLevel:   loc.GeoLevel.Name,
+		Name:       name,
+		Aliases:    []string{},
+		Version:    loc.Version,
+		Attributes: loc.Attributes,
+	}, nil
+}
+
+// AddGeoLevel creates a new geo level
+func (service *ServiceOnPostgres) AddGeoLevel(ctx context.Context, name string, rank *float64) error {
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		_, err := store.InsertGeoLevel(ctx, name, rank)
+		return err
+	})
 }
 
 // AddAliasToLocation adds an alias to a location
@@ -57,17 +97,9 @@ func (service *ServiceOnPostgres) AddAliasToLocation(ctx context.Context, geoID
 	if err != nil {
 		return err
 	}
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	store := &postgres.Store{DB: tx}
-	err = store.InsertNameMap(ctx, id, name, false)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	return tx.Commit().Error
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		return store.InsertNameMap(ctx, id, name, false)
+	})
 }
 
 // AddNewParent adds a new parent to a location.
@@ -80,17 +112,10 @@ func (service *ServiceOnPostgres) AddParent(ctx context.Context, geoID string, p
 	if err != nil {
 		return err
 	}
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	store := &postgres.Store{DB: tx}
-	_, err = store.InsertRelation(ctx, parentID, childID)
-	if err != nil {
-		tx.Rollback()
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		_, err := store.InsertRelation(ctx, parentID, childID)
 		return err
-	}
-	return tx.Commit().Error
+	})
 }
 
 // AddNewChildren adds new children to a location.
@@ -99,24 +124,18 @@ func (service *ServiceOnPostgres) AddChildren(ctx context.Context, geoID string,
 	if err != nil {
 		return err
 	}
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	store := &postgres.Store{DB: tx}
-	for _, child := range childGeoIDs {
-		childID, err := uuidFromString(child)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
-		_, err = store.InsertRelation(ctx, parentID, childID)
-		if err != nil {
-			tx.Rollback()
-			return err
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		for _, child := range childGeoIDs {
+			childID, err := uuidFromString(child)
+			if err != nil {
+				return err
+			}
+			if _, err := store.InsertRelation(ctx, parentID, childID); err != nil {
+				return err
+			}
 		}
-	}
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 // GetLocation retrieves a location by its geo ID
@@ -129,12 +148,8 @@ func (service *ServiceOnPostgres) GetLocation(ctx context.Context, geoID string)
 	if err != nil {
 		return nil, err
 	}
-	return &Location{
-		GeoID:    loc.Id.String(),
-		GeoLevel: loc.GeoLevel,
-		Name:     loc.Name,
-		Aliases:  loc.Aliases,
-	}, nil
+	location := fromLocationWithNames(*loc)
+	return &location, nil
 }
 
 // GetLocations retrieves multiple locations by their geo IDs
@@ -153,45 +168,14 @@ func (service *ServiceOnPostgres) GetLocations(ctx context.Context, geoIDs []str
 
 // GetLocationsByPattern finds locations matching the pattern of the name or one of the aliases
 func (service *ServiceOnPostgres) GetLocationsByPattern(ctx context.Context, name string, geoLevel *string) ([]Location, error) {
-	var out []Location
-	var geoLevelID *uuid.UUID
-	if geoLevel != nil {
-		geoLevelObject, err := service.db.GetGeoLevelByName(ctx, *geoLevel)
-		if err != nil {
-			return out, err
-		}
-		geoLevelID = &geoLevelObject.Id
-	}
-	names, err := service.db.SearchNamesByPattern(ctx, name)
+	results, err := service.SearchLocations(ctx, name, SearchOptions{GeoLevel: geoLevel})
 	if err != nil {
-		return out, err
-	}
-
-	aliases := make(map[uuid.UUID][]string)
-	nameMap := make(map[uuid.UUID]Location)
-	for _, name := range names {
-		if name.IsPrimary {
-			if geoLevelID != nil && name.Location.GeoLevelID != *geoLevelID {
-				continue
-			}
-			loc := name.Location
-			nameMap[loc.Id] = Location{
-				GeoID:    loc.Id.String(),
-				GeoLevel: loc.GeoLevelID.String(),
-				Name:     name.Name,
-				Aliases:  []string{},
-			}
-		} else {
-			aliases[name.LocationID] = append(aliases[name.LocationID], name.Name)
-		}
+		return nil, err
 	}
-	for locID, loc := range nameMap {
-		if alias, ok := aliases[locID]; ok {
-			loc.Aliases = alias
-		}
-		out = append(out, loc)
+	out := make([]Location, 0, len(results))
+	for _, result := range results {
+		out = append(out, result.Location)
 	}
-
 	return out, nil
 }
 
@@ -227,6 +211,7 @@ func (service *ServiceOnPostgres) GetAllParents(ctx context.Context, geoID strin
 				GeoLevel: rel.Parent.GeoLevel.Name,
 				Name:     primaryName,
 				Aliases:  aliases,
+				Version:  rel.Parent.Version,
 			})
 		}
 	}
@@ -265,6 +250,7 @@ func (service *ServiceOnPostgres) GetAllChildren(ctx context.Context, geoID stri
 				GeoLevel: rel.Child.GeoLevel.Name,
 				Name:     primaryName,
 				Aliases:  aliases,
+				Version:  rel.Child.Version,
 			})
 		}
 	}
@@ -277,45 +263,112 @@ func (service *ServiceOnPostgres) UpdateLocation(ctx context.Context, geoID stri
 	if err != nil {
 		return Location{}, err
 	}
-	_, err = service.db.UpdateLocation(ctx, id, geoLevel, name)
-	if err != nil {
-		return Location{}, err
-	}
-	updatedLoc, err := service.db.GetLocation(ctx, id)
+	var updatedLoc *postgres.LocationWithNames
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		if _, err := store.UpdateLocation(ctx, id, geoLevel, name); err != nil {
+			return err
+		}
+		updatedLoc, err = store.GetLocation(ctx, id)
+		return err
+	})
 	if err != nil {
 		return Location{}, err
 	}
 	return Location{
-		GeoID:    updatedLoc.Id.String(),
-		GeoLevel: updatedLoc.GeoLevel,
-		Name:     updatedLoc.Name,
-		Aliases:  updatedLoc.Aliases,
+		GeoID:      updatedLoc.Id.String(),
+		GeoLevel:   updatedLoc.GeoLevel,
+		Name:       updatedLoc.Name,
+		Aliases:    updatedLoc.Aliases,
+		Version:    updatedLoc.Version,
+		Attributes: updatedLoc.Attributes,
 	}, nil
 }
 
 // UpdateGeoLevel updates a geo level by its name
 func (service *ServiceOnPostgres) UpdateGeoLevel(ctx context.Context, name string, newName *string, newRank *float64) error {
-	_, err := service.db.UpdateGeoLevel(ctx, name, newName, newRank)
-	return err
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		_, err := store.UpdateGeoLevel(ctx, name, newName, newRank)
+		return err
+	})
 }
 
-// RemoveAlias removes an alias from a location
-func (service *ServiceOnPostgres) RemoveAlias(ctx context.Context, geoID string, name string) error {
-	id, err := uuidFromString(geoID)
+// GetGeoLevel retrieves a geo level by its name
+func (service *ServiceOnPostgres) GetGeoLevel(ctx context.Context, name string) (*GeoLevel, error) {
+	level, err := service.db.GetGeoLevelByName(ctx, name)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+	counts, err := service.db.CountLocationsByGeoLevel(ctx, level.Id)
+	if err != nil {
+		return nil, err
 	}
-	store := &postgres.Store{DB: tx}
-	err = store.DeleteNameMap(ctx, id, name)
+	return &GeoLevel{
+		Name:            level.Name,
+		Rank:            level.Rank,
+		LocationCount:   counts[level.Id],
+		AttributeSchema: fromStoreSchema(level.AttributeSchema),
+	}, nil
+}
+
+// ListGeoLevels returns all geo levels ordered by rank
+func (service *ServiceOnPostgres) ListGeoLevels(ctx context.Context) ([]GeoLevel, error) {
+	levels, err := service.db.ListGeoLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return service.geoLevelsWithCounts(ctx, levels)
+}
+
+// GetGeoLevelsByPattern finds geo levels whose name matches the pattern
+func (service *ServiceOnPostgres) GetGeoLevelsByPattern(ctx context.Context, name string) ([]GeoLevel, error) {
+	levels, err := service.db.GetGeoLevelsByPattern(ctx, name)
+	if err != nil {
+		if errors.Is(err, postgres.ErrGeoLevelNotFound) {
+			return []GeoLevel{}, nil
+		}
+		return nil, err
+	}
+	return service.geoLevelsWithCounts(ctx, levels)
+}
+
+// DeleteGeoLevel deletes a geo level by its name.
+// If reassignTo is given, all locations of the geo level are first moved to that level,
+// otherwise the deletion fails when the geo level is still in use.
+func (service *ServiceOnPostgres) DeleteGeoLevel(ctx context.Context, name string, reassignTo *string) error {
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		if reassignTo != nil {
+			return store.DeleteGeoLevelWithReassignment(ctx, name, *reassignTo)
+		}
+		return store.DeleteGeoLevel(ctx, name)
+	})
+}
+
+func (service *ServiceOnPostgres) geoLevelsWithCounts(ctx context.Context, levels []postgres.GeoLevel) ([]GeoLevel, error) {
+	counts, err := service.db.CountLocationsByGeoLevel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]GeoLevel, 0, len(levels))
+	for _, level := range levels {
+		out = append(out, GeoLevel{
+			Name:            level.Name,
+			Rank:            level.Rank,
+			LocationCount:   counts[level.Id],
+			AttributeSchema: fromStoreSchema(level.AttributeSchema),
+		})
+	}
+	return out, nil
+}
+
+// RemoveAlias removes an alias from a location
+func (service *ServiceOnPostgres) RemoveAlias(ctx context.Context, geoID string, name string) error {
+	id, err := uuidFromString(geoID)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	return tx.Commit().Error
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		return store.DeleteNameMap(ctx, id, name)
+	})
 }
 
 // RemoveParent removes a parent from a location
@@ -324,59 +377,67 @@ func (service *ServiceOnPostgres) RemoveParent(ctx context.Context, geoID string
 	if err != nil {
 		return err
 	}
-	relations, err := service.db.GetParents(ctx, childID)
-	if err != nil {
-		return err
-	}
 	parentID, err := uuidFromString(parentGeoID)
 	if err != nil {
 		return err
 	}
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
-	}
-	store := &postgres.Store{DB: tx}
-	for _, rel := range relations {
-		if rel.ParentID == parentID {
-			err := store.DeleteRelation(ctx, rel.Id)
-			if err != nil {
-				tx.Rollback()
-				return err
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		relations, err := store.GetParents(ctx, childID)
+		if err != nil {
+			return err
+		}
+		for _, rel := range relations {
+			if rel.ParentID == parentID {
+				return store.DeleteRelation(ctx, rel.Id)
 			}
-			return tx.Commit().Error
 		}
-	}
-	tx.Rollback()
-	return fmt.Errorf("relation not found for parent %s and child %s", parentGeoID, geoID)
+		return fmt.Errorf("relation not found for parent %s and child %s", parentGeoID, geoID)
+	})
 }
 
-// RemoveChildren removes a child from a location.
-func (service *ServiceOnPostgres) RemoveChildren(ctx context.Context, geoID string, childGeoIDs []string) error {
-	parentID, err := uuidFromString(geoID)
+// MoveLocation atomically replaces the parent of a location at the new parent's geo level.
+// With checkDescendants, the move is rejected if descendants of the location have a direct
+// parent of that geo level other than the new parent; those relations are returned.
+func (service *ServiceOnPostgres) MoveLocation(ctx context.Context, geoID string, newParentGeoID string, checkDescendants bool) ([]RelationViolation, error) {
+	id, err := uuidFromString(geoID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	relations, err := service.db.GetChildren(ctx, parentID)
+	newParentID, err := uuidFromString(newParentGeoID)
 	if err != nil {
+		return nil, err
+	}
+	var conflicts []postgres.RelationConflict
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		conflicts, err = store.MoveLocation(ctx, id, newParentID, checkDescendants)
 		return err
+	})
+	if err != nil {
+		return relationConflictViolations(conflicts), err
 	}
+	return []RelationViolation{}, nil
+}
 
-	tx := service.db.Begin()
-	if tx.Error != nil {
-		return tx.Error
+// RemoveChildren removes a child from a location.
+func (service *ServiceOnPostgres) RemoveChildren(ctx context.Context, geoID string, childGeoIDs []string) error {
+	parentID, err := uuidFromString(geoID)
+	if err != nil {
+		return err
 	}
-	store := &postgres.Store{DB: tx}
-	for _, rel := range relations {
-		if slices.Contains(childGeoIDs, rel.ChildID.String()) {
-			err := store.DeleteRelation(ctx, rel.Id)
-			if err != nil {
-				tx.Rollback()
-				return err
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		relations, err := store.GetChildren(ctx, parentID)
+		if err != nil {
+			return err
+		}
+		for _, rel := range relations {
+			if slices.Contains(childGeoIDs, rel.ChildID.String()) {
+				if err := store.DeleteRelation(ctx, rel.Id); err != nil {
+					return err
+				}
 			}
 		}
-	}
-	return tx.Commit().Error
+		return nil
+	})
 }
 
 // DeleteLocation deletes a location by its geo ID
@@ -387,7 +448,9 @@ func (service *ServiceOnPostgres) DeleteLocation(ctx context.Context, geoID stri
 	if err != nil {
 		return err
 	}
-	return service.db.DeleteLocation(ctx, id)
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		return store.DeleteLocation(ctx, id)
+	})
 }
 
 // GetChildrenAtLevel returns the children of a location at a specific geo level.
@@ -437,10 +500,13 @@ func (service *ServiceOnPostgres) GetParentAtLevel(ctx context.Context, geoID st
 	return nil, fmt.Errorf("parent at level %s not found", geoLevel)
 }
 
+// ErrInvalidGeoID is returned for a geo_id that is not a UUID
+var ErrInvalidGeoID = errors.New("invalid UUID format")
+
 func uuidFromString(s string) (uuid.UUID, error) {
 	uid, err := uuid.Parse(s)
 	if err != nil {
-		return uuid.Nil, errors.Join(err, fmt.Errorf("invalid UUID format"))
+		return uuid.Nil, errors.Join(err, ErrInvalidGeoID)
 	}
 	return uid, nil
 }
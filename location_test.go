@@ -70,13 +70,13 @@ func setupTestDB(t *testing.T) *ServiceOnPostgres {
 
 connected:
 	// Auto migrate the schemas
-	err := db.AutoMigrate(&postgres.GeoLevel{}, &postgres.Location{}, &postgres.NameMap{}, &postgres.Relation{})
+	err := postgres.Migrate(db)
 	if err != nil {
-		t.Fatalf("Failed to auto-migrate schemas: %v", err)
+		t.Fatalf("Failed to migrate schemas: %v", err)
 	}
 
 	// Truncate all tables for a clean slate FOR EACH TEST
-	tables := []string{"relations", "name_maps", "locations", "geo_levels"}
+	tables := []string{"location_successions", "location_redirects", "level_rules", "relations", "name_maps", "locations", "geo_levels"}
 	sqlDB, _ := db.DB()
 	for _, table := range tables {
 		_, err := sqlDB.ExecContext(ctx, "TRUNCATE TABLE "+table+" RESTART IDENTITY CASCADE;")
@@ -85,8 +85,11 @@ connected:
 		}
 	}
 
-	pgStore := postgres.Store{DB: db}
-	return &ServiceOnPostgres{db: pgStore}
+	service, err := NewServiceOnPostgres(db)
+	if err != nil {
+		t.Fatalf("Failed to create service: %v", err)
+	}
+	return service
 }
 
 // Helper to create a GeoLevel for tests
@@ -107,6 +110,36 @@ func createTestLocation(t *testing.T, service *ServiceOnPostgres, geoLevel, name
 	return loc
 }
 
+// Helper to create a ranked hierarchy India > Kerala > Ernakulam > Kochi for tests.
+// Locations are keyed by their primary name.
+func createTestHierarchy(t *testing.T, service *ServiceOnPostgres) map[string]Location {
+	t.Helper()
+	ctx := context.Background()
+	levels := []struct {
+		name string
+		rank float64
+	}{
+		{"COUNTRY", 1.0},
+		{"STATE", 2.0},
+		{"DISTRICT", 3.0},
+		{"CITY", 4.0},
+	}
+	for _, level := range levels {
+		createTestGeoLevel(t, service, level.name, float64Ptr(level.rank))
+	}
+
+	locs := map[string]Location{
+		"India":     createTestLocation(t, service, "COUNTRY", "India"),
+		"Kerala":    createTestLocation(t, service, "STATE", "Kerala"),
+		"Ernakulam": createTestLocation(t, service, "DISTRICT", "Ernakulam"),
+		"Kochi":     createTestLocation(t, service, "CITY", "Kochi"),
+	}
+	require.NoError(t, service.AddParent(ctx, locs["Kerala"].GeoID, locs["India"].GeoID))
+	require.NoError(t, service.AddParent(ctx, locs["Ernakulam"].GeoID, locs["Kerala"].GeoID))
+	require.NoError(t, service.AddParent(ctx, locs["Kochi"].GeoID, locs["Ernakulam"].GeoID))
+	return locs
+}
+
 // --- Test Functions ---
 
 func TestServiceOnPostgres_AddGeoLevel(t *testing.T) {
@@ -2209,3 +2242,167 @@ func float64Ptr(f float64) *float64 {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestServiceOnPostgres_ListGeoLevels(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	createTestGeoLevel(t, service, "COUNTRY", float64Ptr(1.0))
+	createTestGeoLevel(t, service, "STATE", float64Ptr(2.0))
+	createTestGeoLevel(t, service, "ZONE", nil)
+	createTestLocation(t, service, "STATE", "Kerala")
+	createTestLocation(t, service, "STATE", "Karnataka")
+
+	levels, err := service.ListGeoLevels(ctx)
+	require.NoError(t, err)
+	require.Len(t, levels, 3)
+
+	assert.Equal(t, "COUNTRY", levels[0].Name)
+	assert.Equal(t, 1.0, *levels[0].Rank)
+	assert.Equal(t, int64(0), levels[0].LocationCount)
+	assert.Equal(t, "STATE", levels[1].Name)
+	assert.Equal(t, int64(2), levels[1].LocationCount)
+	assert.Equal(t, "ZONE", levels[2].Name)
+	assert.Nil(t, levels[2].Rank)
+}
+
+func TestServiceOnPostgres_GetGeoLevel(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	createTestGeoLevel(t, service, "COUNTRY", float64Ptr(1.0))
+	createTestLocation(t, service, "COUNTRY", "India")
+
+	tests := []struct {
+		name      string
+		level     string
+		wantErr   bool
+		errType   error
+		wantCount int64
+	}{
+		{
+			name:      "existing level",
+			level:     "COUNTRY",
+			wantErr:   false,
+			wantCount: 1,
+		},
+		{
+			name:      "case-insensitive level",
+			level:     "country",
+			wantErr:   false,
+			wantCount: 1,
+		},
+		{
+			name:    "non-existent level",
+			level:   "STATE",
+			wantErr: true,
+			errType: postgres.ErrGeoLevelNotFound,
+		},
+		{
+			name:    "empty name",
+			level:   "",
+			wantErr: true,
+			errType: postgres.ErrGeoLevelNameRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := service.GetGeoLevel(ctx, tt.level)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errType != nil {
+					assert.ErrorIs(t, err, tt.errType)
+				}
+				assert.Nil(t, level)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, level)
+			assert.Equal(t, "COUNTRY", level.Name)
+			assert.Equal(t, tt.wantCount, level.LocationCount)
+		})
+	}
+}
+
+func TestServiceOnPostgres_GetGeoLevelsByPattern(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	createTestGeoLevel(t, service, "COUNTRY", float64Ptr(1.0))
+	createTestGeoLevel(t, service, "STATE", float64Ptr(2.0))
+
+	levels, err := service.GetGeoLevelsByPattern(ctx, "coun")
+	require.NoError(t, err)
+	require.Len(t, levels, 1)
+	assert.Equal(t, "COUNTRY", levels[0].Name)
+
+	levels, err = service.GetGeoLevelsByPattern(ctx, "CITY")
+	require.NoError(t, err)
+	assert.Empty(t, levels, "no match should return an empty list")
+}
+
+func TestServiceOnPostgres_DeleteGeoLevel(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	createTestGeoLevel(t, service, "CITY", float64Ptr(4.0))
+	createTestGeoLevel(t, service, "TOWN", float64Ptr(4.0))
+	createTestGeoLevel(t, service, "UNUSED", nil)
+	city := createTestLocation(t, service, "CITY", "Kochi")
+
+	t.Run("delete unused level", func(t *testing.T) {
+		err := service.DeleteGeoLevel(ctx, "UNUSED", nil)
+		require.NoError(t, err)
+		_, err = service.GetGeoLevel(ctx, "UNUSED")
+		assert.ErrorIs(t, err, postgres.ErrGeoLevelNotFound)
+	})
+
+	t.Run("delete level in use", func(t *testing.T) {
+		err := service.DeleteGeoLevel(ctx, "CITY", nil)
+		assert.ErrorIs(t, err, postgres.ErrGeoLevelInUse)
+	})
+
+	t.Run("delete with reassignment", func(t *testing.T) {
+		err := service.DeleteGeoLevel(ctx, "CITY", stringPtr("TOWN"))
+		require.NoError(t, err)
+
+		loc, err := service.GetLocation(ctx, city.GeoID)
+		require.NoError(t, err)
+		assert.Equal(t, "TOWN", loc.GeoLevel)
+
+		level, err := service.GetGeoLevel(ctx, "TOWN")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), level.LocationCount)
+	})
+}
+
+func TestServiceOnPostgres_MoveLocation(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	karnataka := createTestLocation(t, service, "STATE", "Karnataka")
+	require.NoError(t, service.AddParent(ctx, karnataka.GeoID, locs["India"].GeoID))
+
+	violations, err := service.MoveLocation(ctx, locs["Ernakulam"].GeoID, karnataka.GeoID, true)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	parent, err := service.GetParentAtLevel(ctx, locs["Ernakulam"].GeoID, "STATE")
+	require.NoError(t, err)
+	assert.Equal(t, karnataka.GeoID, parent.GeoID)
+
+	children, err := service.GetAllChildren(ctx, locs["Kerala"].GeoID)
+	require.NoError(t, err)
+	assert.Empty(t, children)
+
+	// Moving a location below its own descendant is a cycle or a rank violation
+	_, err = service.MoveLocation(ctx, locs["Ernakulam"].GeoID, locs["Kochi"].GeoID, false)
+	assert.ErrorIs(t, err, postgres.ErrInvalidHierarchy)
+
+	_, err = service.MoveLocation(ctx, "invalid-uuid", karnataka.GeoID, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package location
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// MergeLocations merges duplicate locations into the survivor.
+// The duplicates' names become aliases of the survivor, their relations are re-pointed to the
+// survivor and their geo_ids redirect to the survivor in GetLocation. Relations that could not
+// be re-pointed are returned as violations.
+func (service *ServiceOnPostgres) MergeLocations(ctx context.Context, survivorGeoID string, duplicateGeoIDs []string) (*Location, []RelationViolation, error) {
+	survivorID, err := uuidFromString(survivorGeoID)
+	if err != nil {
+		return nil, nil, err
+	}
+	duplicateIDs := make([]uuid.UUID, 0, len(duplicateGeoIDs))
+	for _, geoID := range duplicateGeoIDs {
+		id, err := uuidFromString(geoID)
+		if err != nil {
+			return nil, nil, err
+		}
+		duplicateIDs = append(duplicateIDs, id)
+	}
+
+	var conflicts []postgres.RelationConflict
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		conflicts, err = store.MergeLocations(ctx, survivorID, duplicateIDs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	loc, err := service.GetLocation(ctx, survivorGeoID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loc, relationConflictViolations(conflicts), nil
+}
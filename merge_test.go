@@ -0,0 +1,41 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_MergeLocations(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	duplicate := createTestLocation(t, service, "DISTRICT", "Ernakulam District")
+	suburb := createTestLocation(t, service, "CITY", "Aluva")
+	require.NoError(t, service.AddParent(ctx, duplicate.GeoID, locs["Kerala"].GeoID))
+	require.NoError(t, service.AddParent(ctx, suburb.GeoID, duplicate.GeoID))
+
+	merged, violations, err := service.MergeLocations(ctx, locs["Ernakulam"].GeoID, []string{duplicate.GeoID})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.Equal(t, "Ernakulam", merged.Name)
+	assert.Equal(t, []string{"Ernakulam District"}, merged.Aliases)
+
+	children, err := service.GetAllChildren(ctx, locs["Ernakulam"].GeoID)
+	require.NoError(t, err)
+	assert.Len(t, children, 2)
+
+	loc, err := service.GetLocation(ctx, duplicate.GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, locs["Ernakulam"].GeoID, loc.GeoID, "old geo_id should resolve to the survivor")
+
+	_, _, err = service.MergeLocations(ctx, locs["Ernakulam"].GeoID, []string{locs["Kerala"].GeoID})
+	assert.ErrorIs(t, err, postgres.ErrMergeGeoLevelMismatch)
+
+	_, _, err = service.MergeLocations(ctx, locs["Ernakulam"].GeoID, []string{"invalid-uuid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,72 @@
+package location
+
+import (
+	"context"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// Neighborhood is a location with the locations around it
+type Neighborhood struct {
+	Location Location   `json:"location"`
+	Parent   Location   `json:"parent"`   // parent at the requested geo level
+	Siblings []Location `json:"siblings"` // other children of the parent at the geo level of the location
+	Children []Location `json:"children"`
+}
+
+// GetSiblings returns the other locations of the same geo level under the parent of the
+// location at the parent geo level, ordered by name, e.g. the other districts of the state
+// of a district. Fails with postgres.ErrRelationNotFound without such a parent.
+func (service *ServiceOnPostgres) GetSiblings(ctx context.Context, geoID string, parentLevel string) ([]Location, error) {
+	neighborhood, err := service.neighborhood(ctx, geoID, parentLevel, false)
+	if err != nil {
+		return nil, err
+	}
+	return neighborhood.Siblings, nil
+}
+
+// GetNeighborhood returns a location with its parent at the parent geo level, its siblings
+// under that parent as GetSiblings does, and its children, in one call
+func (service *ServiceOnPostgres) GetNeighborhood(ctx context.Context, geoID string, parentLevel string) (*Neighborhood, error) {
+	return service.neighborhood(ctx, geoID, parentLevel, true)
+}
+
+func (service *ServiceOnPostgres) neighborhood(ctx context.Context, geoID string, parentLevel string, withChildren bool) (*Neighborhood, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	found, err := service.db.GetNeighborhood(ctx, id, parentLevel, withChildren)
+	if err != nil {
+		return nil, err
+	}
+	neighborhood := &Neighborhood{
+		Location: fromLocationWithNames(found.Location),
+		Parent:   fromLocationWithNames(found.Parent),
+		Siblings: make([]Location, 0, len(found.Siblings)),
+		Children: make([]Location, 0, len(found.Children)),
+	}
+	for _, sibling := range found.Siblings {
+		neighborhood.Siblings = append(neighborhood.Siblings, fromLocationWithNames(sibling))
+	}
+	for _, child := range found.Children {
+		neighborhood.Children = append(neighborhood.Children, fromLocationWithNames(child))
+	}
+	return neighborhood, nil
+}
+
+func fromLocationWithNames(loc postgres.LocationWithNames) Location {
+	var supersededBy []string
+	for _, successorID := range loc.SupersededBy {
+		supersededBy = append(supersededBy, successorID.String())
+	}
+	return Location{
+		GeoID:        loc.Id.String(),
+		GeoLevel:     loc.GeoLevel,
+		Name:         loc.Name,
+		Aliases:      loc.Aliases,
+		SupersededBy: supersededBy,
+		Version:      loc.Version,
+		Attributes:   loc.Attributes,
+	}
+}
@@ -0,0 +1,42 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_GetSiblings(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	for _, name := range []string{"Thrissur", "Kottayam"} {
+		district := createTestLocation(t, service, "DISTRICT", name)
+		require.NoError(t, service.AddParent(ctx, district.GeoID, locs["Kerala"].GeoID))
+	}
+	munnar := createTestLocation(t, service, "CITY", "Munnar")
+	require.NoError(t, service.AddParent(ctx, munnar.GeoID, locs["Kerala"].GeoID))
+
+	siblings, err := service.GetSiblings(ctx, locs["Ernakulam"].GeoID, "STATE")
+	require.NoError(t, err)
+	require.Len(t, siblings, 2)
+	assert.Equal(t, "Kottayam", siblings[0].Name)
+	assert.Equal(t, "Thrissur", siblings[1].Name)
+	assert.Equal(t, "DISTRICT", siblings[0].GeoLevel)
+
+	neighborhood, err := service.GetNeighborhood(ctx, locs["Ernakulam"].GeoID, "STATE")
+	require.NoError(t, err)
+	assert.Equal(t, locs["Ernakulam"].GeoID, neighborhood.Location.GeoID)
+	assert.Equal(t, "Kerala", neighborhood.Parent.Name)
+	assert.Equal(t, siblings, neighborhood.Siblings)
+	require.Len(t, neighborhood.Children, 1)
+	assert.Equal(t, "Kochi", neighborhood.Children[0].Name)
+
+	_, err = service.GetSiblings(ctx, locs["Ernakulam"].GeoID, "COUNTRY")
+	assert.ErrorIs(t, err, postgres.ErrRelationNotFound)
+	_, err = service.GetNeighborhood(ctx, "not-a-uuid", "STATE")
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+}
@@ -0,0 +1,115 @@
+package location
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// DefaultPathSeparator separates the names of a path rendered by GetPath
+const DefaultPathSeparator = " > "
+
+// ResolvePathSeparator separates the names of a path given to ResolvePath
+const ResolvePathSeparator = "/"
+
+// PathOptions configures how GetPath renders a path
+type PathOptions struct {
+	Separator string   // between names, DefaultPathSeparator if empty
+	GeoLevels []string // geo levels included in the path, all if empty
+	Language  string   // localized names in this language where there are, primary names otherwise
+}
+
+// AmbiguousPathError is returned by ResolvePath when a path matches several locations.
+// errors.Is matches postgres.ErrAmbiguousPath.
+type AmbiguousPathError struct {
+	Path       string
+	Candidates []Location
+}
+
+// Error lists the geo_ids of the candidates
+func (e *AmbiguousPathError) Error() string {
+	geoIDs := make([]string, 0, len(e.Candidates))
+	for _, candidate := range e.Candidates {
+		geoIDs = append(geoIDs, candidate.GeoID)
+	}
+	return fmt.Sprintf("%v: %q matches %s", postgres.ErrAmbiguousPath, e.Path, strings.Join(geoIDs, ", "))
+}
+
+// Unwrap returns postgres.ErrAmbiguousPath
+func (e *AmbiguousPathError) Unwrap() error {
+	return postgres.ErrAmbiguousPath
+}
+
+// AddLocalizedName adds an alias of a location in a language, used by GetPath with that language
+func (service *ServiceOnPostgres) AddLocalizedName(ctx context.Context, geoID string, language string, name string) error {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return err
+	}
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		return store.InsertLocalizedName(ctx, id, language, name)
+	})
+}
+
+// GetPath renders the ancestors of a location from the top of the hierarchy down to the
+// location itself, e.g. "India > Kerala > Ernakulam > Kochi"
+func (service *ServiceOnPostgres) GetPath(ctx context.Context, geoID string, opts PathOptions) (string, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return "", err
+	}
+	path, err := service.db.GetPath(ctx, id, opts.Language)
+	if err != nil {
+		return "", err
+	}
+
+	separator := opts.Separator
+	if separator == "" {
+		separator = DefaultPathSeparator
+	}
+	names := make([]string, 0, len(path))
+	for _, location := range path {
+		if len(opts.GeoLevels) > 0 && !slices.ContainsFunc(opts.GeoLevels, sameAs(location.GeoLevel)) {
+			continue
+		}
+		names = append(names, location.Name)
+	}
+	return strings.Join(names, separator), nil
+}
+
+// ResolvePath returns the location at the end of a path of names separated by "/", e.g.
+// "India/Kerala/Ernakulam". Each name is looked up, regardless of case, among the primary names
+// and aliases of the descendants of the locations matched so far, so levels may be skipped.
+// Returns ErrLocationNotFound if nothing matches and an *AmbiguousPathError listing the
+// candidates if several locations do.
+func (service *ServiceOnPostgres) ResolvePath(ctx context.Context, path string) (*Location, error) {
+	var names []string
+	for _, name := range strings.Split(path, ResolvePathSeparator) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	ids, err := service.db.ResolvePath(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, postgres.ErrLocationNotFound
+	case 1:
+		return service.GetLocation(ctx, ids[0].String())
+	}
+	candidates := make([]Location, 0, len(ids))
+	for _, id := range ids {
+		loc, err := service.GetLocation(ctx, id.String())
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, *loc)
+	}
+	return nil, &AmbiguousPathError{Path: path, Candidates: candidates}
+}
@@ -0,0 +1,75 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_GetPath(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	require.NoError(t, service.AddLocalizedName(ctx, locs["Kerala"].GeoID, "ml", "Keralam"))
+
+	tests := []struct {
+		name string
+		opts PathOptions
+		want string
+	}{
+		{name: "defaults", want: "India > Kerala > Ernakulam > Kochi"},
+		{name: "separator", opts: PathOptions{Separator: "/"}, want: "India/Kerala/Ernakulam/Kochi"},
+		{name: "geo levels", opts: PathOptions{Separator: ", ", GeoLevels: []string{"city", "STATE"}}, want: "Kerala, Kochi"},
+		{name: "language", opts: PathOptions{Language: "ml"}, want: "India > Keralam > Ernakulam > Kochi"},
+		{name: "unknown language", opts: PathOptions{Language: "fr"}, want: "India > Kerala > Ernakulam > Kochi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := service.GetPath(ctx, locs["Kochi"].GeoID, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, path)
+		})
+	}
+
+	_, err := service.GetPath(ctx, "not-a-uuid", PathOptions{})
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+}
+
+func TestServiceOnPostgres_ResolvePath(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+
+	loc, err := service.ResolvePath(ctx, "India/Kerala/Ernakulam")
+	require.NoError(t, err)
+	assert.Equal(t, locs["Ernakulam"].GeoID, loc.GeoID)
+	assert.Equal(t, "DISTRICT", loc.GeoLevel)
+
+	path, err := service.GetPath(ctx, locs["Kochi"].GeoID, PathOptions{Separator: ResolvePathSeparator})
+	require.NoError(t, err)
+	loc, err = service.ResolvePath(ctx, path)
+	require.NoError(t, err)
+	assert.Equal(t, locs["Kochi"].GeoID, loc.GeoID)
+
+	_, err = service.ResolvePath(ctx, "India/Tamil Nadu")
+	assert.ErrorIs(t, err, postgres.ErrLocationNotFound)
+
+	kochi := createTestLocation(t, service, "CITY", "Kochi Port")
+	require.NoError(t, service.AddParent(ctx, kochi.GeoID, locs["Ernakulam"].GeoID))
+	require.NoError(t, service.AddAliasToLocation(ctx, kochi.GeoID, "kochi"))
+
+	_, err = service.ResolvePath(ctx, " India / Kerala / Kochi ")
+	assert.ErrorIs(t, err, postgres.ErrAmbiguousPath)
+	var ambiguousErr *AmbiguousPathError
+	require.True(t, errors.As(err, &ambiguousErr))
+	assert.ElementsMatch(t, []string{locs["Kochi"].GeoID, kochi.GeoID},
+		[]string{ambiguousErr.Candidates[0].GeoID, ambiguousErr.Candidates[1].GeoID})
+
+	loc, err = service.ResolvePath(ctx, "Kerala/Kochi Port")
+	require.NoError(t, err)
+	assert.Equal(t, kochi.GeoID, loc.GeoID)
+}
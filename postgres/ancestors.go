@@ -0,0 +1,176 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxHierarchyDepth bounds the ancestor walk, guarding against cycles in inconsistent data
+const maxHierarchyDepth = 64
+
+// ancestorDepths returns the location itself and its ancestors, each with the length
+// of the shortest chain of relations up to it
+func ancestorDepths(tx *gorm.DB, id uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ID    uuid.UUID
+		Depth int
+	}
+	err := tx.Session(&gorm.Session{NewDB: true}).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT CAST(? AS uuid) AS id, 0 AS depth
+			UNION
+			SELECT r.parent_id, a.depth + 1 FROM relations r
+			JOIN ancestors a ON r.child_id = a.id
+			WHERE r.deleted_at IS NULL AND a.depth < ?
+		)
+		SELECT id, MIN(depth) AS depth FROM ancestors GROUP BY id`, id, maxHierarchyDepth).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	depths := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		depths[row.ID] = row.Depth
+	}
+	return depths, nil
+}
+
+// commonAncestor is a location above (or one of) a set of locations with its distances to them
+type commonAncestor struct {
+	id       uuid.UUID
+	rank     *float64
+	farthest int // longest of the distances
+	total    int // sum of the distances
+}
+
+// commonAncestors returns the locations that are ancestors of, or one of, all the given
+// locations, optionally only those of the geo level, lowest first: nearest to the farthest
+// location, then nearest in total, then of the highest geo level rank
+func (s *Store) commonAncestors(ctx context.Context, ids []uuid.UUID, geoLevelName *string) ([]commonAncestor, error) {
+	db := s.DB.WithContext(ctx)
+	var common map[uuid.UUID]*commonAncestor
+	for _, id := range ids {
+		var location Location
+		if err := db.First(&location, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrLocationNotFound
+			}
+			return nil, fmt.Errorf("failed to get location: %w", err)
+		}
+
+		depths, err := ancestorDepths(db, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ancestors: %w", err)
+		}
+		if common == nil {
+			common = make(map[uuid.UUID]*commonAncestor, len(depths))
+			for ancestorID, depth := range depths {
+				common[ancestorID] = &commonAncestor{id: ancestorID, farthest: depth, total: depth}
+			}
+			continue
+		}
+		for ancestorID, ancestor := range common {
+			depth, ok := depths[ancestorID]
+			if !ok {
+				delete(common, ancestorID)
+				continue
+			}
+			ancestor.farthest = max(ancestor.farthest, depth)
+			ancestor.total += depth
+		}
+	}
+	if len(common) == 0 {
+		return []commonAncestor{}, nil
+	}
+
+	var locations []Location
+	query := db.Preload("GeoLevel").Where("locations.id IN ?", slices.Collect(maps.Keys(common)))
+	if geoLevelName != nil {
+		query = query.Joins("JOIN geo_levels ON geo_levels.id = locations.geo_level_id").
+			Where("geo_levels.name = ?", strings.ToUpper(*geoLevelName))
+	}
+	if err := query.Find(&locations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get common ancestors: %w", err)
+	}
+
+	ancestors := make([]commonAncestor, 0, len(locations))
+	for _, location := range locations {
+		ancestor := *common[location.Id]
+		ancestor.rank = location.GeoLevel.Rank
+		ancestors = append(ancestors, ancestor)
+	}
+	slices.SortFunc(ancestors, func(a, b commonAncestor) int {
+		return cmp.Or(
+			cmp.Compare(a.farthest, b.farthest),
+			cmp.Compare(a.total, b.total),
+			compareRanksLowestFirst(a.rank, b.rank),
+			cmp.Compare(a.id.String(), b.id.String()),
+		)
+	})
+	return ancestors, nil
+}
+
+// compareRanksLowestFirst orders geo level ranks from the lowest geo level in the hierarchy,
+// which has the highest rank, with unranked geo levels last
+func compareRanksLowestFirst(a, b *float64) int {
+	switch {
+	case a != nil && b == nil:
+		return -1
+	case a == nil && b != nil:
+		return 1
+	case a != nil && b != nil:
+		return cmp.Compare(*b, *a)
+	}
+	return 0
+}
+
+// CommonAncestor returns the lowest location that is an ancestor of all the given locations,
+// or one of them if it is above the others. With a geo level, the lowest such location of that
+// level is returned. Returns ErrNoCommonAncestor if the locations share no ancestor.
+func (s *Store) CommonAncestor(ctx context.Context, ids []uuid.UUID, geoLevelName *string) (*LocationWithNames, error) {
+	if len(ids) == 0 {
+		return nil, ErrLocationRequired
+	}
+	if geoLevelName != nil {
+		if _, err := s.GetGeoLevelByName(ctx, *geoLevelName); err != nil {
+			return nil, err
+		}
+	}
+
+	ancestors, err := s.commonAncestors(ctx, ids, geoLevelName)
+	if err != nil {
+		return nil, err
+	}
+	if len(ancestors) == 0 {
+		return nil, ErrNoCommonAncestor
+	}
+	return s.GetLocation(ctx, ancestors[0].id)
+}
+
+// TreeDistance returns the number of relations on the shortest path between two locations
+// through a common ancestor: 0 for the same location, 1 for a parent and its child, 2 for
+// siblings. Returns ErrNoCommonAncestor if the locations share no ancestor.
+func (s *Store) TreeDistance(ctx context.Context, id uuid.UUID, otherID uuid.UUID) (int, error) {
+	ancestors, err := s.commonAncestors(ctx, []uuid.UUID{id, otherID}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if len(ancestors) == 0 {
+		return 0, ErrNoCommonAncestor
+	}
+
+	distance := ancestors[0].total
+	for _, ancestor := range ancestors[1:] {
+		distance = min(distance, ancestor.total)
+	}
+	return distance, nil
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAncestorsTest builds Country1 > State1 > District1 > City1, State1 > District2 > City2
+// and Country1 > State2; Country2 stays unrelated
+func setupAncestorsTest(t *testing.T) (*Store, map[string]*Location) {
+	t.Helper()
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+	for _, rel := range [][2]string{
+		{"Country1", "State1"},
+		{"Country1", "State2"},
+		{"State1", "District1"},
+		{"State1", "District2"},
+		{"District1", "City1"},
+		{"District2", "City2"},
+	} {
+		_, err := store.InsertRelation(ctx, locs[rel[0]].Id, locs[rel[1]].Id)
+		require.NoError(t, err)
+	}
+	return store, locs
+}
+
+func TestAncestors_CommonAncestor(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		ids      []string
+		geoLevel *string
+		want     string
+		wantErr  error
+	}{
+		{name: "cousins", ids: []string{"City1", "City2"}, want: "State1"},
+		{name: "at a level", ids: []string{"City1", "City2"}, geoLevel: stringPtr("country"), want: "Country1"},
+		{name: "ancestor of the other", ids: []string{"District1", "City1"}, want: "District1"},
+		{name: "three locations", ids: []string{"City1", "District2", "State2"}, want: "Country1"},
+		{name: "single location", ids: []string{"City1"}, want: "City1"},
+		{name: "single location at a level", ids: []string{"City1"}, geoLevel: stringPtr("STATE"), want: "State1"},
+		{name: "unrelated", ids: []string{"City1", "Country2"}, wantErr: ErrNoCommonAncestor},
+		{name: "no ancestor at the level", ids: []string{"City1", "City2"}, geoLevel: stringPtr("CITY"), wantErr: ErrNoCommonAncestor},
+		{name: "unknown level", ids: []string{"City1"}, geoLevel: stringPtr("PLANET"), wantErr: ErrGeoLevelNotFound},
+		{name: "no locations", ids: nil, wantErr: ErrLocationRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]uuid.UUID, 0, len(tt.ids))
+			for _, name := range tt.ids {
+				ids = append(ids, locs[name].Id)
+			}
+			ancestor, err := store.CommonAncestor(ctx, ids, tt.geoLevel)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, locs[tt.want].Id, ancestor.Id)
+		})
+	}
+
+	t.Run("unknown location", func(t *testing.T) {
+		_, err := store.CommonAncestor(ctx, []uuid.UUID{locs["City1"].Id, uuid.New()}, nil)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	})
+}
+
+func TestAncestors_TreeDistance(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		a, b    string
+		want    int
+		wantErr error
+	}{
+		{a: "City1", b: "City1", want: 0},
+		{a: "District1", b: "City1", want: 1},
+		{a: "City1", b: "District1", want: 1},
+		{a: "District1", b: "District2", want: 2},
+		{a: "City1", b: "City2", want: 4},
+		{a: "City1", b: "State2", want: 4},
+		{a: "City1", b: "Country2", wantErr: ErrNoCommonAncestor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"-"+tt.b, func(t *testing.T) {
+			distance, err := store.TreeDistance(ctx, locs[tt.a].Id, locs[tt.b].Id)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, distance)
+		})
+	}
+
+	t.Run("deleted relations are ignored", func(t *testing.T) {
+		relations, err := store.GetParents(ctx, locs["State2"].Id)
+		require.NoError(t, err)
+		require.Len(t, relations, 1)
+		require.NoError(t, store.DeleteRelation(ctx, relations[0].Id))
+
+		_, err = store.TreeDistance(ctx, locs["City1"].Id, locs["State2"].Id)
+		assert.ErrorIs(t, err, ErrNoCommonAncestor)
+	})
+}
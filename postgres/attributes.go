@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// attributesIndex is the GIN index serving equality filters on the attributes of locations
+const attributesIndex = "idx_locations_attributes"
+
+// attributeKeyPattern restricts attribute keys to lowercase identifiers, which can be inlined
+// in the SQL of filters and in index names
+var attributeKeyPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,39}$`)
+
+// Attributes are arbitrary typed values of a location stored as a JSONB object: strings,
+// numbers (float64), booleans, lists and nested objects
+type Attributes map[string]any
+
+// GormDataType returns the column type of attributes
+func (Attributes) GormDataType() string {
+	return "jsonb"
+}
+
+// Value encodes the attributes as a JSON object, {} for nil
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]any(a))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan decodes attributes from a JSON object
+func (a *Attributes) Scan(value any) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*a = Attributes{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into attributes", value)
+	}
+	attributes := Attributes{}
+	if err := json.Unmarshal(b, &attributes); err != nil {
+		return err
+	}
+	*a = attributes
+	return nil
+}
+
+// ValidateAttributeKey returns ErrInvalidAttributeKey unless the key is a lowercase identifier
+// of at most 40 letters, digits and underscores, e.g. "census_code"
+func ValidateAttributeKey(key string) error {
+	if !attributeKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidAttributeKey, key)
+	}
+	return nil
+}
+
+func validateAttributes(attributes Attributes) error {
+	for key := range attributes {
+		if err := ValidateAttributeKey(key); err != nil {
+			return err
+		}
+	}
+	if _, err := json.Marshal(map[string]any(attributes)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAttributeValue, err)
+	}
+	return nil
+}
+
+// GetAttributes returns the attributes of a location
+func (s *Store) GetAttributes(ctx context.Context, id uuid.UUID) (Attributes, error) {
+	var location Location
+	if err := s.DB.WithContext(ctx).Select("id", "attributes").First(&location, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get attributes: %w", err)
+	}
+	if location.Attributes == nil {
+		return Attributes{}, nil
+	}
+	return location.Attributes, nil
+}
+
+// SetAttributes replaces all attributes of a location
+func (s *Store) SetAttributes(ctx context.Context, id uuid.UUID, attributes Attributes) (Attributes, error) {
+	return s.updateAttributes(ctx, id, func(Attributes) (Attributes, error) {
+		return attributes, nil
+	})
+}
+
+// PatchAttributes merges the patch into the attributes of a location as a JSON merge patch
+// (RFC 7396): nested objects are merged, nil values remove the attribute, and other values
+// replace it
+func (s *Store) PatchAttributes(ctx context.Context, id uuid.UUID, patch Attributes) (Attributes, error) {
+	return s.updateAttributes(ctx, id, func(current Attributes) (Attributes, error) {
+		return mergePatch(current, patch), nil
+	})
+}
+
+// updateAttributes writes the attributes computed from the current ones, which must follow
+// the attribute schema of the geo level of the location. The version bump locks the location
+// first, so concurrent updates cannot lose each other's changes.
+func (s *Store) updateAttributes(ctx context.Context, id uuid.UUID, update func(Attributes) (Attributes, error)) (Attributes, error) {
+	var attributes Attributes
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var location Location
+		if err := tx.Select("id").First(&location, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+		if err := touchLocations(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Preload("GeoLevel").Select("id", "geo_level_id", "attributes").First(&location, id).Error; err != nil {
+			return err
+		}
+
+		var err error
+		attributes, err = update(location.Attributes)
+		if err != nil {
+			return err
+		}
+		if attributes == nil {
+			attributes = Attributes{}
+		}
+		if err := validateAttributes(attributes); err != nil {
+			return err
+		}
+		if err := checkAttributeSchema(id, location.GeoLevel, attributes); err != nil {
+			return err
+		}
+		return tx.Model(&Location{}).Where("id = ?", id).Update("attributes", attributes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// mergePatch applies a JSON merge patch to the attributes without changing them
+func mergePatch(target Attributes, patch Attributes) Attributes {
+	merged := make(Attributes, len(target)+len(patch))
+	for key, value := range target {
+		merged[key] = value
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		patchObject, isObject := value.(map[string]any)
+		if !isObject {
+			if attributes, ok := value.(Attributes); ok {
+				patchObject, isObject = attributes, true
+			}
+		}
+		if !isObject {
+			merged[key] = value
+			continue
+		}
+		targetObject, _ := merged[key].(map[string]any)
+		merged[key] = map[string]any(mergePatch(targetObject, patchObject))
+	}
+	return merged
+}
+
+// AttributeOp is the comparison of an attribute filter
+type AttributeOp string
+
+const (
+	AttributeEq     AttributeOp = "="
+	AttributeNe     AttributeOp = "!="
+	AttributeGt     AttributeOp = ">"
+	AttributeGte    AttributeOp = ">="
+	AttributeLt     AttributeOp = "<"
+	AttributeLte    AttributeOp = "<="
+	AttributeExists AttributeOp = "exists"
+)
+
+// AttributeFilter keeps the locations whose attribute compares to the value. Only values
+// of the same JSON type compare: a number attribute is never greater than a string.
+// Locations without the attribute pass no filter.
+type AttributeFilter struct {
+	Key   string
+	Op    AttributeOp
+	Value any // unused by AttributeExists
+}
+
+// ParseAttributeFilter parses a filter such as "population > 1e6", "serviceable = true",
+// `currency = "INR"` or "timezone exists". Values are JSON, or strings if they are not.
+func ParseAttributeFilter(expr string) (AttributeFilter, error) {
+	expr = strings.TrimSpace(expr)
+	if key, ok := strings.CutSuffix(expr, " "+string(AttributeExists)); ok {
+		filter := AttributeFilter{Key: strings.TrimSpace(key), Op: AttributeExists}
+		return filter, ValidateAttributeKey(filter.Key)
+	}
+	// the first operator of the expression, the longest at the same position: ">=" over ">"
+	op, at := AttributeOp(""), -1
+	for _, candidate := range []AttributeOp{AttributeNe, AttributeGte, AttributeLte, AttributeEq, AttributeGt, AttributeLt} {
+		if i := strings.Index(expr, string(candidate)); i >= 0 && (at < 0 || i < at) {
+			op, at = candidate, i
+		}
+	}
+	if at < 0 {
+		return AttributeFilter{}, fmt.Errorf("%w: %q", ErrInvalidAttributeFilter, expr)
+	}
+
+	filter := AttributeFilter{Key: strings.TrimSpace(expr[:at]), Op: op}
+	if err := ValidateAttributeKey(filter.Key); err != nil {
+		return AttributeFilter{}, err
+	}
+	raw := strings.TrimSpace(expr[at+len(op):])
+	if raw == "" {
+		return AttributeFilter{}, fmt.Errorf("%w: %q has no value", ErrInvalidAttributeFilter, expr)
+	}
+	if err := json.Unmarshal([]byte(raw), &filter.Value); err != nil {
+		filter.Value = raw
+	}
+	return filter, nil
+}
+
+// condition returns the SQL condition of the filter on the locations table
+func (filter AttributeFilter) condition() (string, []any, error) {
+	if err := ValidateAttributeKey(filter.Key); err != nil {
+		return "", nil, err
+	}
+	attribute := "locations.attributes -> '" + filter.Key + "'"
+	if filter.Op == AttributeExists {
+		return attribute + " IS NOT NULL", nil, nil
+	}
+
+	value, err := json.Marshal(filter.Value)
+	if err != nil || filter.Value == nil {
+		return "", nil, fmt.Errorf("%w: invalid value for %s", ErrInvalidAttributeFilter, filter.Key)
+	}
+	switch filter.Op {
+	case AttributeEq:
+		// containment is served by the GIN index of the attributes
+		contained, err := json.Marshal(map[string]json.RawMessage{filter.Key: value})
+		if err != nil {
+			return "", nil, err
+		}
+		return "locations.attributes @> CAST(? AS jsonb)", []any{string(contained)}, nil
+	case AttributeNe:
+		return attribute + " <> CAST(? AS jsonb)", []any{string(value)}, nil
+	case AttributeGt, AttributeGte, AttributeLt, AttributeLte:
+		return "jsonb_typeof(" + attribute + ") = jsonb_typeof(CAST(? AS jsonb)) AND " +
+			attribute + " " + string(filter.Op) + " CAST(? AS jsonb)", []any{string(value), string(value)}, nil
+	}
+	return "", nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidAttributeFilter, filter.Op)
+}
+
+// LocationQuery selects locations by geo level and attributes
+type LocationQuery struct {
+	IDs          []uuid.UUID // only these locations, if not nil
+	GeoLevelName *string
+	Attributes   []AttributeFilter // all of them must pass
+}
+
+// FindLocations returns the locations selected by the query with their geo levels
+func (s *Store) FindLocations(ctx context.Context, query LocationQuery) ([]Location, error) {
+	db := s.DB.WithContext(ctx).Preload("GeoLevel")
+	if query.IDs != nil {
+		if len(query.IDs) == 0 {
+			return []Location{}, nil
+		}
+		db = db.Where("locations.id IN ?", query.IDs)
+	}
+	if query.GeoLevelName != nil {
+		geoLevel, err := s.GetGeoLevelByName(ctx, *query.GeoLevelName)
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("locations.geo_level_id = ?", geoLevel.Id)
+	}
+	for _, filter := range query.Attributes {
+		condition, args, err := filter.condition()
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where(condition, args...)
+	}
+
+	var locations []Location
+	if err := db.Order("locations.created_at, locations.id").Find(&locations).Error; err != nil {
+		return nil, fmt.Errorf("failed to find locations: %w", err)
+	}
+	return locations, nil
+}
+
+// IndexAttribute creates the index serving the range filters (>, >=, <, <=) and inequality
+// filters on an attribute, if it does not exist yet. Equality filters are served by the
+// GIN index of all attributes created by Migrate. The index is built concurrently, without
+// blocking writes to locations, so it cannot be called in a transaction.
+func (s *Store) IndexAttribute(ctx context.Context, key string) error {
+	if err := ValidateAttributeKey(key); err != nil {
+		return err
+	}
+	db := s.DB.WithContext(ctx)
+	index := "idx_locations_attr_" + key
+	err := db.Exec("CREATE INDEX CONCURRENTLY IF NOT EXISTS " + index +
+		" ON locations ((attributes -> '" + key + "'))").Error
+	if err != nil {
+		// A failed concurrent build leaves an invalid index behind, which would be kept by
+		// the next call
+		db.Exec("DROP INDEX CONCURRENTLY IF EXISTS " + index)
+		return fmt.Errorf("failed to index attribute: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAttributeFilter(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    AttributeFilter
+		wantErr error
+	}{
+		{expr: "population > 1e6", want: AttributeFilter{Key: "population", Op: AttributeGt, Value: 1e6}},
+		{expr: "population>=1000", want: AttributeFilter{Key: "population", Op: AttributeGte, Value: 1000.0}},
+		{expr: "area <= 12.5", want: AttributeFilter{Key: "area", Op: AttributeLte, Value: 12.5}},
+		{expr: "serviceable = true", want: AttributeFilter{Key: "serviceable", Op: AttributeEq, Value: true}},
+		{expr: `currency = "INR"`, want: AttributeFilter{Key: "currency", Op: AttributeEq, Value: "INR"}},
+		{expr: "timezone != Asia/Kolkata", want: AttributeFilter{Key: "timezone", Op: AttributeNe, Value: "Asia/Kolkata"}},
+		{expr: " timezone exists ", want: AttributeFilter{Key: "timezone", Op: AttributeExists}},
+		{expr: "population", wantErr: ErrInvalidAttributeFilter},
+		{expr: "population >", wantErr: ErrInvalidAttributeFilter},
+		{expr: "Population > 1", wantErr: ErrInvalidAttributeKey},
+		{expr: "a'b = 1", wantErr: ErrInvalidAttributeKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			filter, err := ParseAttributeFilter(tt.expr)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter)
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	target := Attributes{
+		"population": 100.0,
+		"timezone":   "Asia/Kolkata",
+		"codes":      map[string]any{"iso": "IN-KL", "census": "32"},
+	}
+	merged := mergePatch(target, Attributes{
+		"population": 200.0,
+		"timezone":   nil,
+		"codes":      map[string]any{"census": nil, "postal": "68"},
+		"tags":       []any{"coastal"},
+	})
+	assert.Equal(t, Attributes{
+		"population": 200.0,
+		"codes":      map[string]any{"iso": "IN-KL", "postal": "68"},
+		"tags":       []any{"coastal"},
+	}, merged)
+	assert.Equal(t, "Asia/Kolkata", target["timezone"], "the target is unchanged")
+}
+
+func TestAttributes(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	id := locs["City1"].Id
+	before, err := store.GetLocation(ctx, id)
+	require.NoError(t, err)
+
+	attributes, err := store.GetAttributes(ctx, id)
+	require.NoError(t, err)
+	assert.Empty(t, attributes)
+
+	_, err = store.SetAttributes(ctx, id, Attributes{"population": 2.1e6, "timezone": "Asia/Kolkata", "codes": map[string]any{"iso": "IN-KL"}})
+	require.NoError(t, err)
+	attributes, err = store.PatchAttributes(ctx, id, Attributes{"timezone": nil, "codes": map[string]any{"census": "32"}})
+	require.NoError(t, err)
+	want := Attributes{"population": 2.1e6, "codes": map[string]any{"iso": "IN-KL", "census": "32"}}
+	assert.Equal(t, want, attributes)
+
+	stored, err := store.GetAttributes(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, want, stored)
+	location, err := store.GetLocation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, want, location.Attributes)
+	assert.Greater(t, location.Version, before.Version, "attribute changes bump the version")
+
+	_, err = store.SetAttributes(ctx, id, Attributes{"Population": 1.0})
+	assert.ErrorIs(t, err, ErrInvalidAttributeKey)
+	_, err = store.PatchAttributes(ctx, uuid.New(), Attributes{"population": 1.0})
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+	_, err = store.GetAttributes(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+}
+
+func TestFindLocations(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	set := func(name string, attributes Attributes) {
+		_, err := store.SetAttributes(ctx, locs[name].Id, attributes)
+		require.NoError(t, err)
+	}
+	set("City1", Attributes{"population": 2.1e6, "serviceable": true, "timezone": "Asia/Kolkata"})
+	set("City2", Attributes{"population": 6e5, "serviceable": false})
+	set("District1", Attributes{"population": "unknown"})
+	require.NoError(t, store.IndexAttribute(ctx, "population"))
+	require.NoError(t, store.IndexAttribute(ctx, "population"), "indexing twice is a no-op")
+
+	names := func(locations []Location) []string {
+		out := []string{}
+		for _, location := range locations {
+			for name, loc := range locs {
+				if loc.Id == location.Id {
+					out = append(out, name)
+				}
+			}
+		}
+		return out
+	}
+	filter := func(expr string) AttributeFilter {
+		f, err := ParseAttributeFilter(expr)
+		require.NoError(t, err)
+		return f
+	}
+	city := "city"
+
+	tests := []struct {
+		name    string
+		query   LocationQuery
+		want    []string
+		wantErr error
+	}{
+		{name: "range on numbers only", query: LocationQuery{Attributes: []AttributeFilter{filter("population > 1e6")}}, want: []string{"City1"}},
+		{name: "range and equality", query: LocationQuery{Attributes: []AttributeFilter{filter("population >= 1e5"), filter("serviceable = false")}}, want: []string{"City2"}},
+		{name: "string equality", query: LocationQuery{Attributes: []AttributeFilter{filter("population = unknown")}}, want: []string{"District1"}},
+		{name: "inequality skips missing attributes", query: LocationQuery{Attributes: []AttributeFilter{filter("serviceable != true")}}, want: []string{"City2"}},
+		{name: "exists", query: LocationQuery{Attributes: []AttributeFilter{filter("timezone exists")}}, want: []string{"City1"}},
+		{name: "geo level", query: LocationQuery{GeoLevelName: &city, Attributes: []AttributeFilter{filter("population exists")}}, want: []string{"City1", "City2"}},
+		{name: "ids", query: LocationQuery{IDs: []uuid.UUID{locs["City2"].Id, locs["State1"].Id}}, want: []string{"State1", "City2"}},
+		{name: "no ids", query: LocationQuery{IDs: []uuid.UUID{}}, want: []string{}},
+		{name: "invalid key", query: LocationQuery{Attributes: []AttributeFilter{{Key: "x; DROP", Op: AttributeEq, Value: 1}}}, wantErr: ErrInvalidAttributeKey},
+		{name: "unknown operator", query: LocationQuery{Attributes: []AttributeFilter{{Key: "x", Op: "~", Value: 1}}}, wantErr: ErrInvalidAttributeFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locations, err := store.FindLocations(ctx, tt.query)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, names(locations))
+		})
+	}
+	assert.ErrorIs(t, store.IndexAttribute(ctx, "a b"), ErrInvalidAttributeKey)
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NameSuggestion is a location with a name starting with an autocomplete prefix
+type NameSuggestion struct {
+	LocationID  uuid.UUID
+	GeoLevel    string
+	Name        string // primary name
+	MatchedName string // primary name or alias starting with the prefix
+	IsPrimary   bool   // whether MatchedName is the primary name
+}
+
+// AutocompleteQuery selects and ranks the suggestions of Autocomplete
+type AutocompleteQuery struct {
+	Prefix   string             // matched against the start of the normalized names
+	WithinID *uuid.UUID         // only descendants of this location, if set
+	Boosts   map[string]float64 // added to the score of the locations of each geo level
+	Limit    int                // number of suggestions returned at most
+}
+
+// Autocomplete returns the locations with a name, primary or alias, whose normalized form
+// (see NormalizeName) starts with the normalized prefix, one suggestion per location. The
+// suggestions are ranked by score: the boost of their geo level, plus 1 when the whole name
+// matches and 0.5 for a primary name; then shorter names first. The prefix search uses the
+// idx_name_maps_prefix index created by Migrate.
+func (s *Store) Autocomplete(ctx context.Context, query AutocompleteQuery) ([]NameSuggestion, error) {
+	prefix := NormalizeName(query.Prefix)
+	if prefix == "" {
+		return nil, ErrNameRequired
+	}
+	db := s.DB.WithContext(ctx)
+	if query.WithinID != nil {
+		var location Location
+		if err := db.First(&location, *query.WithinID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrLocationNotFound
+			}
+			return nil, fmt.Errorf("failed to get location: %w", err)
+		}
+	}
+
+	var args []any
+	boost := "0"
+	if len(query.Boosts) > 0 {
+		levels := make([]string, 0, len(query.Boosts))
+		for level := range query.Boosts {
+			levels = append(levels, level)
+		}
+		slices.Sort(levels)
+		var b strings.Builder
+		b.WriteString("CASE g.name")
+		for _, level := range levels {
+			b.WriteString(" WHEN ? THEN CAST(? AS double precision)")
+			args = append(args, strings.ToUpper(level), query.Boosts[level])
+		}
+		b.WriteString(" ELSE 0 END")
+		boost = b.String()
+	}
+	args = append(args, prefix, TenantFromContext(ctx), prefix+"%")
+
+	within := ""
+	if query.WithinID != nil {
+		within = `
+			AND n.location_id IN (
+				WITH RECURSIVE descendants AS (
+					SELECT child_id AS id, 1 AS depth FROM relations
+					WHERE parent_id = ? AND deleted_at IS NULL
+					UNION
+					SELECT r.child_id, d.depth + 1 FROM relations r
+					JOIN descendants d ON r.parent_id = d.id
+					WHERE r.deleted_at IS NULL AND d.depth < ?
+				)
+				SELECT id FROM descendants
+			)`
+		args = append(args, *query.WithinID, maxHierarchyDepth)
+	}
+	args = append(args, query.Limit)
+
+	var suggestions []NameSuggestion
+	err := db.Session(&gorm.Session{NewDB: true}).Raw(`
+		SELECT location_id, geo_level, name, matched_name, is_primary FROM (
+			SELECT DISTINCT ON (n.location_id) n.location_id, g.name AS geo_level, p.name AS name,
+				n.name AS matched_name, n.is_primary, n.normalized_name,
+				`+boost+`
+					+ CASE WHEN n.normalized_name = ? THEN 1 ELSE 0 END
+					+ CASE WHEN n.is_primary THEN 0.5 ELSE 0 END AS score
+			FROM name_maps n
+			JOIN locations l ON l.id = n.location_id AND l.deleted_at IS NULL
+			JOIN geo_levels g ON g.id = l.geo_level_id
+			LEFT JOIN name_maps p ON p.location_id = n.location_id AND p.is_primary AND p.deleted_at IS NULL
+			WHERE n.tenant = ? AND n.deleted_at IS NULL AND n.normalized_name LIKE ?`+within+`
+			ORDER BY n.location_id, score DESC, LENGTH(n.normalized_name), n.normalized_name
+		) suggestions
+		ORDER BY score DESC, LENGTH(normalized_name), normalized_name, location_id
+		LIMIT ?`, args...).
+		Scan(&suggestions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to autocomplete: %w", err)
+	}
+	if suggestions == nil {
+		suggestions = []NameSuggestion{}
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutocomplete(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.InsertNameMap(ctx, locs["City1"].Id, "City One", false))
+	require.NoError(t, store.InsertNameMap(ctx, locs["State2"].Id, "Dakshin", false))
+
+	names := func(suggestions []NameSuggestion) []string {
+		out := []string{}
+		for _, suggestion := range suggestions {
+			out = append(out, suggestion.MatchedName)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		query   AutocompleteQuery
+		want    []string
+		wantErr error
+	}{
+		{name: "aliases of a location are one suggestion", query: AutocompleteQuery{Prefix: "CITY"}, want: []string{"City1", "City2"}},
+		{name: "whole name match first", query: AutocompleteQuery{Prefix: "city one"}, want: []string{"City One"}},
+		{name: "punctuation ignored", query: AutocompleteQuery{Prefix: "City-O"}, want: []string{"City One"}},
+		{name: "primary names first", query: AutocompleteQuery{Prefix: "d"}, want: []string{"District1", "District2", "Dakshin"}},
+		{name: "geo level boost", query: AutocompleteQuery{Prefix: "c", Boosts: map[string]float64{"city": 1}}, want: []string{"City1", "City2", "Country1", "Country2"}},
+		{name: "within an ancestor", query: AutocompleteQuery{Prefix: "d", WithinID: &locs["State1"].Id}, want: []string{"District1", "District2"}},
+		{name: "within a leaf", query: AutocompleteQuery{Prefix: "c", WithinID: &locs["City1"].Id}, want: []string{}},
+		{name: "limit", query: AutocompleteQuery{Prefix: "c", Limit: 1}, want: []string{"City1"}},
+		{name: "no prefix", query: AutocompleteQuery{Prefix: " . "}, wantErr: ErrNameRequired},
+		{name: "unknown ancestor", query: AutocompleteQuery{Prefix: "c", WithinID: func() *uuid.UUID { id := uuid.New(); return &id }()}, wantErr: ErrLocationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.query.Limit == 0 {
+				tt.query.Limit = 10
+			}
+			suggestions, err := store.Autocomplete(ctx, tt.query)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(suggestions))
+		})
+	}
+
+	t.Run("suggestions carry the primary name and geo level", func(t *testing.T) {
+		suggestions, err := store.Autocomplete(ctx, AutocompleteQuery{Prefix: "dak", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, suggestions, 1)
+		assert.Equal(t, NameSuggestion{
+			LocationID:  locs["State2"].Id,
+			GeoLevel:    "STATE",
+			Name:        "State2",
+			MatchedName: "Dakshin",
+		}, suggestions[0])
+	})
+
+	t.Run("prefix index", func(t *testing.T) {
+		var count int64
+		require.NoError(t, store.DB.Raw("SELECT COUNT(*) FROM pg_indexes WHERE indexname = ?", namePrefixIndex).Scan(&count).Error)
+		assert.EqualValues(t, 1, count)
+	})
+}
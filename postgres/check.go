@@ -0,0 +1,254 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CheckCategory classifies a finding of the consistency check
+type CheckCategory string
+
+const (
+	// CheckMissingPrimaryName is a live location without a primary name
+	CheckMissingPrimaryName CheckCategory = "missing_primary_name"
+	// CheckRankViolation is a relation whose parent rank is not lower than its child rank
+	CheckRankViolation CheckCategory = "rank_violation"
+	// CheckLevelRuleViolation is a relation not allowed by the level rules of the child geo level
+	CheckLevelRuleViolation CheckCategory = "level_rule_violation"
+	// CheckDuplicateParentLevel is a child with more than one parent of the same geo level
+	CheckDuplicateParentLevel CheckCategory = "duplicate_parent_level"
+	// CheckOrphanLocation is a location without parents below the top ranked geo level
+	CheckOrphanLocation CheckCategory = "orphan_location"
+	// CheckDanglingName is a live name of a deleted or missing location
+	CheckDanglingName CheckCategory = "dangling_name"
+	// CheckDanglingRelation is a live relation with a deleted or missing parent or child
+	CheckDanglingRelation CheckCategory = "dangling_relation"
+)
+
+// Finding is an inconsistency found by Check
+type Finding struct {
+	Category CheckCategory `json:"category"`
+	GeoIDs   []uuid.UUID   `json:"geo_ids"`   // locations involved
+	RecordID uuid.UUID     `json:"record_id"` // offending name or relation, if any
+	Detail   string        `json:"detail"`
+	Repaired bool          `json:"repaired"`
+}
+
+// Check scans all tables for hierarchy inconsistencies. With repair set, the safe findings are
+// fixed in the same transaction: dangling names and relations are deleted, and a location
+// without a primary name gets its first alias promoted. Other findings are only reported.
+func (s *Store) Check(ctx context.Context, repair bool) ([]Finding, error) {
+	var findings []Finding
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		checks := []func(*gorm.DB, bool) ([]Finding, error){
+			checkMissingPrimaryNames,
+			checkRankViolations,
+			checkLevelRuleViolations,
+			checkDuplicateParentLevels,
+			checkOrphanLocations,
+			checkDanglingNames,
+			checkDanglingRelations,
+		}
+		for _, check := range checks {
+			found, err := check(tx, repair)
+			if err != nil {
+				return err
+			}
+			findings = append(findings, found...)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to check hierarchy: %w", err)
+	}
+
+	return findings, nil
+}
+
+func checkMissingPrimaryNames(tx *gorm.DB, repair bool) ([]Finding, error) {
+	var locations []Location
+	if err := tx.Where("NOT EXISTS (SELECT 1 FROM name_maps n WHERE n.location_id = locations.id AND n.is_primary AND n.deleted_at IS NULL)").
+		Find(&locations).Error; err != nil {
+		return nil, err
+	}
+
+	findings := make([]Finding, 0, len(locations))
+	for _, loc := range locations {
+		finding := Finding{
+			Category: CheckMissingPrimaryName,
+			GeoIDs:   []uuid.UUID{loc.Id},
+			Detail:   ErrPrimaryNameNotFound.Error(),
+		}
+		if repair {
+			var alias NameMap
+			result := tx.Where("location_id = ?", loc.Id).Order("name ASC").Limit(1).Find(&alias)
+			if result.Error != nil {
+				return nil, result.Error
+			}
+			if result.RowsAffected > 0 {
+				if err := tx.Model(&alias).Update("is_primary", true).Error; err != nil {
+					return nil, err
+				}
+				finding.RecordID = alias.Id
+				finding.Detail = fmt.Sprintf("promoted alias %q to primary name", alias.Name)
+				finding.Repaired = true
+			}
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+func checkRankViolations(tx *gorm.DB, _ bool) ([]Finding, error) {
+	var relations []Relation
+	if err := tx.Joins("JOIN locations p ON p.id = relations.parent_id AND p.deleted_at IS NULL").
+		Joins("JOIN locations c ON c.id = relations.child_id AND c.deleted_at IS NULL").
+		Joins("JOIN geo_levels pl ON pl.id = p.geo_level_id").
+		Joins("JOIN geo_levels cl ON cl.id = c.geo_level_id").
+		Where("pl.rank IS NOT NULL AND cl.rank IS NOT NULL AND pl.rank >= cl.rank").
+		Find(&relations).Error; err != nil {
+		return nil, err
+	}
+
+	return relationFindings(CheckRankViolation, relations, ErrInvalidHierarchy.Error()), nil
+}
+
+func checkLevelRuleViolations(tx *gorm.DB, _ bool) ([]Finding, error) {
+	store := &Store{DB: tx}
+	relations, err := store.GetLevelRuleViolations(tx.Statement.Context, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return relationFindings(CheckLevelRuleViolation, relations, ErrLevelRuleViolation.Error()), nil
+}
+
+func checkDuplicateParentLevels(tx *gorm.DB, _ bool) ([]Finding, error) {
+	var relations []Relation
+	if err := tx.Preload("Parent.GeoLevel").
+		Joins("JOIN locations p ON p.id = relations.parent_id").
+		Where(`EXISTS (
+			SELECT 1 FROM relations r2 JOIN locations p2 ON p2.id = r2.parent_id
+			WHERE r2.child_id = relations.child_id AND r2.id <> relations.id
+			AND p2.geo_level_id = p.geo_level_id AND r2.deleted_at IS NULL)`).
+		Order("relations.child_id, p.geo_level_id").
+		Find(&relations).Error; err != nil {
+		return nil, err
+	}
+
+	// One finding per child and parent geo level, listing the child first and then its parents
+	var findings []Finding
+	index := make(map[[2]uuid.UUID]int)
+	for _, rel := range relations {
+		var levelID uuid.UUID
+		var levelName string
+		if rel.Parent != nil {
+			levelID = rel.Parent.GeoLevelID
+			levelName = rel.Parent.GeoLevel.Name
+		}
+		key := [2]uuid.UUID{rel.ChildID, levelID}
+		i, ok := index[key]
+		if !ok {
+			i = len(findings)
+			index[key] = i
+			findings = append(findings, Finding{
+				Category: CheckDuplicateParentLevel,
+				GeoIDs:   []uuid.UUID{rel.ChildID},
+				Detail:   fmt.Sprintf("child has multiple parents of geo level %s", levelName),
+			})
+		}
+		findings[i].GeoIDs = append(findings[i].GeoIDs, rel.ParentID)
+	}
+
+	return findings, nil
+}
+
+func checkOrphanLocations(tx *gorm.DB, _ bool) ([]Finding, error) {
+	var locations []Location
+	if err := tx.Joins("JOIN geo_levels gl ON gl.id = locations.geo_level_id").
+		Where("locations.superseded_at IS NULL AND gl.rank IS NOT NULL").
+		Where("gl.rank > (SELECT MIN(g.rank) FROM geo_levels g WHERE g.tenant = locations.tenant AND g.deleted_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM relations r WHERE r.child_id = locations.id AND r.deleted_at IS NULL)").
+		Find(&locations).Error; err != nil {
+		return nil, err
+	}
+
+	findings := make([]Finding, 0, len(locations))
+	for _, loc := range locations {
+		findings = append(findings, Finding{
+			Category: CheckOrphanLocation,
+			GeoIDs:   []uuid.UUID{loc.Id},
+			Detail:   "location below the top geo level has no parent",
+		})
+	}
+
+	return findings, nil
+}
+
+func checkDanglingNames(tx *gorm.DB, repair bool) ([]Finding, error) {
+	var names []NameMap
+	if err := tx.Joins("LEFT JOIN locations l ON l.id = name_maps.location_id").
+		Where("l.id IS NULL OR l.deleted_at IS NOT NULL").
+		Find(&names).Error; err != nil {
+		return nil, err
+	}
+
+	findings := make([]Finding, 0, len(names))
+	for _, name := range names {
+		finding := Finding{
+			Category: CheckDanglingName,
+			GeoIDs:   []uuid.UUID{name.LocationID},
+			RecordID: name.Id,
+			Detail:   fmt.Sprintf("name %q belongs to a deleted location", name.Name),
+		}
+		if repair {
+			if err := tx.Delete(&name).Error; err != nil {
+				return nil, err
+			}
+			finding.Repaired = true
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings, nil
+}
+
+func checkDanglingRelations(tx *gorm.DB, repair bool) ([]Finding, error) {
+	var relations []Relation
+	if err := tx.Joins("LEFT JOIN locations p ON p.id = relations.parent_id").
+		Joins("LEFT JOIN locations c ON c.id = relations.child_id").
+		Where("p.id IS NULL OR p.deleted_at IS NOT NULL OR c.id IS NULL OR c.deleted_at IS NOT NULL").
+		Find(&relations).Error; err != nil {
+		return nil, err
+	}
+
+	findings := relationFindings(CheckDanglingRelation, relations, "relation refers to a deleted location")
+	if repair {
+		for i, rel := range relations {
+			if err := tx.Delete(&rel).Error; err != nil {
+				return nil, err
+			}
+			findings[i].Repaired = true
+		}
+	}
+
+	return findings, nil
+}
+
+func relationFindings(category CheckCategory, relations []Relation, detail string) []Finding {
+	findings := make([]Finding, 0, len(relations))
+	for _, rel := range relations {
+		findings = append(findings, Finding{
+			Category: category,
+			GeoIDs:   []uuid.UUID{rel.ParentID, rel.ChildID},
+			RecordID: rel.Id,
+			Detail:   detail,
+		})
+	}
+	return findings
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func findingsByCategory(findings []Finding) map[CheckCategory][]Finding {
+	out := make(map[CheckCategory][]Finding)
+	for _, f := range findings {
+		out[f.Category] = append(out[f.Category], f)
+	}
+	return out
+}
+
+func TestCheck_Check(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+	raw := store.DB.Session(&gorm.Session{SkipHooks: true})
+
+	// A clean hierarchy: Country1 > State1 > District1 > City1
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["State1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["District1"].Id, locs["City1"].Id)
+	require.NoError(t, err)
+
+	// District2 has two STATE parents (bypassing the hook and the trigger)
+	require.NoError(t, raw.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET LOCAL session_replication_role = replica").Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&Relation{BaseModel: BaseModel{Id: uuid.New()}, ParentID: locs["State1"].Id, ChildID: locs["District2"].Id}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Relation{BaseModel: BaseModel{Id: uuid.New()}, ParentID: locs["State2"].Id, ChildID: locs["District2"].Id}).Error
+	}))
+
+	// City2 is below a CITY parent of the same rank
+	require.NoError(t, raw.Create(&Relation{BaseModel: BaseModel{Id: uuid.New()}, ParentID: locs["City1"].Id, ChildID: locs["City2"].Id}).Error)
+
+	// Country2 lost its primary name but has an alias
+	require.NoError(t, store.InsertNameMap(ctx, locs["Country2"].Id, "Alias", false))
+	require.NoError(t, raw.Where("location_id = ? AND is_primary", locs["Country2"].Id).Delete(&NameMap{}).Error)
+
+	// A location deleted without its names and relations
+	ghost, err := store.InsertLocation(ctx, "CITY", "Ghost")
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["District1"].Id, ghost.Id)
+	require.NoError(t, err)
+	require.NoError(t, raw.Delete(&Location{}, ghost.Id).Error)
+
+	t.Run("report only", func(t *testing.T) {
+		findings, err := store.Check(ctx, false)
+		require.NoError(t, err)
+		byCategory := findingsByCategory(findings)
+
+		require.Len(t, byCategory[CheckDuplicateParentLevel], 1)
+		assert.Equal(t, locs["District2"].Id, byCategory[CheckDuplicateParentLevel][0].GeoIDs[0])
+		assert.Len(t, byCategory[CheckDuplicateParentLevel][0].GeoIDs, 3)
+
+		require.Len(t, byCategory[CheckRankViolation], 1)
+		assert.Equal(t, []uuid.UUID{locs["City1"].Id, locs["City2"].Id}, byCategory[CheckRankViolation][0].GeoIDs)
+
+		require.Len(t, byCategory[CheckMissingPrimaryName], 1)
+		assert.Equal(t, locs["Country2"].Id, byCategory[CheckMissingPrimaryName][0].GeoIDs[0])
+
+		assert.Len(t, byCategory[CheckDanglingName], 1)
+		assert.Len(t, byCategory[CheckDanglingRelation], 1)
+
+		// State2 has no parent and is below COUNTRY
+		require.Len(t, byCategory[CheckOrphanLocation], 1)
+		assert.Equal(t, locs["State2"].Id, byCategory[CheckOrphanLocation][0].GeoIDs[0])
+
+		for _, f := range findings {
+			assert.False(t, f.Repaired)
+		}
+	})
+
+	t.Run("repair safe findings", func(t *testing.T) {
+		findings, err := store.Check(ctx, true)
+		require.NoError(t, err)
+		for _, f := range findings {
+			switch f.Category {
+			case CheckMissingPrimaryName, CheckDanglingName, CheckDanglingRelation:
+				assert.True(t, f.Repaired, "%s should be repaired", f.Category)
+			default:
+				assert.False(t, f.Repaired, "%s should not be repaired", f.Category)
+			}
+		}
+
+		name, err := store.GetPrimaryName(ctx, locs["Country2"].Id)
+		require.NoError(t, err)
+		assert.Equal(t, "Alias", name)
+
+		findings, err = store.Check(ctx, false)
+		require.NoError(t, err)
+		byCategory := findingsByCategory(findings)
+		assert.Empty(t, byCategory[CheckMissingPrimaryName])
+		assert.Empty(t, byCategory[CheckDanglingName])
+		assert.Empty(t, byCategory[CheckDanglingRelation])
+		assert.NotEmpty(t, byCategory[CheckDuplicateParentLevel])
+	})
+}
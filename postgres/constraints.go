@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const constraintPluginName = "location:constraints"
+
+// Names of the database constraints backing the hierarchy rules
+const (
+	// primaryNameIndex is the partial unique index allowing one live primary name per location
+	primaryNameIndex = "idx_name_maps_primary"
+	// parentLevelConstraint is raised by the relations trigger when a child would get
+	// a second live parent of the same geo level
+	parentLevelConstraint = "relations_one_parent_per_level"
+)
+
+// legacyGeoLevelNameConstraint is the unique constraint on geo_levels.name of databases
+// created before tenants, superseded by the unique index on (tenant, name)
+const legacyGeoLevelNameConstraint = "geo_levels_name_key"
+
+// namePrefixIndex serves the prefix searches of autocomplete on the normalized names
+const namePrefixIndex = "idx_name_maps_prefix"
+
+// uniqueViolation is the SQLSTATE of unique_violation
+const uniqueViolation = "23505"
+
+// parentLevelTrigger enforces the one-parent-per-level rule. The child row is locked first,
+// so concurrent writers of the child's relations are serialized and each sees the relations
+// committed before it.
+const parentLevelTrigger = `
+CREATE OR REPLACE FUNCTION relations_check_parent_level() RETURNS trigger AS $$
+DECLARE
+	parent_level uuid;
+BEGIN
+	IF NEW.deleted_at IS NOT NULL THEN
+		RETURN NEW;
+	END IF;
+	PERFORM 1 FROM locations WHERE id = NEW.child_id FOR NO KEY UPDATE;
+	SELECT geo_level_id INTO parent_level FROM locations WHERE id = NEW.parent_id;
+	IF EXISTS (
+		SELECT 1 FROM relations r
+		JOIN locations parent ON parent.id = r.parent_id
+		WHERE r.child_id = NEW.child_id AND r.id <> NEW.id AND r.deleted_at IS NULL
+			AND parent.geo_level_id = parent_level
+	) THEN
+		RAISE EXCEPTION 'child % already has a parent of geo level %', NEW.child_id, parent_level
+			USING ERRCODE = 'unique_violation', CONSTRAINT = '` + parentLevelConstraint + `';
+	END IF;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+
+// Migrate creates or updates the tables of all models, together with the prefix index of the
+// normalized names, the GIN index of the attributes and the indexes and triggers enforcing the
+// one-primary-name and one-parent-per-level rules. It fills in the normalized names of names
+// created before the column existed and drops the unique constraint on geo level names left
+// by databases created before tenants. It fails if existing rows already break these rules;
+// resolve the findings of Check first.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&GeoLevel{}, &Location{}, &NameMap{}, &Relation{}, &LevelRule{}, &LocationRedirect{}, &LocationSuccession{}); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE geo_levels DROP CONSTRAINT IF EXISTS " + legacyGeoLevelNameConstraint).Error; err != nil {
+			return err
+		}
+		if err := backfillNormalizedNames(tx); err != nil {
+			return err
+		}
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS " + namePrefixIndex +
+			" ON name_maps (tenant, normalized_name varchar_pattern_ops) WHERE deleted_at IS NULL").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("CREATE INDEX IF NOT EXISTS " + attributesIndex +
+			" ON locations USING GIN (attributes jsonb_path_ops)").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(parentLevelTrigger).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DROP TRIGGER IF EXISTS " + parentLevelConstraint + " ON relations").Error; err != nil {
+			return err
+		}
+		return tx.Exec("CREATE TRIGGER " + parentLevelConstraint +
+			" BEFORE INSERT OR UPDATE OF parent_id, child_id, deleted_at ON relations" +
+			" FOR EACH ROW EXECUTE FUNCTION relations_check_parent_level()").Error
+	})
+}
+
+// normalizedNameBatch is the number of names normalized per statement by Migrate
+const normalizedNameBatch = 1000
+
+// backfillNormalizedNames fills in the normalized names of the names of all tenants created
+// before the column existed, with NormalizeName itself so that they match the names it
+// normalizes on write, in batches by id
+func backfillNormalizedNames(tx *gorm.DB) error {
+	raw := tx.Session(&gorm.Session{NewDB: true})
+	after := uuid.Nil
+	for {
+		var names []struct {
+			ID   uuid.UUID
+			Name string
+		}
+		err := raw.Raw("SELECT id, name FROM name_maps WHERE normalized_name = '' AND id > ? ORDER BY id LIMIT ?",
+			after, normalizedNameBatch).Scan(&names).Error
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		values := make([][]any, 0, len(names))
+		for _, name := range names {
+			values = append(values, []any{name.ID.String(), NormalizeName(name.Name)})
+		}
+		err = raw.Exec("UPDATE name_maps SET normalized_name = v.normalized FROM (VALUES ?) AS v(id, normalized)"+
+			" WHERE name_maps.id = v.id::uuid", values).Error
+		if err != nil {
+			return err
+		}
+		after = names[len(names)-1].ID
+	}
+}
+
+// constraintPlugin translates violations of the hierarchy constraints into the errors
+// returned by the equivalent checks in the hooks, so callers see the same error whether
+// a concurrent writer won the race or not.
+type constraintPlugin struct{}
+
+// Name returns the name of the GORM plugin
+func (constraintPlugin) Name() string {
+	return constraintPluginName
+}
+
+// Initialize registers the error translation callbacks on the GORM instance
+func (constraintPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("location:constraints_create", translateConstraintError); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("location:constraints_update", translateConstraintError); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register("location:constraints_raw", translateConstraintError)
+}
+
+func translateConstraintError(db *gorm.DB) {
+	if db.Error != nil {
+		db.Error = translateConstraint(db.Error)
+	}
+}
+
+// translateConstraint returns the store error for a violation of the hierarchy constraints,
+// or err itself
+func translateConstraint(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case primaryNameIndex:
+		return ErrPrimaryNameExists
+	case parentLevelConstraint:
+		return ErrDuplicateRelation
+	}
+	return err
+}
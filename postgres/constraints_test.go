@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// hammer runs fn concurrently n times and returns the errors by call
+func hammer(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestConstraints_PrimaryName(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+	id := locs["State1"].Id
+
+	t.Run("second primary name is rejected by the database", func(t *testing.T) {
+		raw := store.DB.Session(&gorm.Session{SkipHooks: true})
+		err := raw.Create(&NameMap{BaseModel: BaseModel{Id: uuid.New()}, LocationID: id, Name: "Other", IsPrimary: true}).Error
+		assert.ErrorIs(t, err, ErrPrimaryNameExists)
+	})
+
+	t.Run("concurrent primary names leave one primary", func(t *testing.T) {
+		errs := hammer(10, func(i int) error {
+			return store.SetPrimaryName(ctx, id, fmt.Sprintf("Name%d", i))
+		})
+		succeeded := 0
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrPrimaryNameExists)
+		}
+		assert.Positive(t, succeeded)
+
+		var count int64
+		require.NoError(t, store.DB.Model(&NameMap{}).Where("location_id = ? AND is_primary", id).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestConstraints_ParentLevel(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	t.Run("second parent of a level is rejected by the database", func(t *testing.T) {
+		_, err := store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+		require.NoError(t, err)
+
+		raw := store.DB.Session(&gorm.Session{SkipHooks: true})
+		err = raw.Create(&Relation{BaseModel: BaseModel{Id: uuid.New()}, ParentID: locs["State2"].Id, ChildID: locs["District1"].Id}).Error
+		assert.ErrorIs(t, err, ErrDuplicateRelation)
+	})
+
+	t.Run("concurrent parents of a level leave one relation", func(t *testing.T) {
+		child := locs["District2"].Id
+		parents := []uuid.UUID{locs["State1"].Id, locs["State2"].Id}
+		errs := hammer(10, func(i int) error {
+			_, err := store.InsertRelation(ctx, parents[i%len(parents)], child)
+			return err
+		})
+		succeeded := 0
+		for _, err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrDuplicateRelation)
+		}
+		assert.Equal(t, 1, succeeded)
+
+		relations, err := store.GetParents(ctx, child)
+		require.NoError(t, err)
+		assert.Len(t, relations, 1)
+	})
+
+	t.Run("restoring a deleted relation is checked", func(t *testing.T) {
+		child := locs["City1"].Id
+		rel, err := store.InsertRelation(ctx, locs["District1"].Id, child)
+		require.NoError(t, err)
+		require.NoError(t, store.DeleteRelation(ctx, rel.Id))
+		_, err = store.InsertRelation(ctx, locs["District2"].Id, child)
+		require.NoError(t, err)
+
+		err = store.DB.Unscoped().Model(&Relation{}).Where("id = ?", rel.Id).Update("deleted_at", nil).Error
+		assert.ErrorIs(t, err, ErrDuplicateRelation)
+	})
+}
+
+func TestMigrate_LegacyGeoLevelName(t *testing.T) {
+	store := setupTestDB(t)
+	require.NoError(t, store.DB.Exec("ALTER TABLE geo_levels ADD CONSTRAINT "+legacyGeoLevelNameConstraint+" UNIQUE (name)").Error)
+
+	require.NoError(t, Migrate(store.DB))
+	_, err := store.InsertGeoLevel(WithTenant(context.Background(), "sales"), "TERRITORY", nil)
+	require.NoError(t, err)
+	_, err = store.InsertGeoLevel(WithTenant(context.Background(), "ops"), "TERRITORY", nil)
+	assert.NoError(t, err)
+}
+
+func TestMigrate_NormalizedNameBackfill(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.InsertNameMap(ctx, locs["City1"].Id, "O’Hara", false))
+	require.NoError(t, store.InsertNameMap(ctx, locs["City2"].Id, "St. Louis", false))
+	require.NoError(t, store.DB.Exec("UPDATE name_maps SET normalized_name = ''").Error)
+
+	require.NoError(t, Migrate(store.DB))
+	var names []NameMap
+	require.NoError(t, store.DB.Session(&gorm.Session{NewDB: true}).Raw("SELECT name, normalized_name FROM name_maps").Scan(&names).Error)
+	require.NotEmpty(t, names)
+	for _, name := range names {
+		assert.Equal(t, NormalizeName(name.Name), name.NormalizedName, name.Name)
+	}
+}
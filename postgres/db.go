@@ -5,3 +5,17 @@ import "gorm.io/gorm"
 type Store struct {
 	*gorm.DB
 }
+
+// NewStore returns a store on the given database with tenant scoping enabled.
+// Every store operation is scoped to the tenant carried by its context (see WithTenant),
+// and violations of the hierarchy constraints installed by Migrate surface as the store's errors.
+func NewStore(db *gorm.DB) (*Store, error) {
+	for _, plugin := range []gorm.Plugin{tenantPlugin{}, constraintPlugin{}} {
+		if _, ok := db.Config.Plugins[plugin.Name()]; !ok {
+			if err := db.Use(plugin); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &Store{DB: db}, nil
+}
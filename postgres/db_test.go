@@ -64,13 +64,13 @@ func setupTestDB(t *testing.T) *Store {
 
 connected:
 	// Auto migrate the schemas
-	err := db.AutoMigrate(&GeoLevel{}, &Location{}, &NameMap{}, &Relation{})
+	err := Migrate(db)
 	if err != nil {
-		t.Fatalf("Failed to auto-migrate schemas: %v", err)
+		t.Fatalf("Failed to migrate schemas: %v", err)
 	}
 
 	// Truncate all tables for a clean slate FOR EACH TEST
-	tables := []string{"relations", "name_maps", "locations", "geo_levels"}
+	tables := []string{"location_successions", "location_redirects", "level_rules", "relations", "name_maps", "locations", "geo_levels"}
 	sqlDB, _ := db.DB()
 	for _, table := range tables {
 		_, err := sqlDB.ExecContext(ctx, "TRUNCATE TABLE "+table+" RESTART IDENTITY CASCADE;")
@@ -79,7 +79,11 @@ connected:
 		}
 	}
 
-	return &Store{DB: db}
+	store, err := NewStore(db)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store
 }
 
 func float64Ptr(v float64) *float64 {
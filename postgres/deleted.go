@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeletedLocation is a soft-deleted location with the names it had when it was deleted
+type DeletedLocation struct {
+	LocationWithNames
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// RelationConflict is a relation that could not be applied, with the reason why
+type RelationConflict struct {
+	Relation Relation
+	Err      error
+}
+
+// PurgeResult counts the rows permanently removed by PurgeDeleted
+type PurgeResult struct {
+	Locations int64 `json:"locations"`
+	Names     int64 `json:"names"`
+	Relations int64 `json:"relations"`
+}
+
+// RestoreLocation restores a soft-deleted location together with the names and relations
+// deleted by the same DeleteLocation call. Every restored relation is validated against the
+// current hierarchy rules; relations that are no longer valid (e.g. the other location is
+// deleted, or the child already has a parent of that level) stay deleted and are returned
+// as conflicts.
+func (s *Store) RestoreLocation(ctx context.Context, id uuid.UUID) ([]RelationConflict, error) {
+	var conflicts []RelationConflict
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var location Location
+		if err := tx.Unscoped().First(&location, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+		if !location.DeletedAt.Valid {
+			return ErrLocationNotDeleted
+		}
+		deletedAt := location.DeletedAt.Time
+
+		// Locations merged into another live on as redirects
+		var redirects int64
+		if err := tx.Model(&LocationRedirect{}).Where("from_id = ?", id).Count(&redirects).Error; err != nil {
+			return err
+		}
+		if redirects > 0 {
+			return ErrLocationMerged
+		}
+
+		// The geo level may have been deleted after the location
+		var count int64
+		if err := tx.Model(&GeoLevel{}).Where("id = ?", location.GeoLevelID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrGeoLevelNotFound
+		}
+
+		if err := tx.Unscoped().Model(&location).Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to restore location: %w", err)
+		}
+		if err := touchLocations(tx, location.Id); err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&NameMap{}).
+			Where("location_id = ? AND deleted_at = ?", id, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to restore names: %w", err)
+		}
+
+		var relations []Relation
+		if err := tx.Unscoped().
+			Where("(parent_id = ? OR child_id = ?) AND deleted_at = ?", id, id, deletedAt).
+			Find(&relations).Error; err != nil {
+			return err
+		}
+		for _, rel := range relations {
+			if err := validateRelation(tx, rel.ParentID, rel.ChildID); err != nil {
+				if isRelationConflict(err) {
+					conflicts = append(conflicts, RelationConflict{Relation: rel, Err: err})
+					continue
+				}
+				return err
+			}
+			if err := tx.Unscoped().Model(&rel).Update("deleted_at", nil).Error; err != nil {
+				return fmt.Errorf("failed to restore relation: %w", err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// ListDeletedLocations lists the soft-deleted locations with the names deleted alongside them.
+// Locations merged into another are left out, as they cannot be restored.
+func (s *Store) ListDeletedLocations(ctx context.Context) ([]DeletedLocation, error) {
+	db := s.DB.WithContext(ctx)
+	var locations []Location
+	err := db.
+		Unscoped().
+		Preload("GeoLevel", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("deleted_at IS NOT NULL").
+		Where("id NOT IN (?)", db.Model(&LocationRedirect{}).Select("from_id")).
+		Order("deleted_at DESC").
+		Find(&locations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted locations: %w", err)
+	}
+	if len(locations) == 0 {
+		return []DeletedLocation{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(locations))
+	for _, loc := range locations {
+		ids = append(ids, loc.Id)
+	}
+	var names []NameMap
+	if err := db.
+		Unscoped().
+		Where("location_id IN ? AND deleted_at IS NOT NULL", ids).
+		Order("name").
+		Find(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to get names of deleted locations: %w", err)
+	}
+	namesByLocation := make(map[uuid.UUID][]NameMap, len(locations))
+	for _, name := range names {
+		namesByLocation[name.LocationID] = append(namesByLocation[name.LocationID], name)
+	}
+
+	results := make([]DeletedLocation, 0, len(locations))
+	for _, loc := range locations {
+		result := DeletedLocation{
+			LocationWithNames: LocationWithNames{
+				Id:       loc.Id,
+				GeoLevel: loc.GeoLevel.Name,
+				Aliases:  make([]string, 0),
+			},
+			DeletedAt: loc.DeletedAt.Time,
+		}
+		for _, name := range namesByLocation[loc.Id] {
+			// Only the names deleted by the same call come back on restore
+			if !name.DeletedAt.Time.Equal(loc.DeletedAt.Time) {
+				continue
+			}
+			if name.IsPrimary {
+				result.Name = name.Name
+			} else {
+				result.Aliases = append(result.Aliases, name.Name)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// PurgeDeleted permanently removes locations, names and relations soft-deleted before the given time
+func (s *Store) PurgeDeleted(ctx context.Context, before time.Time) (PurgeResult, error) {
+	var result PurgeResult
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&Relation{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to purge relations: %w", res.Error)
+		}
+		result.Relations = res.RowsAffected
+
+		res = tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&NameMap{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to purge names: %w", res.Error)
+		}
+		result.Names = res.RowsAffected
+
+		res = tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&Location{})
+		if res.Error != nil {
+			return fmt.Errorf("failed to purge locations: %w", res.Error)
+		}
+		result.Locations = res.RowsAffected
+
+		return nil
+	})
+
+	if err != nil {
+		return PurgeResult{}, err
+	}
+
+	return result, nil
+}
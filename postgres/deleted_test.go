@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleted_RestoreLocation(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	country, state, city := locs["Country1"], locs["State1"], locs["City1"]
+	_, err := store.InsertRelation(ctx, country.Id, state.Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, state.Id, city.Id)
+	require.NoError(t, err)
+	require.NoError(t, store.InsertNameMap(ctx, state.Id, "State Alias", false))
+
+	// An alias removed before the location is deleted must stay removed
+	require.NoError(t, store.InsertNameMap(ctx, state.Id, "Old Alias", false))
+	require.NoError(t, store.DeleteNameMap(ctx, state.Id, "Old Alias"))
+
+	require.NoError(t, store.DeleteLocation(ctx, state.Id))
+
+	t.Run("restore location, names and relations", func(t *testing.T) {
+		conflicts, err := store.RestoreLocation(ctx, state.Id)
+		require.NoError(t, err)
+		assert.Empty(t, conflicts)
+
+		restored, err := store.GetLocation(ctx, state.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"State Alias"}, restored.Aliases)
+
+		parents, err := store.GetParents(ctx, state.Id)
+		require.NoError(t, err)
+		assert.Len(t, parents, 1)
+		children, err := store.GetChildren(ctx, state.Id)
+		require.NoError(t, err)
+		assert.Len(t, children, 1)
+	})
+
+	t.Run("restore a live location", func(t *testing.T) {
+		_, err := store.RestoreLocation(ctx, state.Id)
+		assert.ErrorIs(t, err, ErrLocationNotDeleted)
+	})
+
+	t.Run("relations to deleted locations are reported", func(t *testing.T) {
+		require.NoError(t, store.DeleteLocation(ctx, state.Id))
+		require.NoError(t, store.DeleteLocation(ctx, country.Id))
+
+		conflicts, err := store.RestoreLocation(ctx, state.Id)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, country.Id, conflicts[0].Relation.ParentID)
+		assert.ErrorIs(t, conflicts[0].Err, ErrLocationNotFound)
+
+		children, err := store.GetChildren(ctx, state.Id)
+		require.NoError(t, err)
+		assert.Len(t, children, 1)
+	})
+}
+
+func TestDeleted_ListDeletedLocations(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := store.InsertGeoLevel(ctx, "COUNTRY", float64Ptr(1.0))
+	require.NoError(t, err)
+	kept, err := store.InsertLocation(ctx, "COUNTRY", "India")
+	require.NoError(t, err)
+	deleted, err := store.InsertLocation(ctx, "COUNTRY", "Ceylon")
+	require.NoError(t, err)
+	require.NoError(t, store.InsertNameMap(ctx, deleted.Id, "Serendib", false))
+	require.NoError(t, store.DeleteLocation(ctx, deleted.Id))
+
+	// Merged duplicates cannot be restored and are not listed
+	duplicate, err := store.InsertLocation(ctx, "COUNTRY", "Bharat")
+	require.NoError(t, err)
+	_, err = store.MergeLocations(ctx, kept.Id, []uuid.UUID{duplicate.Id})
+	require.NoError(t, err)
+
+	list, err := store.ListDeletedLocations(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, deleted.Id, list[0].Id)
+	assert.NotEqual(t, kept.Id, list[0].Id)
+	assert.Equal(t, "Ceylon", list[0].Name)
+	assert.Equal(t, []string{"Serendib"}, list[0].Aliases)
+	assert.Equal(t, "COUNTRY", list[0].GeoLevel)
+	assert.False(t, list[0].DeletedAt.IsZero())
+}
+
+func TestDeleted_PurgeDeleted(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["State1"].Id)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteLocation(ctx, locs["State1"].Id))
+
+	// Nothing is older than an hour ago
+	result, err := store.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, PurgeResult{}, result)
+
+	result, err = store.PurgeDeleted(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.Locations)
+	assert.Equal(t, int64(1), result.Names)
+	assert.Equal(t, int64(1), result.Relations)
+
+	_, err = store.RestoreLocation(ctx, locs["State1"].Id)
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+
+	list, err := store.ListDeletedLocations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
@@ -7,6 +7,7 @@ var (
 	ErrInvalidHierarchy       = errors.New("parent rank must be lower than child rank")
 	ErrDuplicateRelation      = errors.New("child already has a parent of this level")
 	ErrLocationNotFound       = errors.New("location not found")
+	ErrLocationNotDeleted     = errors.New("location is not deleted")
 	ErrNameRequired           = errors.New("name is required")
 	ErrNameAlreadyExists      = errors.New("name already exists for this location")
 	ErrCannotDeletePrimary    = errors.New("cannot delete primary name")
@@ -16,6 +17,31 @@ var (
 	ErrGeoLevelAlreadyExists  = errors.New("geo level with this name already exists")
 	ErrGeoLevelNotFound       = errors.New("geo level not found")
 	ErrGeoLevelInUse          = errors.New("geo level is in use by locations and cannot be deleted")
+	ErrGeoLevelReassignToSelf = errors.New("cannot reassign locations of a geo level to itself")
 	ErrRelationNotFound       = errors.New("relation not found")
 	ErrSelfRelationNotAllowed = errors.New("parent and child cannot be the same location")
+	ErrCyclicRelation         = errors.New("parent cannot be a descendant of the child")
+	ErrInconsistentAncestors  = errors.New("descendants have ancestors inconsistent with the move")
+	ErrLevelRuleExists        = errors.New("level rule already exists")
+	ErrLevelRuleNotFound      = errors.New("level rule not found")
+	ErrLevelRuleViolation     = errors.New("parent geo level is not allowed for the child geo level")
+	ErrMergeIntoSelf          = errors.New("cannot merge a location into itself")
+	ErrMergeGeoLevelMismatch  = errors.New("merged locations must have the same geo level")
+	ErrLocationMerged         = errors.New("location was merged into another location")
+	ErrLocationSuperseded     = errors.New("location is superseded by its successors")
+	ErrSupersededRelation     = errors.New("superseded locations cannot get new relations")
+	ErrSuccessorRequired      = errors.New("at least one successor is required")
+	ErrInvalidSplit           = errors.New("invalid split")
+	ErrHierarchyViolation     = errors.New("update would break existing relations")
+	ErrVersionConflict        = errors.New("location was changed concurrently")
+	ErrLocationRequired       = errors.New("at least one location is required")
+	ErrNoCommonAncestor       = errors.New("locations have no common ancestor")
+	ErrLanguageRequired       = errors.New("language is required")
+	ErrPathRequired           = errors.New("path is required")
+	ErrAmbiguousPath          = errors.New("path matches more than one location")
+	ErrInvalidAttributeKey    = errors.New("invalid attribute key")
+	ErrInvalidAttributeValue  = errors.New("invalid attribute value")
+	ErrInvalidAttributeFilter = errors.New("invalid attribute filter")
+	ErrInvalidAttributeSchema = errors.New("invalid attribute schema")
+	ErrAttributeViolation     = errors.New("attributes break the schema of the geo level")
 )
@@ -6,13 +6,17 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type GeoLevel struct {
 	BaseModel
-	Name string   `gorm:"type:varchar(64);unique;not null;check:name = upper(name)" json:"name"`
-	Rank *float64 `gorm:"type:float" json:"rank"`
+	Tenant string   `gorm:"type:varchar(64);not null;default:'default';uniqueIndex:idx_geo_levels_tenant_name" json:"tenant"`
+	Name   string   `gorm:"type:varchar(64);not null;uniqueIndex:idx_geo_levels_tenant_name;check:name = upper(name)" json:"name"`
+	Rank   *float64 `gorm:"type:float" json:"rank"`
+	// AttributeSchema constrains the attributes of the locations of the geo level
+	AttributeSchema AttributeSchema `gorm:"type:jsonb;not null;default:'{}'" json:"attribute_schema"`
 }
 
 // TableName returns the table name for the GeoLevel model
@@ -104,7 +108,48 @@ func (s *Store) GetGeoLevelByName(ctx context.Context, name string) (*GeoLevel,
 	return &geoLevel, nil
 }
 
-// UpdateGeoLevel updates a geo level by its name
+// ListGeoLevels returns all geo levels ordered by rank (unranked levels last) and name
+func (s *Store) ListGeoLevels(ctx context.Context) ([]GeoLevel, error) {
+	var geoLevels []GeoLevel
+	err := s.DB.WithContext(ctx).
+		Order("rank ASC NULLS LAST, name ASC").
+		Find(&geoLevels).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list geo levels: %w", err)
+	}
+
+	return geoLevels, nil
+}
+
+// CountLocationsByGeoLevel returns the number of locations per geo level id.
+// If geoLevelIDs is empty, counts are returned for every geo level in use.
+func (s *Store) CountLocationsByGeoLevel(ctx context.Context, geoLevelIDs ...uuid.UUID) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		GeoLevelID uuid.UUID
+		Count      int64
+	}
+	query := s.DB.WithContext(ctx).
+		Model(&Location{}).
+		Select("geo_level_id, COUNT(*) AS count").
+		Group("geo_level_id")
+	if len(geoLevelIDs) > 0 {
+		query = query.Where("geo_level_id IN ?", geoLevelIDs)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count locations by geo level: %w", err)
+	}
+
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.GeoLevelID] = row.Count
+	}
+
+	return counts, nil
+}
+
+// UpdateGeoLevel updates a geo level by its name.
+// A rank change that breaks the rank order of existing relations fails with a
+// *HierarchyViolationError listing those relations.
 func (s *Store) UpdateGeoLevel(ctx context.Context, name string, newName *string, newRank *float64) (*GeoLevel, error) {
 	if name == "" {
 		return nil, ErrGeoLevelNameRequired
@@ -146,6 +191,13 @@ func (s *Store) UpdateGeoLevel(ctx context.Context, name string, newName *string
 		}
 
 		if newRank != nil {
+			conflicts, err := rankChangeConflicts(tx, geoLevel, newRank)
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				return &HierarchyViolationError{Conflicts: conflicts}
+			}
 			geoLevel.Rank = newRank
 		}
 
@@ -187,6 +239,11 @@ func (s *Store) DeleteGeoLevel(ctx context.Context, name string) error {
 			return ErrGeoLevelInUse
 		}
 
+		// Delete the level rules referring to the geo level
+		if err := deleteLevelRulesOfGeoLevel(tx, geoLevel.Id); err != nil {
+			return err
+		}
+
 		// Delete the geo level
 		return tx.Delete(&geoLevel).Error
 	})
@@ -197,3 +254,83 @@ func (s *Store) DeleteGeoLevel(ctx context.Context, name string) error {
 
 	return nil
 }
+
+// DeleteGeoLevelWithReassignment moves all locations of a geo level to the target
+// geo level and then deletes the emptied geo level. If relations of the moved locations
+// would break the hierarchy rules at the target, it fails with a *HierarchyViolationError,
+// and if their attributes would break the attribute schema of the target, with an
+// *AttributeSchemaError.
+func (s *Store) DeleteGeoLevelWithReassignment(ctx context.Context, name string, targetName string) error {
+	if name == "" || targetName == "" {
+		return ErrGeoLevelNameRequired
+	}
+
+	name = strings.ToUpper(name)
+	targetName = strings.ToUpper(targetName)
+	if name == targetName {
+		return ErrGeoLevelReassignToSelf
+	}
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var geoLevel, target GeoLevel
+		if err := tx.Where("name = ?", name).First(&geoLevel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+		if err := tx.Where("name = ?", targetName).First(&target).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+
+		// Every relation of the moved locations must remain valid at the target
+		var locations []Location
+		if err := tx.Where("geo_level_id = ?", geoLevel.Id).Find(&locations).Error; err != nil {
+			return err
+		}
+		var conflicts []RelationConflict
+		var violations []AttributeViolation
+		for _, loc := range locations {
+			found, err := levelChangeConflicts(tx, loc.Id, target)
+			if err != nil {
+				return err
+			}
+			conflicts = append(conflicts, found...)
+			var schemaErr *AttributeSchemaError
+			err = checkAttributeSchema(loc.Id, target, loc.Attributes)
+			if errors.As(err, &schemaErr) {
+				violations = append(violations, schemaErr.Violations...)
+			} else if err != nil {
+				return err
+			}
+		}
+		if len(conflicts) > 0 {
+			return &HierarchyViolationError{Conflicts: conflicts}
+		}
+		if len(violations) > 0 {
+			return &AttributeSchemaError{Violations: violations}
+		}
+
+		// Move every location of the geo level to the target
+		if err := tx.Model(&Location{}).
+			Where("geo_level_id = ?", geoLevel.Id).
+			Update("geo_level_id", target.Id).Error; err != nil {
+			return err
+		}
+
+		if err := deleteLevelRulesOfGeoLevel(tx, geoLevel.Id); err != nil {
+			return err
+		}
+
+		return tx.Delete(&geoLevel).Error
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to delete geo level: %w", err)
+	}
+
+	return nil
+}
@@ -444,3 +444,136 @@ func TestGeoLevel_DeleteGeoLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestGeoLevel_ListGeoLevels(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	// Empty table returns no levels
+	levels, err := store.ListGeoLevels(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+
+	_, err = store.InsertGeoLevel(ctx, "STATE", float64Ptr(2.0))
+	require.NoError(t, err)
+	_, err = store.InsertGeoLevel(ctx, "ZONE", nil)
+	require.NoError(t, err)
+	_, err = store.InsertGeoLevel(ctx, "COUNTRY", float64Ptr(1.0))
+	require.NoError(t, err)
+
+	levels, err = store.ListGeoLevels(ctx)
+	require.NoError(t, err)
+	require.Len(t, levels, 3)
+	assert.Equal(t, "COUNTRY", levels[0].Name)
+	assert.Equal(t, "STATE", levels[1].Name)
+	assert.Equal(t, "ZONE", levels[2].Name, "unranked levels should be listed last")
+}
+
+func TestGeoLevel_CountLocationsByGeoLevel(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	country, err := store.InsertGeoLevel(ctx, "COUNTRY", float64Ptr(1.0))
+	require.NoError(t, err)
+	state, err := store.InsertGeoLevel(ctx, "STATE", float64Ptr(2.0))
+	require.NoError(t, err)
+	unused, err := store.InsertGeoLevel(ctx, "DISTRICT", float64Ptr(3.0))
+	require.NoError(t, err)
+
+	_, err = store.InsertLocation(ctx, "COUNTRY", "India")
+	require.NoError(t, err)
+	_, err = store.InsertLocation(ctx, "STATE", "Kerala")
+	require.NoError(t, err)
+	_, err = store.InsertLocation(ctx, "STATE", "Karnataka")
+	require.NoError(t, err)
+
+	counts, err := store.CountLocationsByGeoLevel(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counts[country.Id])
+	assert.Equal(t, int64(2), counts[state.Id])
+	assert.Equal(t, int64(0), counts[unused.Id])
+
+	counts, err = store.CountLocationsByGeoLevel(ctx, state.Id)
+	require.NoError(t, err)
+	assert.Len(t, counts, 1)
+	assert.Equal(t, int64(2), counts[state.Id])
+}
+
+func TestGeoLevel_DeleteGeoLevelWithReassignment(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := store.InsertGeoLevel(ctx, "CITY", float64Ptr(4.0))
+	require.NoError(t, err)
+	town, err := store.InsertGeoLevel(ctx, "TOWN", float64Ptr(4.0))
+	require.NoError(t, err)
+
+	city, err := store.InsertLocation(ctx, "CITY", "Kochi")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		level   string
+		target  string
+		wantErr bool
+		errType error
+	}{
+		{
+			name:    "reassign to itself",
+			level:   "CITY",
+			target:  "city",
+			wantErr: true,
+			errType: ErrGeoLevelReassignToSelf,
+		},
+		{
+			name:    "non-existent target",
+			level:   "CITY",
+			target:  "VILLAGE",
+			wantErr: true,
+			errType: ErrGeoLevelNotFound,
+		},
+		{
+			name:    "non-existent level",
+			level:   "VILLAGE",
+			target:  "TOWN",
+			wantErr: true,
+			errType: ErrGeoLevelNotFound,
+		},
+		{
+			name:    "empty target",
+			level:   "CITY",
+			target:  "",
+			wantErr: true,
+			errType: ErrGeoLevelNameRequired,
+		},
+		{
+			name:    "reassign and delete",
+			level:   "CITY",
+			target:  "TOWN",
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.DeleteGeoLevelWithReassignment(ctx, tt.level, tt.target)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errType != nil {
+					assert.ErrorIs(t, err, tt.errType)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+
+			_, err = store.GetGeoLevelByName(ctx, tt.level)
+			assert.ErrorIs(t, err, ErrGeoLevelNotFound)
+
+			loc, err := store.GetLocation(ctx, city.Id)
+			require.NoError(t, err)
+			assert.Equal(t, town.Name, loc.GeoLevel)
+		})
+	}
+}
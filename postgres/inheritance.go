@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EffectiveAttribute is the value of an attribute for a location with the location that
+// supplies it: the location itself, or the ancestor it inherits the attribute from
+type EffectiveAttribute struct {
+	Value    any
+	SourceID uuid.UUID
+	Depth    int // relations between the location and the source, 0 for an own value
+}
+
+// GetEffectiveAttributes returns the attributes of a location and, for every attribute the
+// schema of its geo level marks as inherited but the location lacks, the value of its nearest
+// ancestor that has it. Between ancestors as near, the one of the lowest geo level wins, then
+// the smallest id. An own value always overrides inherited ones, at any level.
+func (s *Store) GetEffectiveAttributes(ctx context.Context, id uuid.UUID) (map[string]EffectiveAttribute, error) {
+	db := s.DB.WithContext(ctx)
+	var location Location
+	if err := db.Preload("GeoLevel").First(&location, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+
+	effective := make(map[string]EffectiveAttribute, len(location.Attributes))
+	for key, value := range location.Attributes {
+		effective[key] = EffectiveAttribute{Value: value, SourceID: id}
+	}
+	var missing []string
+	for key, rule := range location.GeoLevel.AttributeSchema {
+		if _, ok := effective[key]; rule.Inherited && !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return effective, nil
+	}
+
+	depths, err := ancestorDepths(db, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ancestors: %w", err)
+	}
+	delete(depths, id)
+	if len(depths) == 0 {
+		return effective, nil
+	}
+	var ancestors []Location
+	err = db.Preload("GeoLevel").
+		Select("id", "geo_level_id", "attributes").
+		Where("id IN ?", slices.Collect(maps.Keys(depths))).
+		Find(&ancestors).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ancestors: %w", err)
+	}
+	slices.SortFunc(ancestors, func(a, b Location) int {
+		return cmp.Or(
+			cmp.Compare(depths[a.Id], depths[b.Id]),
+			compareRanksLowestFirst(a.GeoLevel.Rank, b.GeoLevel.Rank),
+			cmp.Compare(a.Id.String(), b.Id.String()),
+		)
+	})
+
+	for _, key := range missing {
+		for _, ancestor := range ancestors {
+			if value, ok := ancestor.Attributes[key]; ok && value != nil {
+				effective[key] = EffectiveAttribute{Value: value, SourceID: ancestor.Id, Depth: depths[ancestor.Id]}
+				break
+			}
+		}
+	}
+	return effective, nil
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEffectiveAttributes(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	set := func(name string, attributes Attributes) {
+		_, err := store.SetAttributes(ctx, locs[name].Id, attributes)
+		require.NoError(t, err)
+	}
+	set("Country1", Attributes{"timezone": "UTC", "currency": "INR"})
+	set("State1", Attributes{"timezone": "Asia/Kolkata"})
+	set("District2", Attributes{"timezone": "Asia/Colombo"})
+	set("City1", Attributes{"population": 2.1e6})
+	_, err := store.SetAttributeSchema(ctx, "city", AttributeSchema{
+		"timezone": {Type: AttributeString, Inherited: true},
+		"currency": {Inherited: true},
+		"locale":   {Inherited: true},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		location string
+		want     map[string]EffectiveAttribute
+	}{
+		{name: "inherited from the nearest ancestor", location: "City1", want: map[string]EffectiveAttribute{
+			"population": {Value: 2.1e6, SourceID: locs["City1"].Id},
+			"timezone":   {Value: "Asia/Kolkata", SourceID: locs["State1"].Id, Depth: 2},
+			"currency":   {Value: "INR", SourceID: locs["Country1"].Id, Depth: 3},
+		}},
+		{name: "overridden below", location: "City2", want: map[string]EffectiveAttribute{
+			"timezone": {Value: "Asia/Colombo", SourceID: locs["District2"].Id, Depth: 1},
+			"currency": {Value: "INR", SourceID: locs["Country1"].Id, Depth: 3},
+		}},
+		{name: "not inherited without the schema", location: "District1", want: map[string]EffectiveAttribute{}},
+		{name: "own values", location: "State1", want: map[string]EffectiveAttribute{
+			"timezone": {Value: "Asia/Kolkata", SourceID: locs["State1"].Id},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attributes, err := store.GetEffectiveAttributes(ctx, locs[tt.location].Id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, attributes)
+		})
+	}
+
+	t.Run("own value overrides", func(t *testing.T) {
+		set("City2", Attributes{"timezone": "Asia/Dhaka"})
+		attributes, err := store.GetEffectiveAttributes(ctx, locs["City2"].Id)
+		require.NoError(t, err)
+		assert.Equal(t, EffectiveAttribute{Value: "Asia/Dhaka", SourceID: locs["City2"].Id}, attributes["timezone"])
+	})
+
+	_, err = store.GetEffectiveAttributes(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrLocationNotFound)
+}
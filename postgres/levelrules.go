@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LevelRule allows locations of the child geo level to have a parent of the parent geo level.
+// A child geo level without any rule may have a parent of any geo level (subject to rank).
+// Once a child geo level has at least one rule, only the listed parent geo levels are allowed.
+type LevelRule struct {
+	BaseModel
+	Tenant           string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant"`
+	ParentGeoLevelID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_level_rules_parent_child,where:deleted_at IS NULL" json:"parent_geo_level_id"`
+	ChildGeoLevelID  uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_level_rules_parent_child,where:deleted_at IS NULL" json:"child_geo_level_id"`
+	ParentGeoLevel   GeoLevel  `gorm:"foreignKey:ParentGeoLevelID;references:Id;constraint:OnDelete:CASCADE" json:"parent_geo_level"`
+	ChildGeoLevel    GeoLevel  `gorm:"foreignKey:ChildGeoLevelID;references:Id;constraint:OnDelete:CASCADE" json:"child_geo_level"`
+}
+
+// TableName returns the table name for the LevelRule model
+func (LevelRule) TableName() string {
+	return "level_rules"
+}
+
+// InsertLevelRule allows locations of the child geo level to have parents of the parent geo level
+func (s *Store) InsertLevelRule(ctx context.Context, parentGeoLevelName string, childGeoLevelName string) (*LevelRule, error) {
+	if parentGeoLevelName == "" || childGeoLevelName == "" {
+		return nil, ErrGeoLevelNameRequired
+	}
+
+	var rule *LevelRule
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var parentLevel, childLevel GeoLevel
+		if err := tx.Where("name = ?", strings.ToUpper(parentGeoLevelName)).First(&parentLevel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+		if err := tx.Where("name = ?", strings.ToUpper(childGeoLevelName)).First(&childLevel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+
+		// A level rule must respect the rank ordering when both levels are ranked
+		if parentLevel.Rank != nil && childLevel.Rank != nil && *parentLevel.Rank >= *childLevel.Rank {
+			return ErrInvalidHierarchy
+		}
+
+		var count int64
+		if err := tx.Model(&LevelRule{}).
+			Where("parent_geo_level_id = ? AND child_geo_level_id = ?", parentLevel.Id, childLevel.Id).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrLevelRuleExists
+		}
+
+		rule = &LevelRule{
+			ParentGeoLevelID: parentLevel.Id,
+			ChildGeoLevelID:  childLevel.Id,
+			ParentGeoLevel:   parentLevel,
+			ChildGeoLevel:    childLevel,
+		}
+		return tx.Omit("ParentGeoLevel", "ChildGeoLevel").Create(rule).Error
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create level rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// ListLevelRules returns all level rules with their geo levels
+func (s *Store) ListLevelRules(ctx context.Context) ([]LevelRule, error) {
+	var rules []LevelRule
+	err := s.DB.WithContext(ctx).
+		Preload("ParentGeoLevel").
+		Preload("ChildGeoLevel").
+		Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list level rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// DeleteLevelRule removes the rule allowing the parent geo level for the child geo level
+func (s *Store) DeleteLevelRule(ctx context.Context, parentGeoLevelName string, childGeoLevelName string) error {
+	if parentGeoLevelName == "" || childGeoLevelName == "" {
+		return ErrGeoLevelNameRequired
+	}
+
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var parentLevel, childLevel GeoLevel
+		if err := tx.Where("name = ?", strings.ToUpper(parentGeoLevelName)).First(&parentLevel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+		if err := tx.Where("name = ?", strings.ToUpper(childGeoLevelName)).First(&childLevel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+
+		result := tx.Where("parent_geo_level_id = ? AND child_geo_level_id = ?", parentLevel.Id, childLevel.Id).
+			Delete(&LevelRule{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLevelRuleNotFound
+		}
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to delete level rule: %w", err)
+	}
+
+	return nil
+}
+
+// GetLevelRuleViolations returns the existing relations whose parent geo level is not allowed
+// by the level rules of the child geo level. If childGeoLevelID is given, only relations of
+// children at that geo level are checked.
+func (s *Store) GetLevelRuleViolations(ctx context.Context, childGeoLevelID *uuid.UUID) ([]Relation, error) {
+	query := s.DB.WithContext(ctx).
+		Joins("JOIN locations parent ON parent.id = relations.parent_id AND parent.deleted_at IS NULL").
+		Joins("JOIN locations child ON child.id = relations.child_id AND child.deleted_at IS NULL").
+		Where("EXISTS (SELECT 1 FROM level_rules lr WHERE lr.child_geo_level_id = child.geo_level_id AND lr.deleted_at IS NULL)").
+		Where("NOT EXISTS (SELECT 1 FROM level_rules lr WHERE lr.child_geo_level_id = child.geo_level_id AND lr.parent_geo_level_id = parent.geo_level_id AND lr.deleted_at IS NULL)")
+	if childGeoLevelID != nil {
+		query = query.Where("child.geo_level_id = ?", *childGeoLevelID)
+	}
+
+	var relations []Relation
+	err := query.
+		Preload("Parent.GeoLevel").
+		Preload("Child.GeoLevel").
+		Find(&relations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get level rule violations: %w", err)
+	}
+
+	return relations, nil
+}
+
+// checkLevelRule returns ErrLevelRuleViolation if the child geo level has level rules
+// and none of them allows the parent geo level
+func checkLevelRule(tx *gorm.DB, parentGeoLevelID uuid.UUID, childGeoLevelID uuid.UUID) error {
+	var rules []LevelRule
+	if err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("child_geo_level_id = ?", childGeoLevelID).
+		Find(&rules).Error; err != nil {
+		return err
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	for _, rule := range rules {
+		if rule.ParentGeoLevelID == parentGeoLevelID {
+			return nil
+		}
+	}
+
+	return ErrLevelRuleViolation
+}
+
+// deleteLevelRulesOfGeoLevel removes every level rule referring to the geo level
+func deleteLevelRulesOfGeoLevel(tx *gorm.DB, geoLevelID uuid.UUID) error {
+	return tx.Where("parent_geo_level_id = ? OR child_geo_level_id = ?", geoLevelID, geoLevelID).
+		Delete(&LevelRule{}).Error
+}
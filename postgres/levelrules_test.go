@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupLevelRulesTest(t *testing.T) (*Store, map[string]*Location) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	levels := []struct {
+		name string
+		rank float64
+	}{
+		{"COUNTRY", 1.0},
+		{"STATE", 2.0},
+		{"UNION_TERRITORY", 2.0},
+		{"DISTRICT", 3.0},
+		{"PINCODE", 5.0},
+	}
+	for _, level := range levels {
+		_, err := store.InsertGeoLevel(ctx, level.name, float64Ptr(level.rank))
+		require.NoError(t, err)
+	}
+
+	locations := make(map[string]*Location)
+	for name, level := range map[string]string{
+		"india":      "COUNTRY",
+		"kerala":     "STATE",
+		"delhi":      "UNION_TERRITORY",
+		"ernakulam":  "DISTRICT",
+		"new_delhi":  "DISTRICT",
+		"pincode":    "PINCODE",
+		"pincode_ut": "PINCODE",
+	} {
+		loc, err := store.InsertLocation(ctx, level, name)
+		require.NoError(t, err)
+		locations[name] = loc
+	}
+
+	return store, locations
+}
+
+func TestLevelRule_InsertLevelRule(t *testing.T) {
+	store, _ := setupLevelRulesTest(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		parent  string
+		child   string
+		wantErr bool
+		errType error
+	}{
+		{
+			name:    "valid rule",
+			parent:  "STATE",
+			child:   "DISTRICT",
+			wantErr: false,
+		},
+		{
+			name:    "valid rule lowercase",
+			parent:  "union_territory",
+			child:   "district",
+			wantErr: false,
+		},
+		{
+			name:    "duplicate rule",
+			parent:  "STATE",
+			child:   "DISTRICT",
+			wantErr: true,
+			errType: ErrLevelRuleExists,
+		},
+		{
+			name:    "parent ranked below child",
+			parent:  "PINCODE",
+			child:   "STATE",
+			wantErr: true,
+			errType: ErrInvalidHierarchy,
+		},
+		{
+			name:    "non-existent geo level",
+			parent:  "STATE",
+			child:   "VILLAGE",
+			wantErr: true,
+			errType: ErrGeoLevelNotFound,
+		},
+		{
+			name:    "empty geo level",
+			parent:  "",
+			child:   "DISTRICT",
+			wantErr: true,
+			errType: ErrGeoLevelNameRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := store.InsertLevelRule(ctx, tt.parent, tt.child)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errType != nil {
+					assert.ErrorIs(t, err, tt.errType)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			require.NotNil(t, rule)
+			assert.NotEqual(t, rule.ParentGeoLevelID, rule.ChildGeoLevelID)
+		})
+	}
+
+	rules, err := store.ListLevelRules(ctx)
+	require.NoError(t, err)
+	assert.Len(t, rules, 2)
+}
+
+func TestLevelRule_EnforcedOnInsertRelation(t *testing.T) {
+	store, locs := setupLevelRulesTest(t)
+	ctx := context.Background()
+
+	_, err := store.InsertLevelRule(ctx, "STATE", "DISTRICT")
+	require.NoError(t, err)
+	_, err = store.InsertLevelRule(ctx, "UNION_TERRITORY", "DISTRICT")
+	require.NoError(t, err)
+	_, err = store.InsertLevelRule(ctx, "DISTRICT", "PINCODE")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		parent  string
+		child   string
+		wantErr bool
+		errType error
+	}{
+		{
+			name:    "district under state",
+			parent:  "kerala",
+			child:   "ernakulam",
+			wantErr: false,
+		},
+		{
+			name:    "district under union territory",
+			parent:  "delhi",
+			child:   "new_delhi",
+			wantErr: false,
+		},
+		{
+			name:    "pincode under district",
+			parent:  "ernakulam",
+			child:   "pincode",
+			wantErr: false,
+		},
+		{
+			name:    "pincode directly under country",
+			parent:  "india",
+			child:   "pincode_ut",
+			wantErr: true,
+			errType: ErrLevelRuleViolation,
+		},
+		{
+			name:    "state under country without rules",
+			parent:  "india",
+			child:   "kerala",
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.InsertRelation(ctx, locs[tt.parent].Id, locs[tt.child].Id)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errType != nil {
+					assert.ErrorIs(t, err, tt.errType)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLevelRule_EnforcedOnUpdateLocation(t *testing.T) {
+	store, locs := setupLevelRulesTest(t)
+	ctx := context.Background()
+
+	_, err := store.InsertRelation(ctx, locs["india"].Id, locs["pincode"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertLevelRule(ctx, "COUNTRY", "STATE")
+	require.NoError(t, err)
+
+	// Moving the pincode to STATE keeps its COUNTRY parent, which is allowed
+	_, err = store.UpdateLocation(ctx, locs["pincode"].Id, stringPtr("STATE"), nil)
+	assert.NoError(t, err)
+
+	// Once DISTRICT only allows STATE parents, its COUNTRY parent blocks the move
+	_, err = store.InsertLevelRule(ctx, "STATE", "DISTRICT")
+	require.NoError(t, err)
+	_, err = store.UpdateLocation(ctx, locs["pincode"].Id, stringPtr("DISTRICT"), nil)
+	assert.ErrorIs(t, err, ErrLevelRuleViolation)
+}
+
+func TestLevelRule_DeleteLevelRule(t *testing.T) {
+	store, _ := setupLevelRulesTest(t)
+	ctx := context.Background()
+
+	_, err := store.InsertLevelRule(ctx, "STATE", "DISTRICT")
+	require.NoError(t, err)
+
+	err = store.DeleteLevelRule(ctx, "STATE", "DISTRICT")
+	assert.NoError(t, err)
+
+	err = store.DeleteLevelRule(ctx, "STATE", "DISTRICT")
+	assert.ErrorIs(t, err, ErrLevelRuleNotFound)
+
+	// A deleted rule can be added again
+	_, err = store.InsertLevelRule(ctx, "STATE", "DISTRICT")
+	assert.NoError(t, err)
+
+	err = store.DeleteLevelRule(ctx, "STATE", "VILLAGE")
+	assert.ErrorIs(t, err, ErrGeoLevelNotFound)
+}
+
+func TestLevelRule_GetLevelRuleViolations(t *testing.T) {
+	store, locs := setupLevelRulesTest(t)
+	ctx := context.Background()
+
+	_, err := store.InsertRelation(ctx, locs["kerala"].Id, locs["ernakulam"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["india"].Id, locs["pincode"].Id)
+	require.NoError(t, err)
+
+	violations, err := store.GetLevelRuleViolations(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, violations, "no rules means no violations")
+
+	rule, err := store.InsertLevelRule(ctx, "DISTRICT", "PINCODE")
+	require.NoError(t, err)
+	_, err = store.InsertLevelRule(ctx, "STATE", "DISTRICT")
+	require.NoError(t, err)
+
+	violations, err = store.GetLevelRuleViolations(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, locs["india"].Id, violations[0].ParentID)
+	assert.Equal(t, locs["pincode"].Id, violations[0].ChildID)
+	assert.Equal(t, "PINCODE", violations[0].Child.GeoLevel.Name)
+
+	violations, err = store.GetLevelRuleViolations(ctx, &rule.ParentGeoLevelID)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
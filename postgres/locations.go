@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -13,8 +14,16 @@ import (
 // Location represents an identifiable unit of a location
 type Location struct {
 	BaseModel
+	Tenant     string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant"`
 	GeoLevelID uuid.UUID `gorm:"type:uuid;not null;index" json:"geo_level_id"`
 	GeoLevel   GeoLevel  `gorm:"foreignKey:GeoLevelID;references:Id;constraint:OnDelete:RESTRICT" json:"geo_level"`
+	// SupersededAt is set when the location was split into successor locations
+	SupersededAt *time.Time `gorm:"index" json:"superseded_at"`
+	// Version is bumped once per transaction changing the location, its names or its relations
+	Version   int64 `gorm:"not null;default:1" json:"version"`
+	VersionTx int64 `gorm:"not null;default:0" json:"-"` // transaction that last bumped Version
+	// Attributes are arbitrary typed values such as population or timezone
+	Attributes Attributes `gorm:"type:jsonb;not null;default:'{}'" json:"attributes"`
 }
 
 // TableName returns the table name for the Location model
@@ -22,6 +31,15 @@ func (Location) TableName() string {
 	return "locations"
 }
 
+// BeforeCreate hook sets the UUID and marks the new location as touched by the creating
+// transaction, so that adding its names and relations does not bump its version
+func (l *Location) BeforeCreate(tx *gorm.DB) error {
+	if err := l.BaseModel.BeforeCreate(tx); err != nil {
+		return err
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Raw("SELECT txid_current()").Scan(&l.VersionTx).Error
+}
+
 // LocationFilter are the fiters to be used to fetch location
 type LocationFilter struct {
 	Ids      *uuid.UUIDs
@@ -35,14 +53,37 @@ type LocationWithNames struct {
 	GeoLevel string    `json:"geo_level"`
 	Name     string    `json:"name"`    // Primary name
 	Aliases  []string  `json:"aliases"` // Other names (non-primary)
+	// SupersededBy lists the successors of a location that was split
+	SupersededBy []uuid.UUID `json:"superseded_by,omitempty"`
+	Version      int64       `json:"version"`
+	Attributes   Attributes  `json:"attributes"`
 }
 
 // InsertLocation inserts a new location with its primary name
 // It accepts the geo level name (not ID) for usability
 func (s *Store) InsertLocation(ctx context.Context, geoLevelName string, name string) (*Location, error) {
+	return s.InsertLocationWithID(ctx, uuid.Nil, geoLevelName, name)
+}
+
+// InsertLocationWithID inserts a new location with the given id and its primary name.
+// A new id is generated if id is uuid.Nil.
+func (s *Store) InsertLocationWithID(ctx context.Context, id uuid.UUID, geoLevelName string, name string) (*Location, error) {
+	return s.InsertLocationWithAttributes(ctx, id, geoLevelName, name, nil)
+}
+
+// InsertLocationWithAttributes inserts a new location with the given id, its primary name and
+// its attributes, which must follow the attribute schema of the geo level.
+// A new id is generated if id is uuid.Nil.
+func (s *Store) InsertLocationWithAttributes(ctx context.Context, id uuid.UUID, geoLevelName string, name string, attributes Attributes) (*Location, error) {
 	if name == "" {
 		return nil, ErrNameRequired
 	}
+	if attributes == nil {
+		attributes = Attributes{}
+	}
+	if err := validateAttributes(attributes); err != nil {
+		return nil, err
+	}
 
 	var location *Location
 	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -55,9 +96,15 @@ func (s *Store) InsertLocation(ctx context.Context, geoLevelName string, name st
 			return err
 		}
 
+		if err := checkAttributeSchema(id, geoLevel, attributes); err != nil {
+			return err
+		}
+
 		// Create the location
 		location = &Location{
+			BaseModel:  BaseModel{Id: id},
 			GeoLevelID: geoLevel.Id,
+			Attributes: attributes,
 		}
 		if err := tx.Create(location).Error; err != nil {
 			return fmt.Errorf("failed to create location: %w", err)
@@ -88,7 +135,8 @@ func (s *Store) InsertLocation(ctx context.Context, geoLevelName string, name st
 	return location, nil
 }
 
-// GetLocation returns a location by its id with names
+// GetLocation returns a location by its id with names.
+// The id of a location merged into another resolves to the surviving location.
 func (s *Store) GetLocation(ctx context.Context, id uuid.UUID) (*LocationWithNames, error) {
 	var location Location
 	err := s.DB.WithContext(ctx).
@@ -96,6 +144,9 @@ func (s *Store) GetLocation(ctx context.Context, id uuid.UUID) (*LocationWithNam
 		First(&location, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if survivorID, redirectErr := s.GetRedirect(ctx, id); redirectErr == nil {
+				return s.GetLocation(ctx, survivorID)
+			}
 			return nil, ErrLocationNotFound
 		}
 		return nil, fmt.Errorf("failed to get location: %w", err)
@@ -103,14 +154,16 @@ func (s *Store) GetLocation(ctx context.Context, id uuid.UUID) (*LocationWithNam
 
 	// Get all names for the location
 	var names []NameMap
-	if err := s.DB.Where("location_id = ?", id).Find(&names).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Where("location_id = ?", id).Find(&names).Error; err != nil {
 		return nil, fmt.Errorf("failed to get location names: %w", err)
 	}
 
 	result := &LocationWithNames{
-		Id:       location.Id,
-		GeoLevel: location.GeoLevel.Name,
-		Aliases:  make([]string, 0),
+		Id:         location.Id,
+		GeoLevel:   location.GeoLevel.Name,
+		Aliases:    make([]string, 0),
+		Version:    location.Version,
+		Attributes: location.Attributes,
 	}
 
 	// Process names, separating primary and aliases
@@ -122,6 +175,73 @@ func (s *Store) GetLocation(ctx context.Context, id uuid.UUID) (*LocationWithNam
 		}
 	}
 
+	if location.SupersededAt != nil {
+		successors, err := s.GetSuccessors(ctx, location.Id)
+		if err != nil {
+			return nil, err
+		}
+		result.SupersededBy = successors
+	}
+
+	return result, nil
+}
+
+// GetLocationsWithNames returns the live locations with their names, geo levels and, for
+// split locations, successors, by id, as GetLocation does, in at most three queries. Unknown
+// ids are left out.
+func (s *Store) GetLocationsWithNames(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]LocationWithNames, error) {
+	if len(ids) == 0 {
+		return map[uuid.UUID]LocationWithNames{}, nil
+	}
+	db := s.DB.WithContext(ctx)
+	var locations []Location
+	if err := db.Preload("GeoLevel").Where("id IN ?", ids).Find(&locations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get locations: %w", err)
+	}
+	var names []NameMap
+	if err := db.Where("location_id IN ?", ids).Order("name").Find(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to get location names: %w", err)
+	}
+	var supersededIDs []uuid.UUID
+	for _, location := range locations {
+		if location.SupersededAt != nil {
+			supersededIDs = append(supersededIDs, location.Id)
+		}
+	}
+	successors := make(map[uuid.UUID][]uuid.UUID)
+	if len(supersededIDs) > 0 {
+		var successions []LocationSuccession
+		if err := db.Where("predecessor_id IN ?", supersededIDs).Order("created_at ASC").Find(&successions).Error; err != nil {
+			return nil, fmt.Errorf("failed to get successors: %w", err)
+		}
+		for _, succession := range successions {
+			successors[succession.PredecessorID] = append(successors[succession.PredecessorID], succession.SuccessorID)
+		}
+	}
+
+	result := make(map[uuid.UUID]LocationWithNames, len(locations))
+	for _, location := range locations {
+		result[location.Id] = LocationWithNames{
+			Id:           location.Id,
+			GeoLevel:     location.GeoLevel.Name,
+			Aliases:      make([]string, 0),
+			SupersededBy: successors[location.Id],
+			Version:      location.Version,
+			Attributes:   location.Attributes,
+		}
+	}
+	for _, name := range names {
+		location, ok := result[name.LocationID]
+		if !ok {
+			continue
+		}
+		if name.IsPrimary {
+			location.Name = name.Name
+		} else {
+			location.Aliases = append(location.Aliases, name.Name)
+		}
+		result[name.LocationID] = location
+	}
 	return result, nil
 }
 
@@ -176,7 +296,10 @@ func (s *Store) SearchLocationsByPattern(ctx context.Context, pattern string, ge
 	return results, nil
 }
 
-// UpdateLocation updates a location
+// UpdateLocation updates a location.
+// A geo level change that breaks existing relations of the location fails with a
+// *HierarchyViolationError listing those relations, and one to a geo level whose attribute
+// schema the location breaks fails with an *AttributeSchemaError.
 func (s *Store) UpdateLocation(ctx context.Context, id uuid.UUID, geoLevelName *string, name *string) (*Location, error) {
 	var updatedLocation *Location
 
@@ -202,7 +325,21 @@ func (s *Store) UpdateLocation(ctx context.Context, id uuid.UUID, geoLevelName *
 				}
 				return err
 			}
-			location.GeoLevelID = geoLevel.Id
+			if geoLevel.Id != location.GeoLevelID {
+				conflicts, err := levelChangeConflicts(tx, location.Id, geoLevel)
+				if err != nil {
+					return err
+				}
+				if len(conflicts) > 0 {
+					return &HierarchyViolationError{Conflicts: conflicts}
+				}
+				if err := checkAttributeSchema(location.Id, geoLevel, location.Attributes); err != nil {
+					return err
+				}
+				if err := tx.Model(&location).Update("geo_level_id", geoLevel.Id).Error; err != nil {
+					return err
+				}
+			}
 		}
 
 		// Update primary name if provided
@@ -238,8 +375,11 @@ func (s *Store) UpdateLocation(ctx context.Context, id uuid.UUID, geoLevelName *
 			}
 		}
 
-		// Save the location (only if changed)
-		if err := tx.Save(&location).Error; err != nil {
+		// Bump the version, checking the expected one even if nothing changed
+		if err := touchLocations(tx, location.Id); err != nil {
+			return err
+		}
+		if err := tx.First(&location, id).Error; err != nil {
 			return err
 		}
 		updatedLocation = &location
@@ -277,7 +417,9 @@ func (s *Store) GetLocationsByGeoLevelName(ctx context.Context, geoLevelName str
 	return locations, nil
 }
 
-// DeleteLocation deletes a location and cascades to its names and relations
+// DeleteLocation deletes a location and cascades to its names and relations.
+// The location, names and relations are soft-deleted with the same deletion time
+// so that RestoreLocation can bring back exactly what this operation removed.
 func (s *Store) DeleteLocation(ctx context.Context, id uuid.UUID) error {
 	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Check if location exists
@@ -289,19 +431,34 @@ func (s *Store) DeleteLocation(ctx context.Context, id uuid.UUID) error {
 			return err
 		}
 
+		// Bump the versions of the location and of the locations losing a relation
+		var relations []Relation
+		if err := tx.Where("parent_id = ? OR child_id = ?", id, id).Find(&relations).Error; err != nil {
+			return err
+		}
+		touched := []uuid.UUID{id}
+		for _, rel := range relations {
+			touched = append(touched, rel.ParentID, rel.ChildID)
+		}
+		if err := touchLocations(tx, touched...); err != nil {
+			return err
+		}
+
+		deletedAt := tx.NowFunc()
+
 		// Delete all names (will be handled by CASCADE constraints)
-		if err := tx.Where("location_id = ?", id).Delete(&NameMap{}).Error; err != nil {
+		if err := tx.Model(&NameMap{}).Where("location_id = ?", id).Update("deleted_at", deletedAt).Error; err != nil {
 			return fmt.Errorf("failed to delete names: %w", err)
 		}
 
 		// Delete all relations where this location is parent or child
 		// (will be handled by CASCADE constraints)
-		if err := tx.Where("parent_id = ? OR child_id = ?", id, id).Delete(&Relation{}).Error; err != nil {
+		if err := tx.Model(&Relation{}).Where("parent_id = ? OR child_id = ?", id, id).Update("deleted_at", deletedAt).Error; err != nil {
 			return fmt.Errorf("failed to delete relations: %w", err)
 		}
 
 		// Delete the location itself
-		if err := tx.Delete(&location).Error; err != nil {
+		if err := tx.Model(&location).Update("deleted_at", deletedAt).Error; err != nil {
 			return fmt.Errorf("failed to delete location: %w", err)
 		}
 
@@ -322,14 +479,16 @@ func (s *Store) ListLocations(ctx context.Context) ([]*LocationWithNames, error)
 	results := make([]*LocationWithNames, 0, len(locations))
 	for _, loc := range locations {
 		var names []NameMap
-		if err := s.DB.Where("location_id = ?", loc.Id).Find(&names).Error; err != nil {
+		if err := s.DB.WithContext(ctx).Where("location_id = ?", loc.Id).Find(&names).Error; err != nil {
 			return nil, fmt.Errorf("failed to get names for location %s: %w", loc.Id, err)
 		}
 
 		result := &LocationWithNames{
-			Id:       loc.Id,
-			GeoLevel: loc.GeoLevel.Name,
-			Aliases:  make([]string, 0),
+			Id:         loc.Id,
+			GeoLevel:   loc.GeoLevel.Name,
+			Aliases:    make([]string, 0),
+			Version:    loc.Version,
+			Attributes: loc.Attributes,
 		}
 
 		for _, name := range names {
@@ -621,3 +621,19 @@ func TestLocation_GetLocationsByGeoLevelName(t *testing.T) {
 		})
 	}
 }
+
+func TestLocation_GetLocationsWithNames(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	successors, err := store.SplitLocation(ctx, locs["City2"].Id, []string{"North", "South"}, nil)
+	require.NoError(t, err)
+
+	named, err := store.GetLocationsWithNames(ctx, []uuid.UUID{locs["City1"].Id, locs["City2"].Id})
+	require.NoError(t, err)
+	for _, id := range []uuid.UUID{locs["City1"].Id, locs["City2"].Id} {
+		want, err := store.GetLocation(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, *want, named[id])
+	}
+	assert.Equal(t, []uuid.UUID{successors[0].Id, successors[1].Id}, named[locs["City2"].Id].SupersededBy)
+}
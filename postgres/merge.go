@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LocationRedirect points the id of a location merged into another to the surviving location
+type LocationRedirect struct {
+	BaseModel
+	Tenant string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant"`
+	FromID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"from_id"`
+	ToID   uuid.UUID `gorm:"type:uuid;not null;index" json:"to_id"`
+}
+
+// TableName returns the table name for the LocationRedirect model
+func (LocationRedirect) TableName() string {
+	return "location_redirects"
+}
+
+// MergeLocations merges the duplicate locations into the survivor in one transaction:
+//   - names of the duplicates become aliases of the survivor (names the survivor already has are dropped)
+//   - parent and child relations of the duplicates are re-pointed to the survivor
+//   - the duplicates are deleted and redirected to the survivor
+//
+// Relations that cannot be re-pointed because they break a hierarchy rule (e.g. the survivor
+// already has a different parent of the same level) are dropped and returned as conflicts.
+func (s *Store) MergeLocations(ctx context.Context, survivorID uuid.UUID, duplicateIDs []uuid.UUID) ([]RelationConflict, error) {
+	if len(duplicateIDs) == 0 {
+		return nil, nil
+	}
+
+	var conflicts []RelationConflict
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var survivor Location
+		if err := tx.First(&survivor, survivorID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+
+		merged := make(map[uuid.UUID]bool, len(duplicateIDs))
+		unique := make([]uuid.UUID, 0, len(duplicateIDs))
+		for _, id := range duplicateIDs {
+			if id == survivorID {
+				return ErrMergeIntoSelf
+			}
+			if merged[id] {
+				continue
+			}
+			var duplicate Location
+			if err := tx.First(&duplicate, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: %s", ErrLocationNotFound, id)
+				}
+				return err
+			}
+			if duplicate.GeoLevelID != survivor.GeoLevelID {
+				return fmt.Errorf("%w: %s", ErrMergeGeoLevelMismatch, id)
+			}
+			merged[id] = true
+			unique = append(unique, id)
+		}
+		duplicateIDs = unique
+		resolve := func(id uuid.UUID) uuid.UUID {
+			if merged[id] {
+				return survivorID
+			}
+			return id
+		}
+
+		// Move names, demoting the duplicates' primary names to aliases
+		var survivorNames []NameMap
+		if err := tx.Where("location_id = ?", survivorID).Find(&survivorNames).Error; err != nil {
+			return err
+		}
+		known := make(map[string]bool, len(survivorNames))
+		for _, name := range survivorNames {
+			known[name.Name] = true
+		}
+
+		var duplicateNames []NameMap
+		if err := tx.Where("location_id IN ?", duplicateIDs).
+			Order("is_primary DESC, created_at ASC").
+			Find(&duplicateNames).Error; err != nil {
+			return err
+		}
+		for _, name := range duplicateNames {
+			if known[name.Name] {
+				if err := tx.Delete(&name).Error; err != nil {
+					return fmt.Errorf("failed to delete duplicate name: %w", err)
+				}
+				continue
+			}
+			known[name.Name] = true
+			if err := tx.Model(&name).Updates(map[string]any{
+				"location_id": survivorID,
+				"is_primary":  false,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to move name: %w", err)
+			}
+		}
+
+		// Re-point relations to the survivor
+		var relations []Relation
+		if err := tx.Where("parent_id IN ? OR child_id IN ?", duplicateIDs, duplicateIDs).
+			Find(&relations).Error; err != nil {
+			return err
+		}
+		for _, rel := range relations {
+			if err := tx.Delete(&rel).Error; err != nil {
+				return fmt.Errorf("failed to delete relation: %w", err)
+			}
+		}
+		for _, rel := range relations {
+			parentID, childID := resolve(rel.ParentID), resolve(rel.ChildID)
+			if parentID == childID {
+				continue
+			}
+
+			var count int64
+			if err := tx.Model(&Relation{}).
+				Where("parent_id = ? AND child_id = ?", parentID, childID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			// A savepoint per relation, as a rejection by the trigger aborts the transaction
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return tx.Create(&Relation{ParentID: parentID, ChildID: childID}).Error
+			})
+			if err != nil {
+				if isRelationConflict(err) {
+					conflicts = append(conflicts, RelationConflict{Relation: rel, Err: err})
+					continue
+				}
+				return fmt.Errorf("failed to re-point relation: %w", err)
+			}
+		}
+
+		// Redirect the duplicates, including locations previously merged into them
+		if err := tx.Model(&LocationRedirect{}).
+			Where("to_id IN ?", duplicateIDs).
+			Update("to_id", survivorID).Error; err != nil {
+			return err
+		}
+		for _, id := range duplicateIDs {
+			if err := tx.Create(&LocationRedirect{FromID: id, ToID: survivorID}).Error; err != nil {
+				return fmt.Errorf("failed to create redirect: %w", err)
+			}
+		}
+
+		if err := tx.Where("id IN ?", duplicateIDs).Delete(&Location{}).Error; err != nil {
+			return fmt.Errorf("failed to delete duplicates: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// GetRedirect returns the id of the location the given location was merged into
+func (s *Store) GetRedirect(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	var redirect LocationRedirect
+	err := s.DB.WithContext(ctx).Where("from_id = ?", id).First(&redirect).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, ErrLocationNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to get redirect: %w", err)
+	}
+
+	return redirect.ToID, nil
+}
+
+// isRelationConflict reports whether the error is a hierarchy rule rejecting a relation
+// rather than a database failure
+func isRelationConflict(err error) bool {
+	return errors.Is(err, ErrLocationNotFound) ||
+		errors.Is(err, ErrInvalidHierarchy) ||
+		errors.Is(err, ErrLevelRuleViolation) ||
+		errors.Is(err, ErrDuplicateRelation) ||
+		errors.Is(err, ErrCyclicRelation) ||
+		errors.Is(err, ErrSupersededRelation)
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge_MergeLocations(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	// State1 and State2 are the same place imported twice
+	survivor, duplicate := locs["State1"], locs["State2"]
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, survivor.Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["Country1"].Id, duplicate.Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, duplicate.Id, locs["District1"].Id)
+	require.NoError(t, err)
+	require.NoError(t, store.InsertNameMap(ctx, duplicate.Id, "State1", false))
+	require.NoError(t, store.InsertNameMap(ctx, duplicate.Id, "Alias2", false))
+
+	conflicts, err := store.MergeLocations(ctx, survivor.Id, []uuid.UUID{duplicate.Id})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	t.Run("names become aliases of the survivor", func(t *testing.T) {
+		loc, err := store.GetLocation(ctx, survivor.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "State1", loc.Name)
+		assert.ElementsMatch(t, []string{"State2", "Alias2"}, loc.Aliases)
+	})
+
+	t.Run("relations are re-pointed without duplicates", func(t *testing.T) {
+		parents, err := store.GetParents(ctx, survivor.Id)
+		require.NoError(t, err)
+		assert.Len(t, parents, 1)
+
+		children, err := store.GetChildren(ctx, survivor.Id)
+		require.NoError(t, err)
+		require.Len(t, children, 1)
+		assert.Equal(t, locs["District1"].Id, children[0].ChildID)
+	})
+
+	t.Run("duplicate redirects to the survivor", func(t *testing.T) {
+		loc, err := store.GetLocation(ctx, duplicate.Id)
+		require.NoError(t, err)
+		assert.Equal(t, survivor.Id, loc.Id)
+
+		_, err = store.RestoreLocation(ctx, duplicate.Id)
+		assert.ErrorIs(t, err, ErrLocationMerged)
+	})
+
+	t.Run("redirects follow further merges", func(t *testing.T) {
+		other, err := store.InsertLocation(ctx, "STATE", "State3")
+		require.NoError(t, err)
+		_, err = store.MergeLocations(ctx, other.Id, []uuid.UUID{survivor.Id})
+		require.NoError(t, err)
+
+		loc, err := store.GetLocation(ctx, duplicate.Id)
+		require.NoError(t, err)
+		assert.Equal(t, other.Id, loc.Id)
+	})
+}
+
+func TestMerge_MergeLocationsConflicts(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	survivor, duplicate := locs["State1"], locs["State2"]
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, survivor.Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["Country2"].Id, duplicate.Id)
+	require.NoError(t, err)
+
+	conflicts, err := store.MergeLocations(ctx, survivor.Id, []uuid.UUID{duplicate.Id})
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, locs["Country2"].Id, conflicts[0].Relation.ParentID)
+	assert.ErrorIs(t, conflicts[0].Err, ErrDuplicateRelation)
+
+	parents, err := store.GetParents(ctx, survivor.Id)
+	require.NoError(t, err)
+	require.Len(t, parents, 1)
+	assert.Equal(t, locs["Country1"].Id, parents[0].ParentID)
+}
+
+func TestMerge_MergeLocationsRepeatedDuplicate(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	survivor, duplicate := locs["State1"], locs["State2"]
+	_, err := store.InsertRelation(ctx, duplicate.Id, locs["District1"].Id)
+	require.NoError(t, err)
+
+	conflicts, err := store.MergeLocations(ctx, survivor.Id, []uuid.UUID{duplicate.Id, duplicate.Id})
+	require.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	redirect, err := store.GetRedirect(ctx, duplicate.Id)
+	require.NoError(t, err)
+	assert.Equal(t, survivor.Id, redirect)
+	children, err := store.GetChildren(ctx, survivor.Id)
+	require.NoError(t, err)
+	assert.Len(t, children, 1)
+}
+
+func TestMerge_MergeLocationsValidation(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		survivor   uuid.UUID
+		duplicates []uuid.UUID
+		errType    error
+	}{
+		{
+			name:       "merge into itself",
+			survivor:   locs["State1"].Id,
+			duplicates: []uuid.UUID{locs["State1"].Id},
+			errType:    ErrMergeIntoSelf,
+		},
+		{
+			name:       "different geo levels",
+			survivor:   locs["State1"].Id,
+			duplicates: []uuid.UUID{locs["City1"].Id},
+			errType:    ErrMergeGeoLevelMismatch,
+		},
+		{
+			name:       "non-existent survivor",
+			survivor:   uuid.New(),
+			duplicates: []uuid.UUID{locs["State1"].Id},
+			errType:    ErrLocationNotFound,
+		},
+		{
+			name:       "non-existent duplicate",
+			survivor:   locs["State1"].Id,
+			duplicates: []uuid.UUID{uuid.New()},
+			errType:    ErrLocationNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.MergeLocations(ctx, tt.survivor, tt.duplicates)
+			assert.ErrorIs(t, err, tt.errType)
+		})
+	}
+}
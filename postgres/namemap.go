@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -12,10 +13,15 @@ import (
 // NameMap represents names including alternate ones by which the location is known
 type NameMap struct {
 	BaseModel
-	LocationID uuid.UUID `gorm:"type:uuid;not null;index" json:"location_id"`
+	Tenant     string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant"`
+	LocationID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_name_maps_primary,where:is_primary AND deleted_at IS NULL" json:"location_id"`
 	Location   *Location `gorm:"foreignKey:LocationID;references:Id;constraint:OnDelete:CASCADE" json:"location"`
 	Name       string    `gorm:"type:varchar(255);not null" json:"name"`
 	IsPrimary  bool      `gorm:"not null;default:false" json:"is_primary"`
+	// Language is the lowercase language tag of a localized name (e.g. "ml"), empty for other names
+	Language string `gorm:"type:varchar(35);not null;default:''" json:"language,omitempty"`
+	// NormalizedName is the name as compared by autocomplete and place resolution (see NormalizeName)
+	NormalizedName string `gorm:"type:varchar(255);not null;default:''" json:"-"`
 }
 
 // TableName returns the table name for the NameMap model
@@ -23,6 +29,12 @@ func (NameMap) TableName() string {
 	return "name_maps"
 }
 
+// BeforeSave hook keeps the normalized name in step with the name
+func (nm *NameMap) BeforeSave(tx *gorm.DB) error {
+	nm.NormalizedName = NormalizeName(nm.Name)
+	return nil
+}
+
 // BeforeCreate hook ensures only one primary name per location
 func (nm *NameMap) BeforeCreate(tx *gorm.DB) error {
 	// Call BaseModel's BeforeCreate to set UUID
@@ -43,8 +55,36 @@ func (nm *NameMap) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// AfterCreate hook bumps the version of the location
+func (nm *NameMap) AfterCreate(tx *gorm.DB) error {
+	return touchLocations(tx, nm.LocationID)
+}
+
+// AfterUpdate hook bumps the version of the location
+func (nm *NameMap) AfterUpdate(tx *gorm.DB) error {
+	return touchLocations(tx, nm.LocationID)
+}
+
+// AfterDelete hook bumps the version of the location
+func (nm *NameMap) AfterDelete(tx *gorm.DB) error {
+	return touchLocations(tx, nm.LocationID)
+}
+
 // InsertNameMap inserts a new name map (alias or primary name) for a location
 func (s *Store) InsertNameMap(ctx context.Context, locationID uuid.UUID, name string, isPrimary bool) error {
+	return s.insertNameMap(ctx, &NameMap{LocationID: locationID, Name: name, IsPrimary: isPrimary})
+}
+
+// InsertLocalizedName inserts an alias of a location in the given language
+func (s *Store) InsertLocalizedName(ctx context.Context, locationID uuid.UUID, language string, name string) error {
+	if language == "" {
+		return ErrLanguageRequired
+	}
+	return s.insertNameMap(ctx, &NameMap{LocationID: locationID, Name: name, Language: strings.ToLower(language)})
+}
+
+func (s *Store) insertNameMap(ctx context.Context, nameMap *NameMap) error {
+	locationID, name, isPrimary := nameMap.LocationID, nameMap.Name, nameMap.IsPrimary
 	if name == "" {
 		return ErrNameRequired
 	}
@@ -90,11 +130,6 @@ func (s *Store) InsertNameMap(ctx context.Context, locationID uuid.UUID, name st
 		}
 
 		// Create the new name
-		nameMap := &NameMap{
-			LocationID: locationID,
-			Name:       name,
-			IsPrimary:  isPrimary,
-		}
 		return tx.Create(nameMap).Error
 	})
 }
@@ -131,7 +166,7 @@ func (s *Store) GetNameMapByLocationID(ctx context.Context, locationID uuid.UUID
 	if len(names) == 0 {
 		// Check if location exists
 		var count int64
-		if err := s.DB.Model(&Location{}).Where("id = ?", locationID).Count(&count).Error; err != nil {
+		if err := s.DB.WithContext(ctx).Model(&Location{}).Where("id = ?", locationID).Count(&count).Error; err != nil {
 			return nil, err
 		}
 		if count == 0 {
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Neighborhood is a location with its parent at a geo level, the other children of that
+// parent at the geo level of the location, and optionally its own children
+type Neighborhood struct {
+	Location LocationWithNames
+	Parent   LocationWithNames
+	Siblings []LocationWithNames
+	Children []LocationWithNames // nil unless requested
+}
+
+// GetNeighborhood returns the neighborhood of a location through its parent of the geo level,
+// each list ordered by primary name. Returns ErrRelationNotFound if the location has no parent
+// of the geo level.
+func (s *Store) GetNeighborhood(ctx context.Context, id uuid.UUID, parentGeoLevelName string, withChildren bool) (*Neighborhood, error) {
+	parentLevel, err := s.GetGeoLevelByName(ctx, parentGeoLevelName)
+	if err != nil {
+		return nil, err
+	}
+	db := s.DB.WithContext(ctx)
+	var location Location
+	if err := db.First(&location, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+
+	var parentIDs []uuid.UUID
+	err = db.Model(&Relation{}).
+		Joins("JOIN locations parent ON parent.id = relations.parent_id AND parent.deleted_at IS NULL").
+		Where("relations.child_id = ? AND parent.geo_level_id = ?", id, parentLevel.Id).
+		Pluck("relations.parent_id", &parentIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent: %w", err)
+	}
+	if len(parentIDs) == 0 {
+		return nil, ErrRelationNotFound
+	}
+	parentID := parentIDs[0]
+
+	var siblingIDs []uuid.UUID
+	err = db.Model(&Relation{}).
+		Joins("JOIN locations child ON child.id = relations.child_id AND child.deleted_at IS NULL").
+		Where("relations.parent_id = ? AND relations.child_id <> ? AND child.geo_level_id = ?", parentID, id, location.GeoLevelID).
+		Pluck("relations.child_id", &siblingIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get siblings: %w", err)
+	}
+	var childIDs []uuid.UUID
+	if withChildren {
+		err = db.Model(&Relation{}).
+			Joins("JOIN locations child ON child.id = relations.child_id AND child.deleted_at IS NULL").
+			Where("relations.parent_id = ?", id).
+			Pluck("relations.child_id", &childIDs).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to get children: %w", err)
+		}
+	}
+
+	named, err := s.GetLocationsWithNames(ctx, slices.Concat([]uuid.UUID{id, parentID}, siblingIDs, childIDs))
+	if err != nil {
+		return nil, err
+	}
+	pick := func(ids []uuid.UUID) []LocationWithNames {
+		out := make([]LocationWithNames, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, named[id])
+		}
+		slices.SortFunc(out, func(a, b LocationWithNames) int {
+			return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Id.String(), b.Id.String()))
+		})
+		return out
+	}
+
+	neighborhood := &Neighborhood{
+		Location: named[id],
+		Parent:   named[parentID],
+		Siblings: pick(siblingIDs),
+	}
+	if withChildren {
+		neighborhood.Children = pick(childIDs)
+	}
+	return neighborhood, nil
+}
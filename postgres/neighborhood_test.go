@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNeighborhood(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.InsertNameMap(ctx, locs["District2"].Id, "Second District", false))
+
+	ids := func(locations []LocationWithNames) []uuid.UUID {
+		out := []uuid.UUID{}
+		for _, location := range locations {
+			out = append(out, location.Id)
+		}
+		return out
+	}
+
+	t.Run("siblings", func(t *testing.T) {
+		neighborhood, err := store.GetNeighborhood(ctx, locs["District1"].Id, "state", false)
+		require.NoError(t, err)
+		assert.Equal(t, locs["District1"].Id, neighborhood.Location.Id)
+		assert.Equal(t, locs["State1"].Id, neighborhood.Parent.Id)
+		assert.Equal(t, "State1", neighborhood.Parent.Name)
+		require.Len(t, neighborhood.Siblings, 1)
+		assert.Equal(t, "District2", neighborhood.Siblings[0].Name)
+		assert.Equal(t, []string{"Second District"}, neighborhood.Siblings[0].Aliases)
+		assert.Equal(t, "DISTRICT", neighborhood.Siblings[0].GeoLevel)
+		assert.Nil(t, neighborhood.Children)
+	})
+
+	t.Run("with children", func(t *testing.T) {
+		neighborhood, err := store.GetNeighborhood(ctx, locs["State1"].Id, "country", true)
+		require.NoError(t, err)
+		assert.Equal(t, locs["Country1"].Id, neighborhood.Parent.Id)
+		assert.Equal(t, []uuid.UUID{locs["State2"].Id}, ids(neighborhood.Siblings))
+		assert.Equal(t, []uuid.UUID{locs["District1"].Id, locs["District2"].Id}, ids(neighborhood.Children))
+	})
+
+	t.Run("only child", func(t *testing.T) {
+		neighborhood, err := store.GetNeighborhood(ctx, locs["City1"].Id, "district", true)
+		require.NoError(t, err)
+		assert.Empty(t, neighborhood.Siblings)
+		assert.Empty(t, neighborhood.Children)
+	})
+
+	t.Run("siblings of the same geo level", func(t *testing.T) {
+		city, err := store.InsertLocation(ctx, "city", "City3")
+		require.NoError(t, err)
+		_, err = store.InsertRelation(ctx, locs["State1"].Id, city.Id)
+		require.NoError(t, err)
+		neighborhood, err := store.GetNeighborhood(ctx, locs["District1"].Id, "state", false)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{locs["District2"].Id}, ids(neighborhood.Siblings))
+	})
+
+	tests := []struct {
+		name        string
+		id          uuid.UUID
+		parentLevel string
+		wantErr     error
+	}{
+		{name: "no parent at the geo level", id: locs["City1"].Id, parentLevel: "country", wantErr: ErrRelationNotFound},
+		{name: "root", id: locs["Country2"].Id, parentLevel: "country", wantErr: ErrRelationNotFound},
+		{name: "unknown geo level", id: locs["City1"].Id, parentLevel: "village", wantErr: ErrGeoLevelNotFound},
+		{name: "unknown location", id: uuid.New(), parentLevel: "state", wantErr: ErrLocationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.GetNeighborhood(ctx, tt.id, tt.parentLevel, true)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
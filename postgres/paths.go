@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PathLocation is a location on the path from the top of the hierarchy down to a location
+type PathLocation struct {
+	LocationID uuid.UUID
+	GeoLevel   string
+	Name       string // primary name, or the name in the requested language if there is one
+}
+
+// pathDepths returns, for each of the locations, the location itself and its ancestors, each
+// with the lengths of the shortest and the longest chain of relations up to it
+func pathDepths(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID][]pathDepth, error) {
+	var rows []struct {
+		Origin   uuid.UUID
+		ID       uuid.UUID
+		Nearest  int
+		Farthest int
+	}
+	err := tx.Session(&gorm.Session{NewDB: true}).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id AS origin, id, 0 AS depth FROM locations
+			WHERE id IN ? AND deleted_at IS NULL
+			UNION
+			SELECT a.origin, r.parent_id, a.depth + 1 FROM relations r
+			JOIN ancestors a ON r.child_id = a.id
+			WHERE r.deleted_at IS NULL AND a.depth < ?
+		)
+		SELECT origin, id, MIN(depth) AS nearest, MAX(depth) AS farthest FROM ancestors
+		GROUP BY origin, id`, ids, maxHierarchyDepth).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	depths := make(map[uuid.UUID][]pathDepth, len(ids))
+	for _, row := range rows {
+		depths[row.Origin] = append(depths[row.Origin], pathDepth{ID: row.ID, Nearest: row.Nearest, Farthest: row.Farthest})
+	}
+	return depths, nil
+}
+
+type pathDepth struct {
+	ID       uuid.UUID
+	Nearest  int
+	Farthest int
+}
+
+// GetPath returns the ancestors of a location from the top of the hierarchy down to the
+// location itself, one per geo level: where ancestors of the same geo level are reached
+// through several parents, the nearest is kept. With a language, localized names in that
+// language replace the primary names of the locations that have one.
+func (s *Store) GetPath(ctx context.Context, id uuid.UUID, language string) ([]PathLocation, error) {
+	paths, err := s.GetPaths(ctx, []uuid.UUID{id}, language)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := paths[id]
+	if !ok {
+		return nil, ErrLocationNotFound
+	}
+	return path, nil
+}
+
+// GetPaths returns the paths of the locations as GetPath does, keyed by location id.
+// Locations that do not exist are left out.
+func (s *Store) GetPaths(ctx context.Context, ids []uuid.UUID, language string) (map[uuid.UUID][]PathLocation, error) {
+	paths := make(map[uuid.UUID][]PathLocation, len(ids))
+	if len(ids) == 0 {
+		return paths, nil
+	}
+	db := s.DB.WithContext(ctx)
+
+	depthsByOrigin, err := pathDepths(db, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ancestors: %w", err)
+	}
+	seen := make(map[uuid.UUID]bool)
+	var ancestorIDs []uuid.UUID
+	for _, depths := range depthsByOrigin {
+		for _, depth := range depths {
+			if !seen[depth.ID] {
+				seen[depth.ID] = true
+				ancestorIDs = append(ancestorIDs, depth.ID)
+			}
+		}
+	}
+	if len(ancestorIDs) == 0 {
+		return paths, nil
+	}
+
+	var locations []Location
+	if err := db.Preload("GeoLevel").Where("id IN ?", ancestorIDs).Find(&locations).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ancestors: %w", err)
+	}
+	byID := make(map[uuid.UUID]Location, len(locations))
+	for _, location := range locations {
+		byID[location.Id] = location
+	}
+
+	var names []NameMap
+	query := db.Where("location_id IN ? AND deleted_at IS NULL", ancestorIDs)
+	if language != "" {
+		query = query.Where("(is_primary OR language = ?)", strings.ToLower(language))
+	} else {
+		query = query.Where("is_primary")
+	}
+	if err := query.Order("created_at").Find(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to get names: %w", err)
+	}
+	primaryNames := make(map[uuid.UUID]string, len(ancestorIDs))
+	localizedNames := make(map[uuid.UUID]string)
+	for _, name := range names {
+		if name.IsPrimary {
+			primaryNames[name.LocationID] = name.Name
+		} else if _, ok := localizedNames[name.LocationID]; !ok {
+			localizedNames[name.LocationID] = name.Name
+		}
+	}
+
+	for origin, depths := range depthsByOrigin {
+		if _, ok := byID[origin]; !ok {
+			continue // not visible to the tenant
+		}
+		// ancestors of a location are always farther than it, so the longest chain orders the path
+		slices.SortFunc(depths, func(a, b pathDepth) int {
+			if a.Farthest != b.Farthest {
+				return b.Farthest - a.Farthest
+			}
+			return strings.Compare(a.ID.String(), b.ID.String())
+		})
+		nearest := make(map[uuid.UUID]pathDepth)
+		for _, depth := range depths {
+			location, ok := byID[depth.ID]
+			if !ok {
+				continue
+			}
+			if kept, ok := nearest[location.GeoLevelID]; !ok || depth.Nearest < kept.Nearest {
+				nearest[location.GeoLevelID] = depth
+			}
+		}
+
+		path := make([]PathLocation, 0, len(nearest))
+		for _, depth := range depths {
+			location, ok := byID[depth.ID]
+			if !ok || nearest[location.GeoLevelID].ID != depth.ID {
+				continue
+			}
+			name, ok := localizedNames[location.Id]
+			if !ok {
+				name = primaryNames[location.Id]
+			}
+			path = append(path, PathLocation{
+				LocationID: location.Id,
+				GeoLevel:   location.GeoLevel.Name,
+				Name:       name,
+			})
+		}
+		paths[origin] = path
+	}
+	return paths, nil
+}
+
+// descendantDepths returns the descendants of the locations with the length of the shortest
+// chain of relations down to each
+func descendantDepths(tx *gorm.DB, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ID    uuid.UUID
+		Depth int
+	}
+	err := tx.Session(&gorm.Session{NewDB: true}).Raw(`
+		WITH RECURSIVE descendants AS (
+			SELECT r.child_id AS id, 1 AS depth FROM relations r
+			WHERE r.parent_id IN ? AND r.deleted_at IS NULL
+			UNION
+			SELECT r.child_id, d.depth + 1 FROM relations r
+			JOIN descendants d ON r.parent_id = d.id
+			WHERE r.deleted_at IS NULL AND d.depth < ?
+		)
+		SELECT id, MIN(depth) AS depth FROM descendants GROUP BY id`, ids, maxHierarchyDepth).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	depths := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		depths[row.ID] = row.Depth
+	}
+	return depths, nil
+}
+
+// ResolvePath returns the locations reached by walking the names down the hierarchy: the
+// locations known by the first name, then their descendants known by the next name, and so on.
+// Names match primary names and aliases regardless of case. At each step the nearest matching
+// descendants are kept, so a child wins over a grandchild of the same name. The result is empty
+// if the path leads nowhere.
+func (s *Store) ResolvePath(ctx context.Context, names []string) ([]uuid.UUID, error) {
+	if len(names) == 0 {
+		return nil, ErrPathRequired
+	}
+	db := s.DB.WithContext(ctx)
+
+	var candidates []uuid.UUID
+	for i, name := range names {
+		if name == "" {
+			return nil, ErrNameRequired
+		}
+		query := db.Model(&NameMap{}).
+			Joins("JOIN locations ON locations.id = name_maps.location_id AND locations.deleted_at IS NULL").
+			Where("LOWER(name_maps.name) = LOWER(?) AND name_maps.deleted_at IS NULL", name)
+		var depths map[uuid.UUID]int
+		if i > 0 {
+			var err error
+			depths, err = descendantDepths(db, candidates)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get descendants: %w", err)
+			}
+			if len(depths) == 0 {
+				return []uuid.UUID{}, nil
+			}
+			ids := make([]uuid.UUID, 0, len(depths))
+			for id := range depths {
+				ids = append(ids, id)
+			}
+			query = query.Where("name_maps.location_id IN ?", ids)
+		}
+
+		var matches []uuid.UUID
+		if err := query.Distinct().Pluck("name_maps.location_id", &matches).Error; err != nil {
+			return nil, fmt.Errorf("failed to match names: %w", err)
+		}
+		if len(matches) == 0 {
+			return []uuid.UUID{}, nil
+		}
+		if depths != nil {
+			nearest := depths[matches[0]]
+			for _, id := range matches[1:] {
+				nearest = min(nearest, depths[id])
+			}
+			matches = slices.DeleteFunc(matches, func(id uuid.UUID) bool { return depths[id] > nearest })
+		}
+		candidates = matches
+	}
+	slices.SortFunc(candidates, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+	return candidates, nil
+}
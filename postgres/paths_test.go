@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaths_GetPath(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.InsertLocalizedName(ctx, locs["State1"].Id, "ML", "Samsthanam1"))
+
+	names := func(path []PathLocation) []string {
+		var out []string
+		for _, location := range path {
+			out = append(out, location.GeoLevel+":"+location.Name)
+		}
+		return out
+	}
+
+	path, err := store.GetPath(ctx, locs["City1"].Id, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"COUNTRY:Country1", "STATE:State1", "DISTRICT:District1", "CITY:City1"}, names(path))
+	assert.Equal(t, locs["City1"].Id, path[3].LocationID)
+
+	path, err = store.GetPath(ctx, locs["City1"].Id, "ml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"COUNTRY:Country1", "STATE:Samsthanam1", "DISTRICT:District1", "CITY:City1"}, names(path))
+
+	path, err = store.GetPath(ctx, locs["Country2"].Id, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"COUNTRY:Country2"}, names(path))
+
+	t.Run("parents of several levels", func(t *testing.T) {
+		_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["City1"].Id)
+		require.NoError(t, err)
+
+		path, err := store.GetPath(ctx, locs["City1"].Id, "")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"COUNTRY:Country1", "STATE:State1", "DISTRICT:District1", "CITY:City1"}, names(path))
+	})
+
+	t.Run("unknown location", func(t *testing.T) {
+		_, err := store.GetPath(ctx, uuid.New(), "")
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	})
+
+	t.Run("language required", func(t *testing.T) {
+		err := store.InsertLocalizedName(ctx, locs["State1"].Id, "", "Samsthanam1")
+		assert.ErrorIs(t, err, ErrLanguageRequired)
+	})
+}
+
+func TestPaths_GetPaths(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+
+	ids := []uuid.UUID{locs["City1"].Id, locs["State1"].Id, locs["Country2"].Id, uuid.New()}
+	paths, err := store.GetPaths(ctx, ids, "")
+	require.NoError(t, err)
+	assert.Len(t, paths, 3, "unknown locations are left out")
+
+	for _, id := range ids[:3] {
+		path, err := store.GetPath(ctx, id, "")
+		require.NoError(t, err)
+		assert.Equal(t, path, paths[id])
+	}
+
+	paths, err = store.GetPaths(ctx, nil, "")
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestPaths_ResolvePath(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.InsertNameMap(ctx, locs["City1"].Id, "Twin", false))
+	require.NoError(t, store.InsertNameMap(ctx, locs["City2"].Id, "Twin", false))
+	require.NoError(t, store.InsertNameMap(ctx, locs["City2"].Id, "District1", false))
+
+	tests := []struct {
+		name    string
+		path    []string
+		want    []string
+		wantErr error
+	}{
+		{name: "full path", path: []string{"Country1", "State1", "District1"}, want: []string{"District1"}},
+		{name: "skipped levels and case", path: []string{"country1", "DISTRICT2"}, want: []string{"District2"}},
+		{name: "alias", path: []string{"State1", "District2", "Twin"}, want: []string{"City2"}},
+		{name: "ambiguous", path: []string{"State1", "Twin"}, want: []string{"City1", "City2"}},
+		{name: "nearest wins", path: []string{"State1", "District1"}, want: []string{"District1"}},
+		{name: "wrong branch", path: []string{"Country2", "State1"}, want: []string{}},
+		{name: "unknown name", path: []string{"Atlantis"}, want: []string{}},
+		{name: "empty path", path: nil, wantErr: ErrPathRequired},
+		{name: "empty name", path: []string{"Country1", ""}, wantErr: ErrNameRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := store.ResolvePath(ctx, tt.path)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			want := make([]uuid.UUID, 0, len(tt.want))
+			for _, name := range tt.want {
+				want = append(want, locs[name].Id)
+			}
+			slices.SortFunc(want, func(a, b uuid.UUID) int { return strings.Compare(a.String(), b.String()) })
+			assert.Equal(t, want, ids)
+		})
+	}
+}
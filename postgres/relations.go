@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -12,6 +13,7 @@ import (
 // Relation represents the relationship between two locations
 type Relation struct {
 	BaseModel
+	Tenant   string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant"`
 	ParentID uuid.UUID `gorm:"type:uuid;not null;index" json:"parent_id"`
 	ChildID  uuid.UUID `gorm:"type:uuid;not null;index" json:"child_id"`
 	Parent   *Location `gorm:"foreignKey:ParentID;references:Id;constraint:OnDelete:CASCADE" json:"parent"`
@@ -30,23 +32,50 @@ func (r *Relation) BeforeCreate(tx *gorm.DB) error {
 		return err
 	}
 
+	return validateRelation(tx, r.ParentID, r.ChildID)
+}
+
+// AfterCreate hook bumps the versions of the parent and the child
+func (r *Relation) AfterCreate(tx *gorm.DB) error {
+	return touchLocations(tx, r.ParentID, r.ChildID)
+}
+
+// AfterUpdate hook bumps the versions of the parent and the child
+func (r *Relation) AfterUpdate(tx *gorm.DB) error {
+	return touchLocations(tx, r.ParentID, r.ChildID)
+}
+
+// AfterDelete hook bumps the versions of the parent and the child
+func (r *Relation) AfterDelete(tx *gorm.DB) error {
+	return touchLocations(tx, r.ParentID, r.ChildID)
+}
+
+// validateRelation checks that the parent and child exist in the tenant and are not
+// superseded, that their ranks and the level rules allow the relation, and that the child
+// has no other parent of the parent's geo level
+func validateRelation(tx *gorm.DB, parentID uuid.UUID, childID uuid.UUID) error {
 	var parent, child Location
 
 	// Get parent and child with their geo levels
-	if err := tx.Preload("GeoLevel").First(&parent, r.ParentID).Error; err != nil {
+	if err := tx.Preload("GeoLevel").First(&parent, parentID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrLocationNotFound
 		}
 		return err
 	}
 
-	if err := tx.Preload("GeoLevel").First(&child, r.ChildID).Error; err != nil {
+	if err := tx.Preload("GeoLevel").First(&child, childID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return ErrLocationNotFound
 		}
 		return err
 	}
 
+	// A split location keeps no relations; its successors took its place
+	if parent.SupersededAt != nil || child.SupersededAt != nil {
+		return ErrSupersededRelation
+	}
+
 	// Check ranks if both parent and child geo levels have ranks
 	if parent.GeoLevel.Rank != nil && child.GeoLevel.Rank != nil {
 		if *parent.GeoLevel.Rank >= *child.GeoLevel.Rank {
@@ -54,13 +83,18 @@ func (r *Relation) BeforeCreate(tx *gorm.DB) error {
 		}
 	}
 
+	// Check the parent geo level is allowed by the level rules of the child geo level
+	if err := checkLevelRule(tx, parent.GeoLevelID, child.GeoLevelID); err != nil {
+		return err
+	}
+
 	// Check for unique child-parent level combination
 	// A child can have only one parent of a particular level
 	var count int64
 	if err := tx.Model(&Relation{}).
 		Joins("JOIN locations parent ON parent.id = relations.parent_id").
 		Where("relations.child_id = ? AND parent.geo_level_id = ? AND relations.deleted_at IS NULL",
-			r.ChildID, parent.GeoLevelID).
+			childID, parent.GeoLevelID).
 		Count(&count).Error; err != nil {
 		return err
 	}
@@ -69,9 +103,38 @@ func (r *Relation) BeforeCreate(tx *gorm.DB) error {
 		return ErrDuplicateRelation
 	}
 
+	// The parent must not be a descendant of the child
+	descendants, err := descendantIDs(tx, childID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(descendants, parentID) {
+		return ErrCyclicRelation
+	}
+
 	return nil
 }
 
+// descendantIDs returns the ids of all locations below the given location
+func descendantIDs(tx *gorm.DB, id uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := tx.Session(&gorm.Session{NewDB: true}).Raw(`
+		WITH RECURSIVE descendants AS (
+			SELECT child_id FROM relations WHERE parent_id = ? AND deleted_at IS NULL
+			UNION
+			SELECT r.child_id FROM relations r
+			JOIN descendants d ON r.parent_id = d.child_id
+			WHERE r.deleted_at IS NULL
+		)
+		SELECT child_id FROM descendants`, id).
+		Scan(&ids).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get descendants: %w", err)
+	}
+
+	return ids, nil
+}
+
 // InsertRelation inserts a new relation
 func (s *Store) InsertRelation(ctx context.Context, parentLocationID uuid.UUID, childLocationID uuid.UUID) (*Relation, error) {
 	if parentLocationID == childLocationID {
@@ -138,13 +201,17 @@ func (s *Store) GetParents(ctx context.Context, childLocationID uuid.UUID) ([]Re
 
 // DeleteRelation deletes a relation by its id
 func (s *Store) DeleteRelation(ctx context.Context, id uuid.UUID) error {
-	result := s.DB.WithContext(ctx).Delete(&Relation{}, id)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete relation: %w", result.Error)
+	// Load the relation first so that the delete hook knows its locations
+	var relation Relation
+	if err := s.DB.WithContext(ctx).First(&relation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRelationNotFound
+		}
+		return fmt.Errorf("failed to delete relation: %w", err)
 	}
 
-	if result.RowsAffected == 0 {
-		return ErrRelationNotFound
+	if err := s.DB.WithContext(ctx).Delete(&relation).Error; err != nil {
+		return fmt.Errorf("failed to delete relation: %w", err)
 	}
 
 	return nil
@@ -153,9 +220,19 @@ func (s *Store) DeleteRelation(ctx context.Context, id uuid.UUID) error {
 // DeleteAllRelations deletes all relations of a location by its location id
 func (s *Store) DeleteAllRelations(ctx context.Context, locationID uuid.UUID) error {
 	// Delete all relations where the location is either parent or child
-	err := s.DB.WithContext(ctx).
-		Where("parent_id = ? OR child_id = ?", locationID, locationID).
-		Delete(&Relation{}).Error
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var relations []Relation
+		if err := tx.Where("parent_id = ? OR child_id = ?", locationID, locationID).
+			Find(&relations).Error; err != nil {
+			return err
+		}
+		for _, rel := range relations {
+			if err := tx.Delete(&rel).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 
 	if err != nil {
 		return fmt.Errorf("failed to delete location relations: %w", err)
@@ -163,3 +240,78 @@ func (s *Store) DeleteAllRelations(ctx context.Context, locationID uuid.UUID) er
 
 	return nil
 }
+
+// MoveLocation makes newParentID the parent of the location at the new parent's geo level,
+// replacing the current parent of that level in one transaction. The new relation is validated
+// like any other (rank, level rules, cycles). If checkDescendants is set, the move fails with
+// ErrInconsistentAncestors when descendants of the location have a direct parent of that level
+// other than the new parent; the offending relations are returned.
+func (s *Store) MoveLocation(ctx context.Context, id uuid.UUID, newParentID uuid.UUID, checkDescendants bool) ([]RelationConflict, error) {
+	if id == newParentID {
+		return nil, ErrSelfRelationNotAllowed
+	}
+
+	var conflicts []RelationConflict
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var newParent Location
+		if err := tx.First(&newParent, newParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+
+		// Replace the current parent at the new parent's geo level, if any
+		var current []Relation
+		if err := tx.Joins("JOIN locations parent ON parent.id = relations.parent_id").
+			Where("relations.child_id = ? AND parent.geo_level_id = ?", id, newParent.GeoLevelID).
+			Find(&current).Error; err != nil {
+			return err
+		}
+		for _, rel := range current {
+			if rel.ParentID == newParentID {
+				return nil
+			}
+			if err := tx.Delete(&rel).Error; err != nil {
+				return fmt.Errorf("failed to remove current parent: %w", err)
+			}
+		}
+
+		if err := tx.Create(&Relation{ParentID: newParentID, ChildID: id}).Error; err != nil {
+			return fmt.Errorf("failed to create relation: %w", err)
+		}
+
+		if !checkDescendants {
+			return nil
+		}
+
+		descendants, err := descendantIDs(tx, id)
+		if err != nil {
+			return err
+		}
+		if len(descendants) == 0 {
+			return nil
+		}
+		var inconsistent []Relation
+		if err := tx.Joins("JOIN locations parent ON parent.id = relations.parent_id").
+			Where("relations.child_id IN ? AND parent.geo_level_id = ? AND relations.parent_id <> ?",
+				descendants, newParent.GeoLevelID, newParentID).
+			Find(&inconsistent).Error; err != nil {
+			return err
+		}
+		for _, rel := range inconsistent {
+			conflicts = append(conflicts, RelationConflict{Relation: rel, Err: ErrInconsistentAncestors})
+		}
+		if len(conflicts) > 0 {
+			return ErrInconsistentAncestors
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return conflicts, err
+	}
+
+	return nil, nil
+}
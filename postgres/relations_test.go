@@ -496,3 +496,89 @@ func TestRelation_DeleteAllRelations(t *testing.T) {
 		})
 	}
 }
+
+func TestRelation_CyclicRelation(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	// Unranked levels are not protected by rank ordering
+	_, err := store.InsertGeoLevel(ctx, "ZONE", nil)
+	require.NoError(t, err)
+	_, err = store.InsertGeoLevel(ctx, "AREA", nil)
+	require.NoError(t, err)
+	zone1, err := store.InsertLocation(ctx, "ZONE", "Zone1")
+	require.NoError(t, err)
+	zone2, err := store.InsertLocation(ctx, "ZONE", "Zone2")
+	require.NoError(t, err)
+	area, err := store.InsertLocation(ctx, "AREA", "Area1")
+	require.NoError(t, err)
+
+	_, err = store.InsertRelation(ctx, zone1.Id, zone2.Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, zone2.Id, area.Id)
+	require.NoError(t, err)
+
+	_, err = store.InsertRelation(ctx, zone2.Id, zone1.Id)
+	assert.ErrorIs(t, err, ErrCyclicRelation)
+	_, err = store.InsertRelation(ctx, area.Id, zone1.Id)
+	assert.ErrorIs(t, err, ErrCyclicRelation)
+}
+
+func TestRelation_MoveLocation(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	// State1 > District1 > City1, and City1 also has State1 as direct STATE parent
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["State1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["District1"].Id, locs["City1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State1"].Id, locs["City1"].Id)
+	require.NoError(t, err)
+
+	t.Run("inconsistent descendants block a checked move", func(t *testing.T) {
+		conflicts, err := store.MoveLocation(ctx, locs["District1"].Id, locs["State2"].Id, true)
+		assert.ErrorIs(t, err, ErrInconsistentAncestors)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, locs["State1"].Id, conflicts[0].Relation.ParentID)
+		assert.Equal(t, locs["City1"].Id, conflicts[0].Relation.ChildID)
+
+		parents, err := store.GetParents(ctx, locs["District1"].Id)
+		require.NoError(t, err)
+		require.Len(t, parents, 1)
+		assert.Equal(t, locs["State1"].Id, parents[0].ParentID, "failed move must roll back")
+	})
+
+	t.Run("unchecked move replaces the parent", func(t *testing.T) {
+		conflicts, err := store.MoveLocation(ctx, locs["District1"].Id, locs["State2"].Id, false)
+		require.NoError(t, err)
+		assert.Empty(t, conflicts)
+
+		parents, err := store.GetParents(ctx, locs["District1"].Id)
+		require.NoError(t, err)
+		require.Len(t, parents, 1)
+		assert.Equal(t, locs["State2"].Id, parents[0].ParentID)
+	})
+
+	t.Run("move to the current parent is a no-op", func(t *testing.T) {
+		_, err := store.MoveLocation(ctx, locs["District1"].Id, locs["State2"].Id, true)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid rank rolls back", func(t *testing.T) {
+		_, err := store.MoveLocation(ctx, locs["District1"].Id, locs["City2"].Id, false)
+		assert.ErrorIs(t, err, ErrInvalidHierarchy)
+
+		parents, err := store.GetParents(ctx, locs["District1"].Id)
+		require.NoError(t, err)
+		require.Len(t, parents, 1)
+		assert.Equal(t, locs["State2"].Id, parents[0].ParentID)
+	})
+
+	t.Run("non-existent parent", func(t *testing.T) {
+		_, err := store.MoveLocation(ctx, locs["District1"].Id, uuid.New(), false)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	})
+}
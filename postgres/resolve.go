@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NormalizeName lowercases a name and collapses its spaces and ASCII punctuation into
+// single spaces, so "St. Louis" and "st louis" compare equal
+func NormalizeName(name string) string {
+	return strings.Join(NameWords(strings.ToLower(name)), " ")
+}
+
+// NameWords splits a name into its words at spaces and ASCII punctuation
+func NameWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return unicode.IsSpace(r) || (r <= unicode.MaxASCII && !unicode.IsLetter(r) && !unicode.IsDigit(r))
+	})
+}
+
+// MatchNames returns the live names, primary or alias, of live locations whose normalized
+// form (see NormalizeName) is one of the given normalized names, with their locations and
+// geo levels
+func (s *Store) MatchNames(ctx context.Context, normalized []string) ([]NameMap, error) {
+	if len(normalized) == 0 {
+		return []NameMap{}, nil
+	}
+
+	var names []NameMap
+	err := s.DB.WithContext(ctx).
+		Preload("Location.GeoLevel").
+		Joins("JOIN locations ON locations.id = name_maps.location_id AND locations.deleted_at IS NULL").
+		Where("name_maps.normalized_name IN ? AND name_maps.deleted_at IS NULL", normalized).
+		Order("name_maps.is_primary DESC, name_maps.name ASC").
+		Find(&names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to match names: %w", err)
+	}
+	return names, nil
+}
+
+// AncestorDepths returns, for each of the locations, the location itself at depth 0 and its
+// ancestors with the length of the shortest chain of relations up to each
+func (s *Store) AncestorDepths(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]map[uuid.UUID]int, error) {
+	result := make(map[uuid.UUID]map[uuid.UUID]int, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		Origin uuid.UUID
+		ID     uuid.UUID
+		Depth  int
+	}
+	err := s.DB.WithContext(ctx).Session(&gorm.Session{NewDB: true}).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id AS origin, id, 0 AS depth FROM locations
+			WHERE id IN ? AND deleted_at IS NULL
+			UNION
+			SELECT a.origin, r.parent_id, a.depth + 1 FROM relations r
+			JOIN ancestors a ON r.child_id = a.id
+			WHERE r.deleted_at IS NULL AND a.depth < ?
+		)
+		SELECT origin, id, MIN(depth) AS depth FROM ancestors GROUP BY origin, id`, ids, maxHierarchyDepth).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ancestors: %w", err)
+	}
+
+	for _, row := range rows {
+		if result[row.Origin] == nil {
+			result[row.Origin] = make(map[uuid.UUID]int)
+		}
+		result[row.Origin][row.ID] = row.Depth
+	}
+	return result, nil
+}
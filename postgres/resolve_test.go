@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Kochi", "kochi"},
+		{"  St. Louis ", "st louis"},
+		{"Winston-Salem", "winston salem"},
+		{"O'Hare", "o hare"},
+		{"തൃശ്ശൂർ", "തൃശ്ശൂർ"},
+		{"...", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeName(tt.name))
+		})
+	}
+}
+
+func TestResolve_MatchNames(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	require.NoError(t, store.InsertNameMap(ctx, locs["State1"].Id, "St. One", false))
+
+	names, err := store.MatchNames(ctx, []string{"st one", "city1", "atlantis"})
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	assert.Equal(t, "City1", names[0].Name)
+	assert.True(t, names[0].IsPrimary)
+	assert.Equal(t, "CITY", names[0].Location.GeoLevel.Name)
+	assert.Equal(t, "St. One", names[1].Name)
+	assert.Equal(t, locs["State1"].Id, names[1].LocationID)
+
+	names, err = store.MatchNames(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestResolve_AncestorDepths(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+
+	depths, err := store.AncestorDepths(ctx, []uuid.UUID{locs["City1"].Id, locs["State2"].Id, uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]map[uuid.UUID]int{
+		locs["City1"].Id: {
+			locs["City1"].Id:     0,
+			locs["District1"].Id: 1,
+			locs["State1"].Id:    2,
+			locs["Country1"].Id:  3,
+		},
+		locs["State2"].Id: {
+			locs["State2"].Id:   0,
+			locs["Country1"].Id: 1,
+		},
+	}, depths)
+}
@@ -0,0 +1,353 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AttributeType is the JSON type of an attribute value
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeBoolean AttributeType = "boolean"
+	AttributeArray   AttributeType = "array"
+	AttributeObject  AttributeType = "object"
+)
+
+// AttributeRule constrains one attribute of the locations of a geo level
+type AttributeRule struct {
+	Type     AttributeType `json:"type,omitempty"` // any type if empty
+	Required bool          `json:"required,omitempty"`
+	Enum     []any         `json:"enum,omitempty"` // allowed values, any if empty
+	// Pattern is a regular expression that string values must match as a whole, e.g. `\d{3}`
+	Pattern string `json:"pattern,omitempty"`
+	// Inherited makes locations without the attribute take it from their nearest ancestor
+	// that has it, see GetEffectiveAttributes. The other rules apply to own values only.
+	Inherited bool `json:"inherited,omitempty"`
+}
+
+// AttributeSchema maps attribute keys to the rules the locations of a geo level must follow.
+// Attributes without a rule are unconstrained.
+type AttributeSchema map[string]AttributeRule
+
+// GormDataType returns the column type of attribute schemas
+func (AttributeSchema) GormDataType() string {
+	return "jsonb"
+}
+
+// Value encodes the schema as a JSON object, {} for nil
+func (schema AttributeSchema) Value() (driver.Value, error) {
+	if schema == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(map[string]AttributeRule(schema))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan decodes a schema from a JSON object
+func (schema *AttributeSchema) Scan(value any) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*schema = AttributeSchema{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into attribute schema", value)
+	}
+	decoded := AttributeSchema{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return err
+	}
+	*schema = decoded
+	return nil
+}
+
+// AttributeViolation is an attribute of a location breaking the schema of its geo level
+type AttributeViolation struct {
+	LocationID uuid.UUID
+	GeoLevel   string
+	Key        string
+	Reason     string
+}
+
+// AttributeSchemaError is returned when attributes break the schema of their geo level. It
+// lists every violation; errors.Is matches ErrAttributeViolation.
+type AttributeSchemaError struct {
+	Violations []AttributeViolation
+}
+
+// Error lists the violating attributes with their reasons
+func (e *AttributeSchemaError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrAttributeViolation.Error())
+	for i, violation := range e.Violations {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s %s", violation.Key, violation.Reason)
+	}
+	return b.String()
+}
+
+// Unwrap returns ErrAttributeViolation
+func (e *AttributeSchemaError) Unwrap() error {
+	return ErrAttributeViolation
+}
+
+// Validate returns ErrInvalidAttributeSchema if a key is not a valid attribute key, an inherited
+// attribute is required, a type is unknown, a pattern does not compile or is set on a non-string
+// attribute, or an allowed value is not of the type of its attribute
+func (schema AttributeSchema) Validate() error {
+	for key, rule := range schema {
+		if err := ValidateAttributeKey(key); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAttributeSchema, err)
+		}
+		if rule.Required && rule.Inherited {
+			return fmt.Errorf("%w: inherited attribute %s cannot be required", ErrInvalidAttributeSchema, key)
+		}
+		switch rule.Type {
+		case "", AttributeString, AttributeNumber, AttributeBoolean, AttributeArray, AttributeObject:
+		default:
+			return fmt.Errorf("%w: unknown type %q of %s", ErrInvalidAttributeSchema, rule.Type, key)
+		}
+		if rule.Pattern != "" {
+			if rule.Type != "" && rule.Type != AttributeString {
+				return fmt.Errorf("%w: pattern of %s needs type string", ErrInvalidAttributeSchema, key)
+			}
+			if _, err := compilePattern(rule.Pattern); err != nil {
+				return fmt.Errorf("%w: pattern of %s: %v", ErrInvalidAttributeSchema, key, err)
+			}
+		}
+		for _, value := range rule.Enum {
+			if rule.Type != "" && attributeType(value) != rule.Type {
+				return fmt.Errorf("%w: allowed value %v of %s is not a %s", ErrInvalidAttributeSchema, value, key, rule.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// Check returns the violations of the schema by the attributes, sorted by key. It returns
+// ErrInvalidAttributeSchema if a pattern of the schema does not compile.
+func (schema AttributeSchema) Check(attributes Attributes) ([]AttributeViolation, error) {
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var violations []AttributeViolation
+	for _, key := range keys {
+		rule := schema[key]
+		value, ok := attributes[key]
+		if !ok || value == nil {
+			if rule.Required {
+				violations = append(violations, AttributeViolation{Key: key, Reason: "is required"})
+			}
+			continue
+		}
+		reason, err := rule.check(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pattern of %s: %v", ErrInvalidAttributeSchema, key, err)
+		}
+		if reason != "" {
+			violations = append(violations, AttributeViolation{Key: key, Reason: reason})
+		}
+	}
+	return violations, nil
+}
+
+// check returns why the value breaks the rule, or "" if it does not
+func (rule AttributeRule) check(value any) (string, error) {
+	if rule.Type != "" && attributeType(value) != rule.Type {
+		return "must be a " + string(rule.Type), nil
+	}
+	if len(rule.Enum) > 0 && !slices.ContainsFunc(rule.Enum, func(allowed any) bool { return sameJSON(allowed, value) }) {
+		return fmt.Sprintf("must be one of %v", rule.Enum), nil
+	}
+	if rule.Pattern != "" {
+		pattern, err := compilePattern(rule.Pattern)
+		if err != nil {
+			return "", err
+		}
+		if s, isString := value.(string); !isString || !pattern.MatchString(s) {
+			return "must match " + rule.Pattern, nil
+		}
+	}
+	return "", nil
+}
+
+// maxCachedPatterns bounds the cache of compiled patterns, whose keys come from callers
+const maxCachedPatterns = 256
+
+// patternCache holds the compiled patterns of attribute rules by pattern. SetAttributeSchema
+// fills it when it validates a schema; when it is full, an arbitrary pattern is evicted.
+var patternCache = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: make(map[string]*regexp.Regexp)}
+
+// compilePattern compiles a pattern of an attribute rule to match whole values
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patternCache.Lock()
+	compiled, ok := patternCache.compiled[pattern]
+	patternCache.Unlock()
+	if ok {
+		return compiled, nil
+	}
+
+	compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Lock()
+	defer patternCache.Unlock()
+	if len(patternCache.compiled) >= maxCachedPatterns {
+		for cached := range patternCache.compiled {
+			delete(patternCache.compiled, cached)
+			break
+		}
+	}
+	patternCache.compiled[pattern] = compiled
+	return compiled, nil
+}
+
+// attributeType returns the JSON type of a value decoded from or encodable to JSON
+func attributeType(value any) AttributeType {
+	switch value.(type) {
+	case string:
+		return AttributeString
+	case bool:
+		return AttributeBoolean
+	case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return AttributeNumber
+	case []any:
+		return AttributeArray
+	case map[string]any, Attributes:
+		return AttributeObject
+	}
+	return ""
+}
+
+// sameJSON reports whether two values have the same JSON encoding, so 3 and 3.0 are equal
+func sameJSON(a, b any) bool {
+	x, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	y, err := json.Marshal(b)
+	return err == nil && bytes.Equal(x, y)
+}
+
+// checkAttributeSchema returns an *AttributeSchemaError if the attributes of the location
+// break the schema of the geo level
+func checkAttributeSchema(locationID uuid.UUID, geoLevel GeoLevel, attributes Attributes) error {
+	violations, err := geoLevel.AttributeSchema.Check(attributes)
+	if err != nil {
+		return err
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	for i := range violations {
+		violations[i].LocationID = locationID
+		violations[i].GeoLevel = geoLevel.Name
+	}
+	return &AttributeSchemaError{Violations: violations}
+}
+
+// SetAttributeSchema replaces the attribute schema of a geo level. Existing locations are
+// not checked, see GetAttributeViolations.
+func (s *Store) SetAttributeSchema(ctx context.Context, geoLevelName string, schema AttributeSchema) (*GeoLevel, error) {
+	if geoLevelName == "" {
+		return nil, ErrGeoLevelNameRequired
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		schema = AttributeSchema{}
+	}
+
+	var geoLevel GeoLevel
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("name = ?", strings.ToUpper(geoLevelName)).First(&geoLevel).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGeoLevelNotFound
+			}
+			return err
+		}
+		geoLevel.AttributeSchema = schema
+		return tx.Model(&geoLevel).Update("attribute_schema", schema).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set attribute schema: %w", err)
+	}
+	return &geoLevel, nil
+}
+
+// GetAttributeViolations returns the attributes of live locations breaking the schema of their
+// geo level, by location from the oldest. If geoLevelID is given, only locations of that geo
+// level are checked.
+func (s *Store) GetAttributeViolations(ctx context.Context, geoLevelID *uuid.UUID) ([]AttributeViolation, error) {
+	db := s.DB.WithContext(ctx)
+	var geoLevels []GeoLevel
+	query := db.Where("attribute_schema <> '{}'::jsonb")
+	if geoLevelID != nil {
+		query = query.Where("id = ?", *geoLevelID)
+	}
+	if err := query.Find(&geoLevels).Error; err != nil {
+		return nil, fmt.Errorf("failed to get attribute schemas: %w", err)
+	}
+	violations := []AttributeViolation{}
+	if len(geoLevels) == 0 {
+		return violations, nil
+	}
+
+	schemas := make(map[uuid.UUID]GeoLevel, len(geoLevels))
+	ids := make([]uuid.UUID, 0, len(geoLevels))
+	for _, geoLevel := range geoLevels {
+		schemas[geoLevel.Id] = geoLevel
+		ids = append(ids, geoLevel.Id)
+	}
+	var locations []Location
+	err := db.Select("id", "geo_level_id", "attributes").
+		Where("geo_level_id IN ?", ids).
+		Order("created_at, id").
+		Find(&locations).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attribute violations: %w", err)
+	}
+	for _, location := range locations {
+		var schemaErr *AttributeSchemaError
+		err := checkAttributeSchema(location.Id, schemas[location.GeoLevelID], location.Attributes)
+		if errors.As(err, &schemaErr) {
+			violations = append(violations, schemaErr.Violations...)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeSchema_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  AttributeSchema
+		wantErr bool
+	}{
+		{name: "nil", schema: nil},
+		{name: "full rule", schema: AttributeSchema{"census_code": {Type: AttributeString, Required: true, Pattern: `\d{3}`, Enum: []any{"001", "002"}}}},
+		{name: "any type", schema: AttributeSchema{"tags": {Required: true}}},
+		{name: "invalid key", schema: AttributeSchema{"Census": {}}, wantErr: true},
+		{name: "required and inherited", schema: AttributeSchema{"timezone": {Required: true, Inherited: true}}, wantErr: true},
+		{name: "unknown type", schema: AttributeSchema{"code": {Type: "integer"}}, wantErr: true},
+		{name: "pattern of a number", schema: AttributeSchema{"code": {Type: AttributeNumber, Pattern: `\d+`}}, wantErr: true},
+		{name: "invalid pattern", schema: AttributeSchema{"code": {Pattern: `(`}}, wantErr: true},
+		{name: "enum of another type", schema: AttributeSchema{"currency": {Type: AttributeString, Enum: []any{"INR", 1.0}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schema.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAttributeSchema)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAttributeSchema_Check(t *testing.T) {
+	schema := AttributeSchema{
+		"census_code": {Type: AttributeString, Required: true, Pattern: `\d{3}`},
+		"currency":    {Type: AttributeString, Enum: []any{"INR", "USD"}},
+		"population":  {Type: AttributeNumber},
+		"level":       {Enum: []any{1, 2}},
+	}
+	tests := []struct {
+		name       string
+		attributes Attributes
+		want       map[string]string
+	}{
+		{name: "valid", attributes: Attributes{"census_code": "032", "currency": "INR", "population": 1e6, "level": 2.0, "other": true}},
+		{name: "missing required", attributes: Attributes{"currency": "INR"}, want: map[string]string{"census_code": "is required"}},
+		{name: "null required", attributes: Attributes{"census_code": nil}, want: map[string]string{"census_code": "is required"}},
+		{name: "whole pattern", attributes: Attributes{"census_code": "0321"}, want: map[string]string{"census_code": `must match \d{3}`}},
+		{name: "type", attributes: Attributes{"census_code": 32.0, "population": "many"}, want: map[string]string{
+			"census_code": "must be a string",
+			"population":  "must be a number",
+		}},
+		{name: "enum", attributes: Attributes{"census_code": "032", "currency": "EUR", "level": 3.0}, want: map[string]string{
+			"currency": "must be one of [INR USD]",
+			"level":    "must be one of [1 2]",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			violations, err := schema.Check(tt.attributes)
+			require.NoError(t, err)
+			for _, violation := range violations {
+				got[violation.Key] = violation.Reason
+			}
+			if tt.want == nil {
+				tt.want = map[string]string{}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAttributeSchema_CheckInvalidPattern(t *testing.T) {
+	// A schema stored without validation must not panic
+	schema := AttributeSchema{"code": {Pattern: `(`}}
+	_, err := schema.Check(Attributes{"code": "x"})
+	assert.ErrorIs(t, err, ErrInvalidAttributeSchema)
+	violations, err := schema.Check(Attributes{})
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestCompilePattern_Bounded(t *testing.T) {
+	for i := range maxCachedPatterns + 10 {
+		compiled, err := compilePattern(fmt.Sprintf(`code-%d`, i))
+		require.NoError(t, err)
+		assert.True(t, compiled.MatchString(fmt.Sprintf("code-%d", i)))
+	}
+	patternCache.Lock()
+	defer patternCache.Unlock()
+	assert.LessOrEqual(t, len(patternCache.compiled), maxCachedPatterns)
+}
+
+func TestAttributeSchema_Enforced(t *testing.T) {
+	store, locs := setupAncestorsTest(t)
+	ctx := context.Background()
+	_, err := store.SetAttributes(ctx, locs["District1"].Id, Attributes{"census_code": "032"})
+	require.NoError(t, err)
+
+	level, err := store.SetAttributeSchema(ctx, "district", AttributeSchema{
+		"census_code": {Type: AttributeString, Required: true, Pattern: `\d{3}`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "DISTRICT", level.Name)
+	stored, err := store.GetGeoLevelByName(ctx, "DISTRICT")
+	require.NoError(t, err)
+	assert.Equal(t, level.AttributeSchema, stored.AttributeSchema)
+
+	t.Run("existing violators are reported", func(t *testing.T) {
+		violations, err := store.GetAttributeViolations(ctx, &level.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []AttributeViolation{
+			{LocationID: locs["District2"].Id, GeoLevel: "DISTRICT", Key: "census_code", Reason: "is required"},
+		}, violations)
+		all, err := store.GetAttributeViolations(ctx, nil)
+		require.NoError(t, err)
+		assert.Equal(t, violations, all)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		_, err := store.InsertLocation(ctx, "district", "District3")
+		assert.ErrorIs(t, err, ErrAttributeViolation)
+		_, err = store.InsertLocationWithAttributes(ctx, uuid.Nil, "district", "District3", Attributes{"census_code": "33"})
+		var schemaErr *AttributeSchemaError
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, `must match \d{3}`, schemaErr.Violations[0].Reason)
+		location, err := store.InsertLocationWithAttributes(ctx, uuid.Nil, "district", "District3", Attributes{"census_code": "033"})
+		require.NoError(t, err)
+		assert.Equal(t, Attributes{"census_code": "033"}, location.Attributes)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		_, err := store.PatchAttributes(ctx, locs["District1"].Id, Attributes{"census_code": nil})
+		assert.ErrorIs(t, err, ErrAttributeViolation)
+		_, err = store.SetAttributes(ctx, locs["District1"].Id, Attributes{"census_code": "034", "area": 100.0})
+		assert.NoError(t, err)
+	})
+
+	t.Run("level change", func(t *testing.T) {
+		city := "city"
+		location, err := store.InsertLocation(ctx, city, "City3")
+		require.NoError(t, err)
+		district := "district"
+		_, err = store.UpdateLocation(ctx, location.Id, &district, nil)
+		assert.ErrorIs(t, err, ErrAttributeViolation)
+		_, err = store.SetAttributes(ctx, location.Id, Attributes{"census_code": "035"})
+		require.NoError(t, err)
+		_, err = store.UpdateLocation(ctx, location.Id, &district, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		_, err := store.SetAttributeSchema(ctx, "district", AttributeSchema{"code": {Type: "integer"}})
+		assert.ErrorIs(t, err, ErrInvalidAttributeSchema)
+		_, err = store.SetAttributeSchema(ctx, "unknown", AttributeSchema{})
+		assert.ErrorIs(t, err, ErrGeoLevelNotFound)
+	})
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Snapshot is the live state of a hierarchy, loaded in bulk
+type Snapshot struct {
+	GeoLevels []GeoLevel
+	Locations []LocationWithNames
+	Relations []Relation // relations between the snapshot's locations
+	// Languages maps the localized aliases of each location to their language
+	Languages map[uuid.UUID]map[string]string
+}
+
+// LoadSnapshot loads all geo levels with the live locations, their names and the relations
+// between them. Locations superseded by a split are left out, as they are no longer part of
+// the hierarchy. If rootID is given, only the root and its descendants are loaded, and
+// ErrLocationSuperseded is returned if the root was split.
+func (s *Store) LoadSnapshot(ctx context.Context, rootID *uuid.UUID) (*Snapshot, error) {
+	snapshot := &Snapshot{Languages: make(map[uuid.UUID]map[string]string)}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Order("rank ASC NULLS LAST, name ASC").Find(&snapshot.GeoLevels).Error; err != nil {
+			return err
+		}
+
+		query := tx.Preload("GeoLevel")
+		if rootID != nil {
+			var root Location
+			if err := tx.First(&root, *rootID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrLocationNotFound
+				}
+				return err
+			}
+			if root.SupersededAt != nil {
+				return ErrLocationSuperseded
+			}
+			ids, err := descendantIDs(tx, root.Id)
+			if err != nil {
+				return err
+			}
+			query = query.Where("id IN ?", append(ids, root.Id))
+		}
+		var locations []Location
+		if err := query.Where("superseded_at IS NULL").Order("created_at ASC").Find(&locations).Error; err != nil {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(locations))
+		for _, loc := range locations {
+			ids = append(ids, loc.Id)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		var names []NameMap
+		if err := tx.Where("location_id IN ?", ids).Order("created_at ASC").Find(&names).Error; err != nil {
+			return err
+		}
+		byLocation := make(map[uuid.UUID][]NameMap, len(ids))
+		for _, name := range names {
+			byLocation[name.LocationID] = append(byLocation[name.LocationID], name)
+		}
+
+		snapshot.Locations = make([]LocationWithNames, 0, len(locations))
+		for _, loc := range locations {
+			result := LocationWithNames{
+				Id:         loc.Id,
+				GeoLevel:   loc.GeoLevel.Name,
+				Aliases:    make([]string, 0),
+				Version:    loc.Version,
+				Attributes: loc.Attributes,
+			}
+			for _, name := range byLocation[loc.Id] {
+				if name.IsPrimary {
+					result.Name = name.Name
+					continue
+				}
+				result.Aliases = append(result.Aliases, name.Name)
+				if name.Language != "" {
+					if snapshot.Languages[loc.Id] == nil {
+						snapshot.Languages[loc.Id] = make(map[string]string)
+					}
+					snapshot.Languages[loc.Id][name.Name] = name.Language
+				}
+			}
+			snapshot.Locations = append(snapshot.Locations, result)
+		}
+
+		return tx.Where("parent_id IN ? AND child_id IN ?", ids, ids).
+			Order("created_at ASC").
+			Find(&snapshot.Relations).Error
+	})
+
+	if err != nil {
+		if errors.Is(err, ErrLocationNotFound) || errors.Is(err, ErrLocationSuperseded) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot_LoadSnapshot(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	// Country1 > State1 > District1 > City1, Country2 > State2
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["State1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["District1"].Id, locs["City1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["Country2"].Id, locs["State2"].Id)
+	require.NoError(t, err)
+	require.NoError(t, store.InsertNameMap(ctx, locs["State1"].Id, "State One", false))
+
+	t.Run("whole hierarchy", func(t *testing.T) {
+		snapshot, err := store.LoadSnapshot(ctx, nil)
+		require.NoError(t, err)
+		assert.Len(t, snapshot.GeoLevels, 4)
+		assert.Len(t, snapshot.Locations, len(locs))
+		assert.Len(t, snapshot.Relations, 4)
+
+		for _, loc := range snapshot.Locations {
+			if loc.Id == locs["State1"].Id {
+				assert.Equal(t, "STATE", loc.GeoLevel)
+				assert.Equal(t, []string{"State One"}, loc.Aliases)
+			}
+		}
+	})
+
+	t.Run("subtree", func(t *testing.T) {
+		rootID := locs["State1"].Id
+		snapshot, err := store.LoadSnapshot(ctx, &rootID)
+		require.NoError(t, err)
+
+		ids := make([]uuid.UUID, 0, len(snapshot.Locations))
+		for _, loc := range snapshot.Locations {
+			ids = append(ids, loc.Id)
+		}
+		assert.ElementsMatch(t, []uuid.UUID{locs["State1"].Id, locs["District1"].Id, locs["City1"].Id}, ids)
+		assert.Len(t, snapshot.Relations, 2, "the relation to the root's parent is not included")
+	})
+
+	t.Run("unknown root", func(t *testing.T) {
+		rootID := uuid.New()
+		_, err := store.LoadSnapshot(ctx, &rootID)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+	})
+
+	t.Run("superseded locations are left out", func(t *testing.T) {
+		successors, err := store.SplitLocation(ctx, locs["District1"].Id, []string{"North", "South"}, map[uuid.UUID]string{
+			locs["City1"].Id: "North",
+		})
+		require.NoError(t, err)
+
+		snapshot, err := store.LoadSnapshot(ctx, nil)
+		require.NoError(t, err)
+		ids := make([]uuid.UUID, 0, len(snapshot.Locations))
+		for _, loc := range snapshot.Locations {
+			ids = append(ids, loc.Id)
+		}
+		assert.NotContains(t, ids, locs["District1"].Id)
+		assert.Contains(t, ids, successors[0].Id)
+
+		rootID := locs["District1"].Id
+		_, err = store.LoadSnapshot(ctx, &rootID)
+		assert.ErrorIs(t, err, ErrLocationSuperseded)
+	})
+}
+
+func TestLocation_InsertLocationWithID(t *testing.T) {
+	store := setupTestDB(t)
+	ctx := context.Background()
+
+	_, err := store.InsertGeoLevel(ctx, "COUNTRY", float64Ptr(1.0))
+	require.NoError(t, err)
+
+	id := uuid.New()
+	loc, err := store.InsertLocationWithID(ctx, id, "COUNTRY", "India")
+	require.NoError(t, err)
+	assert.Equal(t, id, loc.Id)
+
+	got, err := store.GetLocation(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "India", got.Name)
+
+	_, err = store.InsertLocationWithID(ctx, id, "COUNTRY", "Bharat")
+	assert.Error(t, err, "ids are unique")
+}
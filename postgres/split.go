@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LocationSuccession links a location that was split to one of its successor locations
+type LocationSuccession struct {
+	BaseModel
+	Tenant        string    `gorm:"type:varchar(64);not null;default:'default';index" json:"tenant"`
+	PredecessorID uuid.UUID `gorm:"type:uuid;not null;index" json:"predecessor_id"`
+	SuccessorID   uuid.UUID `gorm:"type:uuid;not null;index" json:"successor_id"`
+}
+
+// TableName returns the table name for the LocationSuccession model
+func (LocationSuccession) TableName() string {
+	return "location_successions"
+}
+
+// SplitLocation splits a location into successor locations in one transaction.
+// The successors are created at the source's geo level with the given primary names and the
+// attributes of the source, and attached to the source's parents. Every child of the source
+// must be assigned to the name of one successor and is moved under it. The source is detached
+// from its parents and marked as superseded, with links to its successors.
+func (s *Store) SplitLocation(ctx context.Context, sourceID uuid.UUID, successorNames []string, assignments map[uuid.UUID]string) ([]Location, error) {
+	if len(successorNames) == 0 {
+		return nil, ErrSuccessorRequired
+	}
+	seen := make(map[string]bool, len(successorNames))
+	for _, name := range successorNames {
+		if name == "" {
+			return nil, ErrNameRequired
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate successor %q", ErrInvalidSplit, name)
+		}
+		seen[name] = true
+	}
+	for childID, name := range assignments {
+		if !seen[name] {
+			return nil, fmt.Errorf("%w: child %s assigned to unknown successor %q", ErrInvalidSplit, childID, name)
+		}
+	}
+
+	var successors []Location
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var source Location
+		if err := tx.Preload("GeoLevel").First(&source, sourceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLocationNotFound
+			}
+			return err
+		}
+		if source.SupersededAt != nil {
+			return ErrLocationSuperseded
+		}
+
+		var parents, children []Relation
+		if err := tx.Where("child_id = ?", sourceID).Find(&parents).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("parent_id = ?", sourceID).Find(&children).Error; err != nil {
+			return err
+		}
+
+		// Every child must be assigned, and only children may be assigned
+		isChild := make(map[uuid.UUID]bool, len(children))
+		for _, rel := range children {
+			if _, ok := assignments[rel.ChildID]; !ok {
+				return fmt.Errorf("%w: child %s is not assigned to a successor", ErrInvalidSplit, rel.ChildID)
+			}
+			isChild[rel.ChildID] = true
+		}
+		for childID := range assignments {
+			if !isChild[childID] {
+				return fmt.Errorf("%w: %s is not a child of the split location", ErrInvalidSplit, childID)
+			}
+		}
+
+		// Detach the source from its parents and children first, so that the successors
+		// can take its place under the one-parent-per-level rule
+		for _, rel := range slices.Concat(parents, children) {
+			if err := tx.Delete(&rel).Error; err != nil {
+				return fmt.Errorf("failed to detach split location: %w", err)
+			}
+		}
+
+		store := &Store{DB: tx}
+		successorIDs := make(map[string]uuid.UUID, len(successorNames))
+		for _, name := range successorNames {
+			successor, err := store.InsertLocationWithAttributes(ctx, uuid.Nil, source.GeoLevel.Name, name, source.Attributes)
+			if err != nil {
+				return err
+			}
+			for _, rel := range parents {
+				if err := tx.Create(&Relation{ParentID: rel.ParentID, ChildID: successor.Id}).Error; err != nil {
+					return fmt.Errorf("failed to attach successor to parent: %w", err)
+				}
+			}
+			if err := tx.Create(&LocationSuccession{PredecessorID: sourceID, SuccessorID: successor.Id}).Error; err != nil {
+				return fmt.Errorf("failed to link successor: %w", err)
+			}
+			successorIDs[name] = successor.Id
+			successors = append(successors, *successor)
+		}
+
+		for _, rel := range children {
+			successorID := successorIDs[assignments[rel.ChildID]]
+			if err := tx.Create(&Relation{ParentID: successorID, ChildID: rel.ChildID}).Error; err != nil {
+				return fmt.Errorf("failed to move child to successor: %w", err)
+			}
+		}
+
+		return tx.Model(&source).Update("superseded_at", tx.NowFunc()).Error
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return successors, nil
+}
+
+// GetSuccessors returns the ids of the locations a split location was superseded by
+func (s *Store) GetSuccessors(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	var successions []LocationSuccession
+	err := s.DB.WithContext(ctx).
+		Where("predecessor_id = ?", id).
+		Order("created_at ASC").
+		Find(&successions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get successors: %w", err)
+	}
+
+	successors := make([]uuid.UUID, 0, len(successions))
+	for _, succession := range successions {
+		successors = append(successors, succession.SuccessorID)
+	}
+
+	return successors, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplit_SplitLocation(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	source := locs["District1"]
+	_, err := store.InsertRelation(ctx, locs["State1"].Id, source.Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, source.Id, locs["City1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, source.Id, locs["City2"].Id)
+	require.NoError(t, err)
+
+	successors, err := store.SplitLocation(ctx, source.Id, []string{"North", "South"}, map[uuid.UUID]string{
+		locs["City1"].Id: "North",
+		locs["City2"].Id: "South",
+	})
+	require.NoError(t, err)
+	require.Len(t, successors, 2)
+	north, south := successors[0], successors[1]
+	assert.Equal(t, source.GeoLevelID, north.GeoLevelID)
+
+	t.Run("successors take the source's place under its parents", func(t *testing.T) {
+		children, err := store.GetChildren(ctx, locs["State1"].Id)
+		require.NoError(t, err)
+		var childIDs []uuid.UUID
+		for _, rel := range children {
+			childIDs = append(childIDs, rel.ChildID)
+		}
+		assert.ElementsMatch(t, []uuid.UUID{north.Id, south.Id}, childIDs)
+	})
+
+	t.Run("children are moved to their successor", func(t *testing.T) {
+		parents, err := store.GetParents(ctx, locs["City1"].Id)
+		require.NoError(t, err)
+		require.Len(t, parents, 1)
+		assert.Equal(t, north.Id, parents[0].ParentID)
+
+		parents, err = store.GetParents(ctx, locs["City2"].Id)
+		require.NoError(t, err)
+		require.Len(t, parents, 1)
+		assert.Equal(t, south.Id, parents[0].ParentID)
+	})
+
+	t.Run("source is superseded", func(t *testing.T) {
+		loc, err := store.GetLocation(ctx, source.Id)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{north.Id, south.Id}, loc.SupersededBy)
+
+		_, err = store.SplitLocation(ctx, source.Id, []string{"Again"}, nil)
+		assert.ErrorIs(t, err, ErrLocationSuperseded)
+	})
+
+	t.Run("source cannot be re-attached", func(t *testing.T) {
+		_, err := store.InsertRelation(ctx, locs["State1"].Id, source.Id)
+		assert.ErrorIs(t, err, ErrSupersededRelation)
+		_, err = store.InsertRelation(ctx, source.Id, locs["City1"].Id)
+		assert.ErrorIs(t, err, ErrSupersededRelation)
+	})
+}
+
+func TestSplit_SplitLocationValidation(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	source := locs["District1"]
+	_, err := store.InsertRelation(ctx, source.Id, locs["City1"].Id)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		source      uuid.UUID
+		successors  []string
+		assignments map[uuid.UUID]string
+		errType     error
+	}{
+		{
+			name:       "no successors",
+			source:     source.Id,
+			successors: nil,
+			errType:    ErrSuccessorRequired,
+		},
+		{
+			name:       "empty successor name",
+			source:     source.Id,
+			successors: []string{""},
+			errType:    ErrNameRequired,
+		},
+		{
+			name:       "duplicate successor name",
+			source:     source.Id,
+			successors: []string{"North", "North"},
+			errType:    ErrInvalidSplit,
+		},
+		{
+			name:        "unassigned child",
+			source:      source.Id,
+			successors:  []string{"North", "South"},
+			assignments: map[uuid.UUID]string{},
+			errType:     ErrInvalidSplit,
+		},
+		{
+			name:        "assignment to unknown successor",
+			source:      source.Id,
+			successors:  []string{"North"},
+			assignments: map[uuid.UUID]string{locs["City1"].Id: "East"},
+			errType:     ErrInvalidSplit,
+		},
+		{
+			name:        "assignment of a non-child",
+			source:      source.Id,
+			successors:  []string{"North"},
+			assignments: map[uuid.UUID]string{locs["City1"].Id: "North", locs["City2"].Id: "North"},
+			errType:     ErrInvalidSplit,
+		},
+		{
+			name:       "non-existent source",
+			source:     uuid.New(),
+			successors: []string{"North"},
+			errType:    ErrLocationNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.SplitLocation(ctx, tt.source, tt.successors, tt.assignments)
+			assert.ErrorIs(t, err, tt.errType)
+		})
+	}
+
+	// Failed splits leave the hierarchy untouched
+	children, err := store.GetChildren(ctx, source.Id)
+	require.NoError(t, err)
+	assert.Len(t, children, 1)
+}
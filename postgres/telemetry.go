@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// InstrumentationName is the name of the tracers and meters of the location service and store
+const InstrumentationName = "github.com/xaults/platform/location"
+
+const telemetryPluginName = "location:telemetry"
+
+// Error classes recorded on spans and metrics (see ErrorClass)
+const (
+	ErrorClassNotFound = "not_found"
+	ErrorClassConflict = "conflict"
+	ErrorClassInvalid  = "invalid"
+	ErrorClassCanceled = "canceled"
+	ErrorClassTimeout  = "timeout"
+	ErrorClassInternal = "internal"
+)
+
+// ErrorClass groups an error of the store into a small set of classes suitable as a metric
+// attribute, or returns "" for a nil error
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.Is(err, gorm.ErrRecordNotFound),
+		errors.Is(err, ErrLocationNotFound),
+		errors.Is(err, ErrGeoLevelNotFound),
+		errors.Is(err, ErrRelationNotFound),
+		errors.Is(err, ErrLevelRuleNotFound),
+		errors.Is(err, ErrPrimaryNameNotFound),
+		errors.Is(err, ErrNoCommonAncestor):
+		return ErrorClassNotFound
+	case errors.Is(err, ErrVersionConflict),
+		errors.Is(err, ErrPrimaryNameExists),
+		errors.Is(err, ErrDuplicateRelation),
+		errors.Is(err, ErrNameAlreadyExists),
+		errors.Is(err, ErrGeoLevelAlreadyExists),
+		errors.Is(err, ErrLevelRuleExists),
+		errors.Is(err, ErrGeoLevelInUse),
+		errors.Is(err, ErrLocationMerged),
+		errors.Is(err, ErrLocationSuperseded),
+		errors.Is(err, ErrSupersededRelation),
+		errors.Is(err, ErrLocationNotDeleted):
+		return ErrorClassConflict
+	case errors.Is(err, ErrInvalidHierarchy),
+		errors.Is(err, ErrHierarchyViolation),
+		errors.Is(err, ErrCyclicRelation),
+		errors.Is(err, ErrInconsistentAncestors),
+		errors.Is(err, ErrLevelRuleViolation),
+		errors.Is(err, ErrSelfRelationNotAllowed),
+		errors.Is(err, ErrNameRequired),
+		errors.Is(err, ErrCannotDeletePrimary),
+		errors.Is(err, ErrGeoLevelNameRequired),
+		errors.Is(err, ErrGeoLevelNameNotUpper),
+		errors.Is(err, ErrGeoLevelReassignToSelf),
+		errors.Is(err, ErrMergeIntoSelf),
+		errors.Is(err, ErrMergeGeoLevelMismatch),
+		errors.Is(err, ErrSuccessorRequired),
+		errors.Is(err, ErrInvalidSplit),
+		errors.Is(err, ErrLocationRequired),
+		errors.Is(err, ErrLanguageRequired),
+		errors.Is(err, ErrPathRequired),
+		errors.Is(err, ErrAmbiguousPath),
+		errors.Is(err, ErrInvalidAttributeKey),
+		errors.Is(err, ErrInvalidAttributeValue),
+		errors.Is(err, ErrInvalidAttributeFilter),
+		errors.Is(err, ErrInvalidAttributeSchema),
+		errors.Is(err, ErrAttributeViolation):
+		return ErrorClassInvalid
+	default:
+		return ErrorClassInternal
+	}
+}
+
+// Instrument records a span and the query count and latency for every statement run on the
+// database. Nil providers default to the global ones of the otel package. Spans carry the
+// operation, the table and the SQL; metrics carry the operation, the table and the error class.
+func Instrument(db *gorm.DB, tracerProvider trace.TracerProvider, meterProvider metric.MeterProvider) error {
+	if _, ok := db.Config.Plugins[telemetryPluginName]; ok {
+		return nil
+	}
+	if tracerProvider == nil {
+		tracerProvider = otel.GetTracerProvider()
+	}
+	if meterProvider == nil {
+		meterProvider = otel.GetMeterProvider()
+	}
+
+	meter := meterProvider.Meter(InstrumentationName)
+	queries, err := meter.Int64Counter("location.store.queries",
+		metric.WithDescription("Number of statements run by the location store"),
+		metric.WithUnit("{query}"))
+	if err != nil {
+		return err
+	}
+	duration, err := meter.Float64Histogram("location.store.duration",
+		metric.WithDescription("Duration of the statements run by the location store"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	return db.Use(&telemetryPlugin{
+		tracer:   tracerProvider.Tracer(InstrumentationName),
+		queries:  queries,
+		duration: duration,
+	})
+}
+
+// telemetryPlugin wraps every GORM operation in a span and records its metrics
+type telemetryPlugin struct {
+	tracer   trace.Tracer
+	queries  metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// telemetrySpan is the span and start time of a statement, kept on the statement instance
+type telemetrySpan struct {
+	span  trace.Span
+	start time.Time
+}
+
+const telemetrySpanKey = "location:telemetry_span"
+
+// Name returns the name of the GORM plugin
+func (*telemetryPlugin) Name() string {
+	return telemetryPluginName
+}
+
+// Initialize registers the span callbacks around each GORM operation
+func (p *telemetryPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("gorm:create").Register("location:telemetry_before_create", p.before("create")),
+		callback.Create().After("gorm:create").Register("location:telemetry_after_create", p.after("create")),
+		callback.Query().Before("gorm:query").Register("location:telemetry_before_query", p.before("query")),
+		callback.Query().After("gorm:query").Register("location:telemetry_after_query", p.after("query")),
+		callback.Update().Before("gorm:update").Register("location:telemetry_before_update", p.before("update")),
+		callback.Update().After("gorm:update").Register("location:telemetry_after_update", p.after("update")),
+		callback.Delete().Before("gorm:delete").Register("location:telemetry_before_delete", p.before("delete")),
+		callback.Delete().After("gorm:delete").Register("location:telemetry_after_delete", p.after("delete")),
+		callback.Row().Before("gorm:row").Register("location:telemetry_before_row", p.before("row")),
+		callback.Row().After("gorm:row").Register("location:telemetry_after_row", p.after("row")),
+		callback.Raw().Before("gorm:raw").Register("location:telemetry_before_raw", p.before("raw")),
+		callback.Raw().After("gorm:raw").Register("location:telemetry_after_raw", p.after("raw")),
+	)
+}
+
+func (p *telemetryPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		ctx, span := p.tracer.Start(ctx, "location.store."+operation, trace.WithSpanKind(trace.SpanKindClient))
+		db.Statement.Context = ctx
+		db.InstanceSet(telemetrySpanKey, &telemetrySpan{span: span, start: time.Now()})
+	}
+}
+
+func (p *telemetryPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(telemetrySpanKey)
+		if !ok {
+			return
+		}
+		ts := value.(*telemetrySpan)
+		elapsed := time.Since(ts.start).Seconds()
+
+		attrs := []attribute.KeyValue{
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.collection.name", db.Statement.Table),
+		}
+		err := translateConstraint(db.Error)
+		if class := ErrorClass(err); class != "" {
+			attrs = append(attrs, attribute.String("error.type", class))
+		}
+		p.queries.Add(db.Statement.Context, 1, metric.WithAttributes(attrs...))
+		p.duration.Record(db.Statement.Context, elapsed, metric.WithAttributes(attrs...))
+
+		ts.span.SetAttributes(attrs...)
+		ts.span.SetAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.query.text", db.Statement.SQL.String()),
+			attribute.Int64("db.response.returned_rows", db.Statement.RowsAffected),
+		)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			ts.span.RecordError(err)
+			ts.span.SetStatus(codes.Error, err.Error())
+		}
+		ts.span.End()
+	}
+}
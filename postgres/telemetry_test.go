@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+)
+
+func TestTelemetry_ErrorClass(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"record not found", gorm.ErrRecordNotFound, ErrorClassNotFound},
+		{"location not found", fmt.Errorf("failed to get location: %w", ErrLocationNotFound), ErrorClassNotFound},
+		{"duplicate relation", ErrDuplicateRelation, ErrorClassConflict},
+		{"version conflict", ErrVersionConflict, ErrorClassConflict},
+		{"superseded relation", ErrSupersededRelation, ErrorClassConflict},
+		{"hierarchy violation", &HierarchyViolationError{Conflicts: []RelationConflict{{Err: ErrInvalidHierarchy}}}, ErrorClassInvalid},
+		{"cyclic relation", ErrCyclicRelation, ErrorClassInvalid},
+		{"timeout", context.DeadlineExceeded, ErrorClassTimeout},
+		{"canceled", context.Canceled, ErrorClassCanceled},
+		{"other", errors.New("connection refused"), ErrorClassInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ErrorClass(tt.err))
+		})
+	}
+}
+
+func TestTelemetry_Instrument(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	require.NoError(t, Instrument(store.DB,
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	// A second call keeps the first instrumentation
+	require.NoError(t, Instrument(store.DB, nil, nil))
+
+	_, err := store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State2"].Id, locs["District1"].Id)
+	require.ErrorIs(t, err, ErrDuplicateRelation)
+
+	t.Run("spans carry operation, table and query", func(t *testing.T) {
+		var inserts int
+		for _, span := range spans.Ended() {
+			attrs := attribute.NewSet(span.Attributes()...)
+			table, _ := attrs.Value("db.collection.name")
+			operation, _ := attrs.Value("db.operation.name")
+			if table.AsString() != "relations" || operation.AsString() != "create" {
+				continue
+			}
+			inserts++
+			query, _ := attrs.Value("db.query.text")
+			assert.Contains(t, query.AsString(), `INSERT INTO "relations"`)
+			assert.Equal(t, "location.store.create", span.Name())
+			assert.Equal(t, codes.Unset, span.Status().Code)
+		}
+		// The duplicate is rejected by the hook before the insert
+		assert.Equal(t, 1, inserts)
+	})
+
+	t.Run("metrics count queries by table", func(t *testing.T) {
+		var rm metricdata.ResourceMetrics
+		require.NoError(t, reader.Collect(ctx, &rm))
+		counts := map[string]int64{}
+		for _, scope := range rm.ScopeMetrics {
+			for _, m := range scope.Metrics {
+				sum, ok := m.Data.(metricdata.Sum[int64])
+				if m.Name != "location.store.queries" || !ok {
+					continue
+				}
+				for _, point := range sum.DataPoints {
+					table, _ := point.Attributes.Value("db.collection.name")
+					counts[table.AsString()] += point.Value
+				}
+			}
+		}
+		assert.Positive(t, counts["relations"])
+		assert.Positive(t, counts["locations"])
+	})
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultTenant is the tenant used when the context does not carry one
+const DefaultTenant = "default"
+
+const tenantPluginName = "location:tenant"
+
+type tenantKey struct{}
+
+// WithTenant returns a context scoping all store operations to the given tenant namespace
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by the context, or DefaultTenant if none
+func TenantFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if tenant, ok := ctx.Value(tenantKey{}).(string); ok && tenant != "" {
+			return tenant
+		}
+	}
+	return DefaultTenant
+}
+
+// tenantPlugin scopes every statement on a model with a Tenant field to the tenant
+// of the statement context: queries, updates and deletes are filtered by tenant,
+// and created records are stamped with it.
+type tenantPlugin struct{}
+
+// Name returns the name of the GORM plugin
+func (tenantPlugin) Name() string {
+	return tenantPluginName
+}
+
+// Initialize registers the tenant callbacks on the GORM instance
+func (tenantPlugin) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("location:tenant_create", setTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().Before("gorm:query").Register("location:tenant_query", scopeTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("location:tenant_row", scopeTenant); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("location:tenant_update", scopeTenant); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register("location:tenant_delete", scopeTenant)
+}
+
+func hasTenant(db *gorm.DB) bool {
+	return db.Statement.Schema != nil && db.Statement.Schema.LookUpField("Tenant") != nil
+}
+
+func setTenant(db *gorm.DB) {
+	if db.Error != nil || !hasTenant(db) {
+		return
+	}
+	db.Statement.SetColumn("Tenant", TenantFromContext(db.Statement.Context), true)
+}
+
+func scopeTenant(db *gorm.DB) {
+	if db.Error != nil || !hasTenant(db) {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: "tenant"},
+			Value:  TenantFromContext(db.Statement.Context),
+		},
+	}})
+}
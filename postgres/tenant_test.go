@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenant_TenantFromContext(t *testing.T) {
+	assert.Equal(t, DefaultTenant, TenantFromContext(context.Background()))
+	assert.Equal(t, DefaultTenant, TenantFromContext(WithTenant(context.Background(), "")))
+	assert.Equal(t, "sales", TenantFromContext(WithTenant(context.Background(), "sales")))
+}
+
+func TestTenant_Isolation(t *testing.T) {
+	store := setupTestDB(t)
+	sales := WithTenant(context.Background(), "sales")
+	ops := WithTenant(context.Background(), "ops")
+
+	// The same geo level name can exist once per tenant
+	salesLevel, err := store.InsertGeoLevel(sales, "TERRITORY", float64Ptr(1.0))
+	require.NoError(t, err)
+	assert.Equal(t, "sales", salesLevel.Tenant)
+	opsLevel, err := store.InsertGeoLevel(ops, "TERRITORY", float64Ptr(1.0))
+	require.NoError(t, err)
+	assert.NotEqual(t, salesLevel.Id, opsLevel.Id)
+	_, err = store.InsertGeoLevel(sales, "TERRITORY", nil)
+	assert.ErrorIs(t, err, ErrGeoLevelAlreadyExists)
+
+	_, err = store.InsertGeoLevel(sales, "REGION", float64Ptr(2.0))
+	require.NoError(t, err)
+	_, err = store.InsertGeoLevel(ops, "REGION", float64Ptr(2.0))
+	require.NoError(t, err)
+
+	salesTerritory, err := store.InsertLocation(sales, "TERRITORY", "North")
+	require.NoError(t, err)
+	salesRegion, err := store.InsertLocation(sales, "REGION", "Kerala")
+	require.NoError(t, err)
+	opsRegion, err := store.InsertLocation(ops, "REGION", "Kerala")
+	require.NoError(t, err)
+
+	t.Run("records are stamped with the tenant", func(t *testing.T) {
+		assert.Equal(t, "sales", salesTerritory.Tenant)
+		names, err := store.GetNameMapByLocationID(sales, salesTerritory.Id)
+		require.NoError(t, err)
+		require.Len(t, names, 1)
+		assert.Equal(t, "sales", names[0].Tenant)
+	})
+
+	t.Run("locations are invisible to other tenants", func(t *testing.T) {
+		_, err := store.GetLocation(ops, salesTerritory.Id)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+		_, err = store.GetLocation(context.Background(), salesTerritory.Id)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+
+		names, err := store.SearchNamesByPattern(ops, "Kerala")
+		require.NoError(t, err)
+		require.Len(t, names, 1)
+		assert.Equal(t, opsRegion.Id, names[0].LocationID)
+	})
+
+	t.Run("geo levels are listed per tenant", func(t *testing.T) {
+		levels, err := store.ListGeoLevels(ops)
+		require.NoError(t, err)
+		assert.Len(t, levels, 2)
+		levels, err = store.ListGeoLevels(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, levels)
+	})
+
+	t.Run("relations across tenants are rejected", func(t *testing.T) {
+		_, err := store.InsertRelation(sales, salesTerritory.Id, opsRegion.Id)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+		_, err = store.InsertRelation(ops, salesTerritory.Id, opsRegion.Id)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+
+		rel, err := store.InsertRelation(sales, salesTerritory.Id, salesRegion.Id)
+		require.NoError(t, err)
+		assert.Equal(t, "sales", rel.Tenant)
+
+		children, err := store.GetChildren(ops, salesTerritory.Id)
+		require.NoError(t, err)
+		assert.Empty(t, children)
+	})
+
+	t.Run("deletes are scoped to the tenant", func(t *testing.T) {
+		err := store.DeleteLocation(ops, salesRegion.Id)
+		assert.ErrorIs(t, err, ErrLocationNotFound)
+		_, err = store.GetLocation(sales, salesRegion.Id)
+		assert.NoError(t, err)
+	})
+}
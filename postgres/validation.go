@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HierarchyViolationError is returned when an update would leave existing relations
+// breaking the hierarchy rules. It lists every violating relation; errors.Is matches
+// ErrHierarchyViolation as well as the reasons of the individual conflicts.
+type HierarchyViolationError struct {
+	Conflicts []RelationConflict
+}
+
+// Error lists the violating relations with their reasons
+func (e *HierarchyViolationError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrHierarchyViolation.Error())
+	for i, conflict := range e.Conflicts {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		fmt.Fprintf(&b, "%s -> %s: %v", conflict.Relation.ParentID, conflict.Relation.ChildID, conflict.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns ErrHierarchyViolation and the reasons of the conflicts
+func (e *HierarchyViolationError) Unwrap() []error {
+	errs := []error{ErrHierarchyViolation}
+	for _, conflict := range e.Conflicts {
+		errs = append(errs, conflict.Err)
+	}
+	return errs
+}
+
+// ValidateGeoLevelUpdate reports the existing relations that would break the hierarchy rules
+// if the rank of the geo level were changed, without changing anything
+func (s *Store) ValidateGeoLevelUpdate(ctx context.Context, name string, newRank *float64) ([]RelationConflict, error) {
+	if name == "" {
+		return nil, ErrGeoLevelNameRequired
+	}
+
+	var geoLevel GeoLevel
+	err := s.DB.WithContext(ctx).Where("name = ?", strings.ToUpper(name)).First(&geoLevel).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGeoLevelNotFound
+		}
+		return nil, fmt.Errorf("failed to get geo level: %w", err)
+	}
+
+	conflicts, err := rankChangeConflicts(s.DB.WithContext(ctx), geoLevel, newRank)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate geo level update: %w", err)
+	}
+
+	return conflicts, nil
+}
+
+// ValidateLocationUpdate reports the existing relations that would break the hierarchy rules
+// if the location were moved to the given geo level, without changing anything
+func (s *Store) ValidateLocationUpdate(ctx context.Context, id uuid.UUID, geoLevelName string) ([]RelationConflict, error) {
+	if geoLevelName == "" {
+		return nil, ErrGeoLevelNameRequired
+	}
+
+	db := s.DB.WithContext(ctx)
+	var location Location
+	if err := db.First(&location, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLocationNotFound
+		}
+		return nil, fmt.Errorf("failed to get location: %w", err)
+	}
+
+	var geoLevel GeoLevel
+	if err := db.Where("name = ?", strings.ToUpper(geoLevelName)).First(&geoLevel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGeoLevelNotFound
+		}
+		return nil, fmt.Errorf("failed to get geo level: %w", err)
+	}
+	if geoLevel.Id == location.GeoLevelID {
+		return []RelationConflict{}, nil
+	}
+
+	conflicts, err := levelChangeConflicts(db, location.Id, geoLevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate location update: %w", err)
+	}
+
+	return conflicts, nil
+}
+
+// rankChangeConflicts returns the relations of locations at the geo level whose rank order
+// breaks if the geo level gets the new rank. An unranked level is not rank checked.
+func rankChangeConflicts(tx *gorm.DB, geoLevel GeoLevel, newRank *float64) ([]RelationConflict, error) {
+	conflicts := []RelationConflict{}
+	if newRank == nil {
+		return conflicts, nil
+	}
+
+	var relations []Relation
+	if err := tx.Preload("Parent.GeoLevel").Preload("Child.GeoLevel").
+		Joins("JOIN locations p ON p.id = relations.parent_id AND p.deleted_at IS NULL").
+		Joins("JOIN locations c ON c.id = relations.child_id AND c.deleted_at IS NULL").
+		Where("p.geo_level_id = ? OR c.geo_level_id = ?", geoLevel.Id, geoLevel.Id).
+		Find(&relations).Error; err != nil {
+		return nil, err
+	}
+
+	rank := func(loc *Location) *float64 {
+		if loc.GeoLevelID == geoLevel.Id {
+			return newRank
+		}
+		return loc.GeoLevel.Rank
+	}
+	for _, rel := range relations {
+		if rel.Parent == nil || rel.Child == nil {
+			continue
+		}
+		parentRank, childRank := rank(rel.Parent), rank(rel.Child)
+		if parentRank != nil && childRank != nil && *parentRank >= *childRank {
+			conflicts = append(conflicts, RelationConflict{Relation: rel, Err: ErrInvalidHierarchy})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// levelChangeConflicts returns the relations of the location that break the rank order,
+// the level rules or the one-parent-per-level rule if the location moves to the geo level.
+// The returned relations show the location at its new geo level.
+func levelChangeConflicts(tx *gorm.DB, locationID uuid.UUID, geoLevel GeoLevel) ([]RelationConflict, error) {
+	var relations []Relation
+	if err := tx.Preload("Parent.GeoLevel").Preload("Child.GeoLevel").
+		Where("parent_id = ? OR child_id = ?", locationID, locationID).
+		Find(&relations).Error; err != nil {
+		return nil, err
+	}
+
+	conflicts := []RelationConflict{}
+	for _, rel := range relations {
+		if rel.Parent == nil || rel.Child == nil {
+			continue
+		}
+
+		var parentLevel, childLevel GeoLevel
+		if rel.ChildID == locationID {
+			child := *rel.Child
+			child.GeoLevelID, child.GeoLevel = geoLevel.Id, geoLevel
+			rel.Child = &child
+			parentLevel, childLevel = rel.Parent.GeoLevel, geoLevel
+		} else {
+			parent := *rel.Parent
+			parent.GeoLevelID, parent.GeoLevel = geoLevel.Id, geoLevel
+			rel.Parent = &parent
+			parentLevel, childLevel = geoLevel, rel.Child.GeoLevel
+		}
+
+		if parentLevel.Rank != nil && childLevel.Rank != nil && *parentLevel.Rank >= *childLevel.Rank {
+			conflicts = append(conflicts, RelationConflict{Relation: rel, Err: ErrInvalidHierarchy})
+			continue
+		}
+
+		if err := checkLevelRule(tx, parentLevel.Id, childLevel.Id); err != nil {
+			if !errors.Is(err, ErrLevelRuleViolation) {
+				return nil, err
+			}
+			conflicts = append(conflicts, RelationConflict{Relation: rel, Err: err})
+			continue
+		}
+
+		// A child of the location must not already have another parent of the new level
+		if rel.ParentID == locationID {
+			var count int64
+			if err := tx.Model(&Relation{}).
+				Joins("JOIN locations parent ON parent.id = relations.parent_id").
+				Where("relations.child_id = ? AND relations.parent_id <> ? AND parent.geo_level_id = ? AND parent.deleted_at IS NULL",
+					rel.ChildID, locationID, geoLevel.Id).
+				Count(&count).Error; err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				conflicts = append(conflicts, RelationConflict{Relation: rel, Err: ErrDuplicateRelation})
+			}
+		}
+	}
+
+	return conflicts, nil
+}
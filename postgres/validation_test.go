@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidation_GeoLevelRankChange(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	// Country1 > State1 > District1
+	_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["State1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		rank          *float64
+		wantConflicts int
+	}{
+		{name: "rank keeps the order", rank: float64Ptr(1.5), wantConflicts: 0},
+		{name: "rank equal to the child rank", rank: float64Ptr(3.0), wantConflicts: 1},
+		{name: "rank below the child rank", rank: float64Ptr(3.5), wantConflicts: 1},
+		{name: "rank above the parent rank", rank: float64Ptr(0.5), wantConflicts: 1},
+		{name: "no rank change", rank: nil, wantConflicts: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts, err := store.ValidateGeoLevelUpdate(ctx, "STATE", tt.rank)
+			require.NoError(t, err)
+			assert.Len(t, conflicts, tt.wantConflicts)
+			for _, conflict := range conflicts {
+				assert.ErrorIs(t, conflict.Err, ErrInvalidHierarchy)
+			}
+
+			// The dry run leaves the rank untouched
+			level, err := store.GetGeoLevelByName(ctx, "STATE")
+			require.NoError(t, err)
+			assert.Equal(t, 2.0, *level.Rank)
+		})
+	}
+
+	t.Run("violating update fails with the relations", func(t *testing.T) {
+		_, err := store.UpdateGeoLevel(ctx, "STATE", nil, float64Ptr(3.5))
+		assert.ErrorIs(t, err, ErrHierarchyViolation)
+		assert.ErrorIs(t, err, ErrInvalidHierarchy)
+
+		var violationErr *HierarchyViolationError
+		require.True(t, errors.As(err, &violationErr))
+		require.Len(t, violationErr.Conflicts, 1)
+		assert.Equal(t, locs["State1"].Id, violationErr.Conflicts[0].Relation.ParentID)
+		assert.Equal(t, locs["District1"].Id, violationErr.Conflicts[0].Relation.ChildID)
+
+		level, err := store.GetGeoLevelByName(ctx, "STATE")
+		require.NoError(t, err)
+		assert.Equal(t, 2.0, *level.Rank)
+	})
+
+	t.Run("valid update succeeds", func(t *testing.T) {
+		level, err := store.UpdateGeoLevel(ctx, "STATE", nil, float64Ptr(1.5))
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, *level.Rank)
+	})
+
+	t.Run("unknown geo level", func(t *testing.T) {
+		_, err := store.ValidateGeoLevelUpdate(ctx, "VILLAGE", float64Ptr(1.0))
+		assert.ErrorIs(t, err, ErrGeoLevelNotFound)
+	})
+}
+
+func TestValidation_LocationLevelChange(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	// State1 > District1 > City1, and City2 below both State2 and District2
+	_, err := store.InsertRelation(ctx, locs["State1"].Id, locs["District1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["District1"].Id, locs["City1"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["State2"].Id, locs["City2"].Id)
+	require.NoError(t, err)
+	_, err = store.InsertRelation(ctx, locs["District2"].Id, locs["City2"].Id)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		location   string
+		geoLevel   string
+		wantErrors []error
+	}{
+		{name: "same geo level", location: "District1", geoLevel: "DISTRICT"},
+		{name: "parent rank no longer lower", location: "District1", geoLevel: "COUNTRY", wantErrors: []error{ErrInvalidHierarchy}},
+		{name: "child rank no longer higher", location: "District1", geoLevel: "CITY", wantErrors: []error{ErrInvalidHierarchy}},
+		{name: "child gets a second parent of the level", location: "District2", geoLevel: "STATE", wantErrors: []error{ErrDuplicateRelation}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := locs[tt.location].Id
+			conflicts, err := store.ValidateLocationUpdate(ctx, id, tt.geoLevel)
+			require.NoError(t, err)
+			require.Len(t, conflicts, len(tt.wantErrors))
+			for i, conflict := range conflicts {
+				assert.ErrorIs(t, conflict.Err, tt.wantErrors[i])
+			}
+
+			_, err = store.UpdateLocation(ctx, id, stringPtr(tt.geoLevel), nil)
+			if len(tt.wantErrors) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrHierarchyViolation)
+
+			// The location keeps its geo level
+			loc, err := store.GetLocation(ctx, id)
+			require.NoError(t, err)
+			assert.Equal(t, locs[tt.location].GeoLevel.Name, loc.GeoLevel)
+		})
+	}
+
+	t.Run("conflicts show the new geo level", func(t *testing.T) {
+		conflicts, err := store.ValidateLocationUpdate(ctx, locs["District1"].Id, "COUNTRY")
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "STATE", conflicts[0].Relation.Parent.GeoLevel.Name)
+		assert.Equal(t, "COUNTRY", conflicts[0].Relation.Child.GeoLevel.Name)
+	})
+
+	t.Run("reassignment of a geo level is validated", func(t *testing.T) {
+		err := store.DeleteGeoLevelWithReassignment(ctx, "DISTRICT", "COUNTRY")
+		assert.ErrorIs(t, err, ErrHierarchyViolation)
+
+		_, err = store.GetGeoLevelByName(ctx, "DISTRICT")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type expectedVersionsKey struct{}
+
+// WithExpectedVersion returns a context in which changes to the location fail with
+// ErrVersionConflict unless the location is at the given version. Expectations for
+// several locations can be combined by wrapping the context repeatedly.
+func WithExpectedVersion(ctx context.Context, id uuid.UUID, version int64) context.Context {
+	expected := map[uuid.UUID]int64{}
+	if outer, ok := ctx.Value(expectedVersionsKey{}).(map[uuid.UUID]int64); ok {
+		maps.Copy(expected, outer)
+	}
+	expected[id] = version
+	return context.WithValue(ctx, expectedVersionsKey{}, expected)
+}
+
+func expectedVersion(ctx context.Context, id uuid.UUID) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	expected, ok := ctx.Value(expectedVersionsKey{}).(map[uuid.UUID]int64)
+	if !ok {
+		return 0, false
+	}
+	version, ok := expected[id]
+	return version, ok
+}
+
+// touchLocations bumps the version of the locations, once per database transaction however
+// often a location is touched in it. A location with an expected version in the context must
+// have been at that version before the transaction touched it. Concurrent writers serialize
+// on the row lock of the update, so only one of them can see the expected version.
+func touchLocations(tx *gorm.DB, ids ...uuid.UUID) error {
+	db := tx.Session(&gorm.Session{NewDB: true})
+	for _, id := range ids {
+		if id == uuid.Nil {
+			continue
+		}
+		if err := db.Exec(`UPDATE locations SET version = version + 1, version_tx = txid_current()
+			WHERE id = ? AND version_tx IS DISTINCT FROM txid_current()`, id).Error; err != nil {
+			return fmt.Errorf("failed to bump location version: %w", err)
+		}
+
+		expected, ok := expectedVersion(tx.Statement.Context, id)
+		if !ok {
+			continue
+		}
+		var version int64
+		if err := db.Raw("SELECT version FROM locations WHERE id = ?", id).Scan(&version).Error; err != nil {
+			return err
+		}
+		if version != expected+1 {
+			return fmt.Errorf("%w: location %s is at version %d, expected %d", ErrVersionConflict, id, version-1, expected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersion_Bumps(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+
+	version := func(t *testing.T, name string) int64 {
+		t.Helper()
+		loc, err := store.GetLocation(ctx, locs[name].Id)
+		require.NoError(t, err)
+		return loc.Version
+	}
+
+	assert.Equal(t, int64(1), version(t, "State1"), "creating a location with its primary name is version 1")
+
+	tests := []struct {
+		name    string
+		change  func() error
+		touched []string
+		kept    []string
+	}{
+		{
+			name:    "add alias",
+			change:  func() error { return store.InsertNameMap(ctx, locs["State1"].Id, "Alias", false) },
+			touched: []string{"State1"},
+			kept:    []string{"Country1"},
+		},
+		{
+			name:    "set primary name",
+			change:  func() error { return store.SetPrimaryName(ctx, locs["State1"].Id, "Alias") },
+			touched: []string{"State1"},
+		},
+		{
+			name:    "remove alias",
+			change:  func() error { return store.DeleteNameMap(ctx, locs["State1"].Id, "State1") },
+			touched: []string{"State1"},
+		},
+		{
+			name: "add relation",
+			change: func() error {
+				_, err := store.InsertRelation(ctx, locs["Country1"].Id, locs["State1"].Id)
+				return err
+			},
+			touched: []string{"Country1", "State1"},
+			kept:    []string{"Country2"},
+		},
+		{
+			name: "rename and change level in one update",
+			change: func() error {
+				_, err := store.UpdateLocation(ctx, locs["City1"].Id, stringPtr("DISTRICT"), stringPtr("Renamed"))
+				return err
+			},
+			touched: []string{"City1"},
+		},
+		{
+			name: "remove relations",
+			change: func() error {
+				return store.DeleteAllRelations(ctx, locs["State1"].Id)
+			},
+			touched: []string{"Country1", "State1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := make(map[string]int64)
+			for _, name := range append(tt.touched, tt.kept...) {
+				before[name] = version(t, name)
+			}
+
+			require.NoError(t, tt.change())
+
+			for _, name := range tt.touched {
+				assert.Equal(t, before[name]+1, version(t, name), "%s is bumped once", name)
+			}
+			for _, name := range tt.kept {
+				assert.Equal(t, before[name], version(t, name), "%s is unchanged", name)
+			}
+		})
+	}
+}
+
+func TestVersion_ExpectedVersion(t *testing.T) {
+	store, locs, _ := setupRelationsTest(t)
+	ctx := context.Background()
+	id := locs["State1"].Id
+
+	t.Run("stale version", func(t *testing.T) {
+		stale := WithExpectedVersion(ctx, id, 0)
+		err := store.InsertNameMap(stale, id, "Alias", false)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		_, err = store.UpdateLocation(stale, id, nil, nil)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		names, err := store.GetNameMapByLocationID(ctx, id)
+		require.NoError(t, err)
+		assert.Len(t, names, 1, "the failed change is rolled back")
+	})
+
+	t.Run("current version", func(t *testing.T) {
+		current := WithExpectedVersion(ctx, id, 1)
+		require.NoError(t, store.InsertNameMap(current, id, "Alias", false))
+
+		// The version moved on, so the same expectation now conflicts
+		err := store.InsertNameMap(current, id, "Other", false)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("relation changes check both ends", func(t *testing.T) {
+		parent := WithExpectedVersion(ctx, locs["Country1"].Id, 0)
+		_, err := store.InsertRelation(parent, locs["Country1"].Id, id)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		unrelated := WithExpectedVersion(ctx, locs["Country2"].Id, 0)
+		_, err = store.InsertRelation(unrelated, locs["Country1"].Id, id)
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		err := store.DeleteLocation(WithExpectedVersion(ctx, locs["City1"].Id, 5), locs["City1"].Id)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		err = store.DeleteLocation(WithExpectedVersion(ctx, locs["City1"].Id, 1), locs["City1"].Id)
+		assert.NoError(t, err)
+	})
+}
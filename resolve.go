@@ -0,0 +1,262 @@
+package location
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// DefaultResolveLimit is the number of candidates ResolvePlace returns when no limit is given
+const DefaultResolveLimit = 10
+
+const (
+	// maxPlaceNameWords is the longest run of words of the input looked up as one name
+	maxPlaceNameWords = 5
+	// aliasMatchWeight scales the confidence of a match on an alias instead of a primary name
+	aliasMatchWeight = 0.9
+)
+
+// PlaceMatch is a location of a resolved chain with the part of the input it matched
+type PlaceMatch struct {
+	GeoID       string `json:"geo_id"`
+	GeoLevel    string `json:"geo_level"`
+	Name        string `json:"name"`         // primary name of the location
+	MatchedName string `json:"matched_name"` // primary name or alias that matched
+	Text        string `json:"text"`         // words of the input that matched
+}
+
+// PlaceCandidate is a location that free text may refer to
+type PlaceCandidate struct {
+	Location Location `json:"location"`
+	// Confidence is the share of the words of the input matched by the chain, lowered for
+	// matches on aliases and shared between candidates matching the same words
+	Confidence float64 `json:"confidence"`
+	// Chain lists the candidate first, then the ancestors matched by the rest of the input
+	// from the nearest up
+	Chain []PlaceMatch `json:"chain"`
+}
+
+// placeSpan is a run of words of the input looked up as one name
+type placeSpan struct {
+	start, end int // word positions in the whole input, end excluded
+	text       string
+	normalized string
+}
+
+func (span placeSpan) overlaps(other placeSpan) bool {
+	return span.start < other.end && other.start < span.end
+}
+
+// placeSpans splits free text into parts at commas, semicolons and line breaks, and returns
+// every run of up to maxPlaceNameWords words of each part, with the total number of words
+func placeSpans(text string) ([]placeSpan, int) {
+	var spans []placeSpan
+	position := 0
+	parts := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' || r == '\n' })
+	for _, part := range parts {
+		words := postgres.NameWords(part)
+		for start := range words {
+			for end := start + 1; end <= min(len(words), start+maxPlaceNameWords); end++ {
+				spanText := strings.Join(words[start:end], " ")
+				spans = append(spans, placeSpan{
+					start:      position + start,
+					end:        position + end,
+					text:       spanText,
+					normalized: postgres.NormalizeName(spanText),
+				})
+			}
+		}
+		position += len(words)
+	}
+	return spans, position
+}
+
+// placeNameMatch is a name of a location matching a span of the input
+type placeNameMatch struct {
+	span       placeSpan
+	locationID uuid.UUID
+	name       string
+	isPrimary  bool
+}
+
+// placeChain is a candidate location with the ancestors matched by other spans of the input
+type placeChain struct {
+	matches []placeNameMatch
+	score   float64
+	key     string // words matched, shared by chains reading the input the same way
+}
+
+// ResolvePlace maps user-entered text such as "Kochi, Ernakulam, KL" to the locations it may
+// refer to. Runs of words are matched against the primary names and aliases of the locations,
+// regardless of case and punctuation, and each matching location is combined with the matches
+// among its ancestors. Candidates are ranked by confidence, at most limit of them
+// (DefaultResolveLimit if limit is not positive).
+func (service *ServiceOnPostgres) ResolvePlace(ctx context.Context, text string, limit int) ([]PlaceCandidate, error) {
+	if limit <= 0 {
+		limit = DefaultResolveLimit
+	}
+	spans, words := placeSpans(text)
+	if words == 0 {
+		return nil, postgres.ErrNameRequired
+	}
+
+	normalized := make([]string, 0, len(spans))
+	for _, span := range spans {
+		if !slices.Contains(normalized, span.normalized) {
+			normalized = append(normalized, span.normalized)
+		}
+	}
+	names, err := service.db.MatchNames(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []placeNameMatch
+	var ids []uuid.UUID
+	for _, span := range spans {
+		for _, name := range names {
+			if postgres.NormalizeName(name.Name) != span.normalized {
+				continue
+			}
+			matches = append(matches, placeNameMatch{span: span, locationID: name.LocationID, name: name.Name, isPrimary: name.IsPrimary})
+			if !slices.Contains(ids, name.LocationID) {
+				ids = append(ids, name.LocationID)
+			}
+		}
+	}
+	ancestors, err := service.db.AncestorDepths(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// the best chain of each location, anchored at any of its matches
+	chains := make(map[uuid.UUID]placeChain, len(ids))
+	for _, anchor := range matches {
+		chain := buildPlaceChain(anchor, matches, ancestors[anchor.locationID], words)
+		if best, ok := chains[anchor.locationID]; !ok || chain.score > best.score {
+			chains[anchor.locationID] = chain
+		}
+	}
+	readings := make(map[string]int)
+	for _, chain := range chains {
+		readings[chain.key]++
+	}
+
+	ranked := make([]placeChain, 0, len(chains))
+	for _, chain := range chains {
+		chain.score /= float64(readings[chain.key])
+		ranked = append(ranked, chain)
+	}
+	slices.SortFunc(ranked, func(a, b placeChain) int {
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			cmp.Compare(len(b.matches), len(a.matches)),
+			cmp.Compare(a.matches[0].locationID.String(), b.matches[0].locationID.String()),
+		)
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	locations := make(map[uuid.UUID]*Location)
+	getLocation := func(id uuid.UUID) (*Location, error) {
+		if loc, ok := locations[id]; ok {
+			return loc, nil
+		}
+		loc, err := service.GetLocation(ctx, id.String())
+		if err != nil {
+			return nil, err
+		}
+		locations[id] = loc
+		return loc, nil
+	}
+	candidates := make([]PlaceCandidate, 0, len(ranked))
+	for _, chain := range ranked {
+		candidate := PlaceCandidate{Confidence: chain.score, Chain: make([]PlaceMatch, 0, len(chain.matches))}
+		for i, match := range chain.matches {
+			loc, err := getLocation(match.locationID)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				candidate.Location = *loc
+			}
+			candidate.Chain = append(candidate.Chain, PlaceMatch{
+				GeoID:       loc.GeoID,
+				GeoLevel:    loc.GeoLevel,
+				Name:        loc.Name,
+				MatchedName: match.name,
+				Text:        match.span.text,
+			})
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// buildPlaceChain adds to the anchor the matches of its ancestors on the other words of the
+// input, longest runs of words first, then nearest ancestors, then primary names
+func buildPlaceChain(anchor placeNameMatch, matches []placeNameMatch, ancestors map[uuid.UUID]int, words int) placeChain {
+	var others []placeNameMatch
+	for _, match := range matches {
+		if depth, ok := ancestors[match.locationID]; ok && depth > 0 && !match.span.overlaps(anchor.span) {
+			others = append(others, match)
+		}
+	}
+	slices.SortStableFunc(others, func(a, b placeNameMatch) int {
+		return cmp.Or(
+			cmp.Compare(b.span.end-b.span.start, a.span.end-a.span.start),
+			cmp.Compare(ancestors[a.locationID], ancestors[b.locationID]),
+			compareBool(b.isPrimary, a.isPrimary),
+		)
+	})
+
+	chain := placeChain{matches: []placeNameMatch{anchor}}
+	for _, match := range others {
+		if slices.ContainsFunc(chain.matches, func(m placeNameMatch) bool {
+			return m.locationID == match.locationID || m.span.overlaps(match.span)
+		}) {
+			continue
+		}
+		chain.matches = append(chain.matches, match)
+	}
+	slices.SortStableFunc(chain.matches[1:], func(a, b placeNameMatch) int {
+		return cmp.Compare(ancestors[a.locationID], ancestors[b.locationID])
+	})
+
+	matched := 0
+	weight := 0.0
+	spans := make([]placeSpan, 0, len(chain.matches))
+	for _, match := range chain.matches {
+		matched += match.span.end - match.span.start
+		if match.isPrimary {
+			weight++
+		} else {
+			weight += aliasMatchWeight
+		}
+		spans = append(spans, match.span)
+	}
+	slices.SortFunc(spans, func(a, b placeSpan) int { return cmp.Compare(a.start, b.start) })
+	var key strings.Builder
+	for _, span := range spans {
+		key.WriteString(strconv.Itoa(span.start) + "-" + strconv.Itoa(span.end) + ";")
+	}
+	chain.key = key.String()
+	chain.score = float64(matched) / float64(words) * weight / float64(len(chain.matches))
+	return chain
+}
+
+func compareBool(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,78 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestPlaceSpans(t *testing.T) {
+	spans, words := placeSpans("Kochi, Ernakulam;  St. Louis")
+	assert.Equal(t, 4, words)
+	var texts []string
+	for _, span := range spans {
+		texts = append(texts, span.text)
+	}
+	// runs of words do not cross commas or semicolons
+	assert.Equal(t, []string{"Kochi", "Ernakulam", "St", "St Louis", "Louis"}, texts)
+	assert.Equal(t, placeSpan{start: 2, end: 4, text: "St Louis", normalized: "st louis"}, spans[3])
+
+	_, words = placeSpans(" , ;")
+	assert.Zero(t, words)
+}
+
+func TestServiceOnPostgres_ResolvePlace(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+	require.NoError(t, service.AddAliasToLocation(ctx, locs["Kerala"].GeoID, "KL"))
+	karnataka := createTestLocation(t, service, "STATE", "Karnataka")
+	require.NoError(t, service.AddParent(ctx, karnataka.GeoID, locs["India"].GeoID))
+	otherKochi := createTestLocation(t, service, "CITY", "Kochi")
+	require.NoError(t, service.AddParent(ctx, otherKochi.GeoID, karnataka.GeoID))
+
+	t.Run("chain consistent with the hierarchy", func(t *testing.T) {
+		candidates, err := service.ResolvePlace(ctx, "Kochi, Ernakulam, KL", 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, candidates)
+
+		best := candidates[0]
+		assert.Equal(t, locs["Kochi"].GeoID, best.Location.GeoID)
+		assert.InDelta(t, (1+1+0.9)/3.0, best.Confidence, 1e-9)
+		require.Len(t, best.Chain, 3)
+		assert.Equal(t, PlaceMatch{GeoID: locs["Kochi"].GeoID, GeoLevel: "CITY", Name: "Kochi", MatchedName: "Kochi", Text: "Kochi"}, best.Chain[0])
+		assert.Equal(t, locs["Ernakulam"].GeoID, best.Chain[1].GeoID)
+		assert.Equal(t, PlaceMatch{GeoID: locs["Kerala"].GeoID, GeoLevel: "STATE", Name: "Kerala", MatchedName: "KL", Text: "KL"}, best.Chain[2])
+
+		for _, candidate := range candidates[1:] {
+			assert.Less(t, candidate.Confidence, best.Confidence)
+		}
+	})
+
+	t.Run("ambiguous names share the confidence", func(t *testing.T) {
+		candidates, err := service.ResolvePlace(ctx, "kochi", 0)
+		require.NoError(t, err)
+		require.Len(t, candidates, 2)
+		assert.ElementsMatch(t, []string{locs["Kochi"].GeoID, otherKochi.GeoID},
+			[]string{candidates[0].Location.GeoID, candidates[1].Location.GeoID})
+		assert.InDelta(t, 0.5, candidates[0].Confidence, 1e-9)
+
+		candidates, err = service.ResolvePlace(ctx, "Kochi Karnataka", 1)
+		require.NoError(t, err)
+		require.Len(t, candidates, 1)
+		assert.Equal(t, otherKochi.GeoID, candidates[0].Location.GeoID)
+		assert.InDelta(t, 1.0, candidates[0].Confidence, 1e-9)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		candidates, err := service.ResolvePlace(ctx, "Atlantis", 0)
+		require.NoError(t, err)
+		assert.Empty(t, candidates)
+
+		_, err = service.ResolvePlace(ctx, " , ", 0)
+		assert.ErrorIs(t, err, postgres.ErrNameRequired)
+	})
+}
@@ -0,0 +1,85 @@
+package location
+
+import (
+	"context"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// SetAttributeSchema replaces the attribute schema of a geo level, nil removing it. From then
+// on, locations created at the geo level, moved to it, or whose attributes change must follow
+// the schema. It returns the existing locations of the geo level that break the schema; those
+// are reported, not changed.
+func (service *ServiceOnPostgres) SetAttributeSchema(ctx context.Context, geoLevel string, schema AttributeSchema) ([]AttributeViolation, error) {
+	var level *postgres.GeoLevel
+	err := service.transaction(ctx, func(store *postgres.Store) error {
+		var err error
+		level, err = store.SetAttributeSchema(ctx, geoLevel, toStoreSchema(schema))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	violations, err := service.db.GetAttributeViolations(ctx, &level.Id)
+	if err != nil {
+		return nil, err
+	}
+	return attributeViolations(violations), nil
+}
+
+// GetAttributeViolations reports the attributes of all locations that break the attribute
+// schema of their geo level
+func (service *ServiceOnPostgres) GetAttributeViolations(ctx context.Context) ([]AttributeViolation, error) {
+	violations, err := service.db.GetAttributeViolations(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return attributeViolations(violations), nil
+}
+
+func attributeViolations(violations []postgres.AttributeViolation) []AttributeViolation {
+	out := make([]AttributeViolation, 0, len(violations))
+	for _, violation := range violations {
+		out = append(out, AttributeViolation{
+			GeoID:    violation.LocationID.String(),
+			GeoLevel: violation.GeoLevel,
+			Key:      violation.Key,
+			Reason:   violation.Reason,
+		})
+	}
+	return out
+}
+
+func toStoreSchema(schema AttributeSchema) postgres.AttributeSchema {
+	if schema == nil {
+		return nil
+	}
+	out := make(postgres.AttributeSchema, len(schema))
+	for key, rule := range schema {
+		out[key] = postgres.AttributeRule{
+			Type:      postgres.AttributeType(rule.Type),
+			Required:  rule.Required,
+			Enum:      rule.Enum,
+			Pattern:   rule.Pattern,
+			Inherited: rule.Inherited,
+		}
+	}
+	return out
+}
+
+func fromStoreSchema(schema postgres.AttributeSchema) AttributeSchema {
+	if schema == nil {
+		return nil
+	}
+	out := make(AttributeSchema, len(schema))
+	for key, rule := range schema {
+		out[key] = AttributeRule{
+			Type:      AttributeType(rule.Type),
+			Required:  rule.Required,
+			Enum:      rule.Enum,
+			Pattern:   rule.Pattern,
+			Inherited: rule.Inherited,
+		}
+	}
+	return out
+}
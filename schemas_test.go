@@ -0,0 +1,52 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_AttributeSchema(t *testing.T) {
+	service := setupTestDB(t)
+	locs := createTestHierarchy(t, service)
+	ctx := context.Background()
+
+	violations, err := service.SetAttributeSchema(ctx, "COUNTRY", AttributeSchema{
+		"currency": {Type: AttributeString, Required: true, Enum: []any{"INR", "USD", "EUR"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []AttributeViolation{
+		{GeoID: locs["India"].GeoID, GeoLevel: "COUNTRY", Key: "currency", Reason: "is required"},
+	}, violations)
+
+	level, err := service.GetGeoLevel(ctx, "COUNTRY")
+	require.NoError(t, err)
+	assert.True(t, level.AttributeSchema["currency"].Required)
+
+	_, err = service.AddLocation(ctx, "", "COUNTRY", "Nepal")
+	assert.ErrorIs(t, err, postgres.ErrAttributeViolation)
+	_, err = service.AddLocationWithAttributes(ctx, "", "COUNTRY", "Nepal", map[string]any{"currency": "NPR"})
+	assert.ErrorIs(t, err, postgres.ErrAttributeViolation)
+	nepal, err := service.AddLocationWithAttributes(ctx, "", "COUNTRY", "Nepal", map[string]any{"currency": "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"currency": "USD"}, nepal.Attributes)
+
+	_, err = service.PatchAttributes(ctx, locs["India"].GeoID, map[string]any{"currency": "INR"})
+	require.NoError(t, err)
+	violations, err = service.GetAttributeViolations(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+
+	goa := createTestLocation(t, service, "STATE", "Goa")
+	_, err = service.UpdateLocation(ctx, goa.GeoID, nil, stringPtr("COUNTRY"))
+	assert.ErrorIs(t, err, postgres.ErrAttributeViolation)
+
+	violations, err = service.SetAttributeSchema(ctx, "COUNTRY", nil)
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	_, err = service.AddLocation(ctx, "", "COUNTRY", "Bhutan")
+	assert.NoError(t, err)
+}
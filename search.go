@@ -0,0 +1,131 @@
+package location
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// SearchOptions narrows and enriches the results of SearchLocations
+type SearchOptions struct {
+	GeoLevel      *string           // only locations of this geo level, all if nil
+	Attributes    []AttributeFilter // only locations passing all the filters
+	WithAncestors bool              // include the ancestors of each result
+}
+
+// Ancestor is a location above a search result
+type Ancestor struct {
+	GeoID    string `json:"geo_id"`
+	GeoLevel string `json:"geo_level"`
+	Name     string `json:"name"`
+}
+
+// SearchResult is a location matching a search with the name that matched
+type SearchResult struct {
+	Location
+	MatchedName    string `json:"matched_name"`
+	MatchedPrimary bool   `json:"matched_primary"` // whether MatchedName is the primary name or an alias
+	// Ancestors lists one ancestor per geo level from the nearest up, with SearchOptions.WithAncestors
+	Ancestors []Ancestor `json:"ancestors,omitempty"`
+}
+
+// Label returns the name of the result followed by the names of its ancestors, e.g.
+// "Springfield (Sangamon, Illinois, USA)", or the name alone without ancestors
+func (result SearchResult) Label() string {
+	if len(result.Ancestors) == 0 {
+		return result.Name
+	}
+	names := make([]string, 0, len(result.Ancestors))
+	for _, ancestor := range result.Ancestors {
+		names = append(names, ancestor.Name)
+	}
+	return result.Name + " (" + strings.Join(names, ", ") + ")"
+}
+
+// SearchLocations finds the locations whose primary name or one of whose aliases contains
+// the pattern, regardless of case. Each location is returned once with the name that matched,
+// its primary name if it did, and locations matching by their primary name come first.
+// Attribute filters are checked on the database, e.g. "population > 1e6".
+func (service *ServiceOnPostgres) SearchLocations(ctx context.Context, pattern string, opts SearchOptions) ([]SearchResult, error) {
+	var geoLevelID *uuid.UUID
+	if opts.GeoLevel != nil {
+		geoLevel, err := service.db.GetGeoLevelByName(ctx, *opts.GeoLevel)
+		if err != nil {
+			return nil, err
+		}
+		geoLevelID = &geoLevel.Id
+	}
+	names, err := service.db.SearchNamesByPattern(ctx, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []postgres.NameMap
+	var ids []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, name := range names {
+		if name.Location == nil || seen[name.LocationID] {
+			continue
+		}
+		if geoLevelID != nil && name.Location.GeoLevelID != *geoLevelID {
+			continue
+		}
+		seen[name.LocationID] = true
+		matches = append(matches, name)
+		ids = append(ids, name.LocationID)
+	}
+	if len(opts.Attributes) > 0 && len(ids) > 0 {
+		passing, err := service.db.FindLocations(ctx, postgres.LocationQuery{IDs: ids, Attributes: toStoreFilters(opts.Attributes)})
+		if err != nil {
+			return nil, err
+		}
+		passes := make(map[uuid.UUID]bool, len(passing))
+		for _, loc := range passing {
+			passes[loc.Id] = true
+		}
+		matches = slices.DeleteFunc(matches, func(name postgres.NameMap) bool {
+			return !passes[name.LocationID]
+		})
+	}
+	if len(matches) == 0 {
+		return []SearchResult{}, nil
+	}
+	ids = ids[:0]
+	for _, name := range matches {
+		ids = append(ids, name.LocationID)
+	}
+	locations, err := service.db.GetLocationsWithNames(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	var paths map[uuid.UUID][]postgres.PathLocation
+	if opts.WithAncestors {
+		if paths, err = service.db.GetPaths(ctx, ids, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]SearchResult, 0, len(matches))
+	for _, name := range matches {
+		loc, ok := locations[name.LocationID]
+		if !ok {
+			continue // deleted since the search
+		}
+		result := SearchResult{Location: fromLocationWithNames(loc), MatchedName: name.Name, MatchedPrimary: name.IsPrimary}
+		if opts.WithAncestors {
+			path := paths[name.LocationID]
+			for i := len(path) - 2; i >= 0; i-- {
+				result.Ancestors = append(result.Ancestors, Ancestor{
+					GeoID:    path[i].LocationID.String(),
+					GeoLevel: path[i].GeoLevel,
+					Name:     path[i].Name,
+				})
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
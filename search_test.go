@@ -0,0 +1,88 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestSearchResult_Label(t *testing.T) {
+	result := SearchResult{Location: Location{Name: "Springfield"}}
+	assert.Equal(t, "Springfield", result.Label())
+
+	result.Ancestors = []Ancestor{{Name: "Illinois"}, {Name: "USA"}}
+	assert.Equal(t, "Springfield (Illinois, USA)", result.Label())
+}
+
+func TestServiceOnPostgres_SearchLocations(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+	createTestGeoLevel(t, service, "COUNTRY", float64Ptr(1))
+	createTestGeoLevel(t, service, "STATE", float64Ptr(2))
+	createTestGeoLevel(t, service, "CITY", float64Ptr(3))
+	usa := createTestLocation(t, service, "COUNTRY", "USA")
+	illinois := createTestLocation(t, service, "STATE", "Illinois")
+	missouri := createTestLocation(t, service, "STATE", "Missouri")
+	springfieldIL := createTestLocation(t, service, "CITY", "Springfield")
+	springfieldMO := createTestLocation(t, service, "CITY", "Springfield")
+	springdale := createTestLocation(t, service, "CITY", "Springdale")
+	require.NoError(t, service.AddChildren(ctx, usa.GeoID, []string{illinois.GeoID, missouri.GeoID}))
+	require.NoError(t, service.AddParent(ctx, springfieldIL.GeoID, illinois.GeoID))
+	require.NoError(t, service.AddParent(ctx, springfieldMO.GeoID, missouri.GeoID))
+	require.NoError(t, service.AddAliasToLocation(ctx, springfieldMO.GeoID, "Queen City of the Ozarks"))
+	require.NoError(t, service.AddAliasToLocation(ctx, springdale.GeoID, "Old Springfield"))
+
+	t.Run("ancestors disambiguate", func(t *testing.T) {
+		results, err := service.SearchLocations(ctx, "springfield", SearchOptions{WithAncestors: true})
+		require.NoError(t, err)
+		labels := make(map[string]string)
+		for _, result := range results {
+			labels[result.GeoID] = result.Label()
+			assert.Equal(t, "CITY", result.GeoLevel)
+		}
+		assert.Equal(t, map[string]string{
+			springfieldIL.GeoID: "Springfield (Illinois, USA)",
+			springfieldMO.GeoID: "Springfield (Missouri, USA)",
+			springdale.GeoID:    "Springdale",
+		}, labels)
+
+		// primary name matches come first, aliases are reported as such
+		require.Len(t, results, 3)
+		assert.Equal(t, springdale.GeoID, results[2].GeoID)
+		assert.Equal(t, "Old Springfield", results[2].MatchedName)
+		assert.False(t, results[2].MatchedPrimary)
+		assert.True(t, results[0].MatchedPrimary)
+		assert.Empty(t, results[2].Ancestors)
+	})
+
+	t.Run("alias match returns the whole location", func(t *testing.T) {
+		results, err := service.SearchLocations(ctx, "ozarks", SearchOptions{})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, springfieldMO.GeoID, results[0].GeoID)
+		assert.Equal(t, "Springfield", results[0].Name)
+		assert.Equal(t, "Queen City of the Ozarks", results[0].MatchedName)
+		assert.Nil(t, results[0].Ancestors)
+	})
+
+	t.Run("geo level filter", func(t *testing.T) {
+		results, err := service.SearchLocations(ctx, "i", SearchOptions{GeoLevel: stringPtr("state")})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		_, err = service.SearchLocations(ctx, "i", SearchOptions{GeoLevel: stringPtr("PLANET")})
+		assert.ErrorIs(t, err, postgres.ErrGeoLevelNotFound)
+	})
+
+	t.Run("pattern search reports geo level names", func(t *testing.T) {
+		locs, err := service.GetLocationsByPattern(ctx, "Springfield", nil)
+		require.NoError(t, err)
+		require.Len(t, locs, 3)
+		for _, loc := range locs {
+			assert.Equal(t, "CITY", loc.GeoLevel)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package location
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// SplitLocation splits a location into successors at the same geo level and under the same parents.
+// childAssignments maps the geo_id of every child of the location to the name of the successor
+// it moves to. The original location is kept, detached and marked as superseded by its successors.
+func (service *ServiceOnPostgres) SplitLocation(ctx context.Context, geoID string, successorNames []string, childAssignments map[string]string) ([]Location, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	assignments := make(map[uuid.UUID]string, len(childAssignments))
+	for childGeoID, successorName := range childAssignments {
+		childID, err := uuidFromString(childGeoID)
+		if err != nil {
+			return nil, err
+		}
+		assignments[childID] = successorName
+	}
+
+	var successors []postgres.Location
+	err = service.transaction(ctx, func(store *postgres.Store) error {
+		successors, err = store.SplitLocation(ctx, id, successorNames, assignments)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload the successors so that they carry their version and attributes
+	ids := make([]uuid.UUID, 0, len(successors))
+	for _, successor := range successors {
+		ids = append(ids, successor.Id)
+	}
+	named, err := service.db.GetLocationsWithNames(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Location, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, fromLocationWithNames(named[id]))
+	}
+	return out, nil
+}
@@ -0,0 +1,74 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_SplitLocation(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	aluva := createTestLocation(t, service, "CITY", "Aluva")
+	require.NoError(t, service.AddParent(ctx, aluva.GeoID, locs["Ernakulam"].GeoID))
+
+	successors, err := service.SplitLocation(ctx, locs["Ernakulam"].GeoID, []string{"Ernakulam", "Aluva District"}, map[string]string{
+		locs["Kochi"].GeoID: "Ernakulam",
+		aluva.GeoID:         "Aluva District",
+	})
+	require.NoError(t, err)
+	require.Len(t, successors, 2)
+	assert.Equal(t, "DISTRICT", successors[1].GeoLevel)
+	assert.Equal(t, "Aluva District", successors[1].Name)
+
+	parent, err := service.GetParentAtLevel(ctx, aluva.GeoID, "DISTRICT")
+	require.NoError(t, err)
+	assert.Equal(t, successors[1].GeoID, parent.GeoID)
+
+	districts, err := service.GetChildrenAtLevel(ctx, locs["Kerala"].GeoID, "DISTRICT")
+	require.NoError(t, err)
+	assert.Len(t, districts, 2)
+
+	original, err := service.GetLocation(ctx, locs["Ernakulam"].GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{successors[0].GeoID, successors[1].GeoID}, original.SupersededBy)
+
+	_, err = service.SplitLocation(ctx, locs["Ernakulam"].GeoID, []string{"Again"}, nil)
+	assert.ErrorIs(t, err, postgres.ErrLocationSuperseded)
+
+	_, err = service.SplitLocation(ctx, locs["Kerala"].GeoID, []string{"North Kerala"}, map[string]string{"invalid-uuid": "North Kerala"})
+	assert.Error(t, err)
+}
+
+func TestServiceOnPostgres_SplitLocationSuccessorVersion(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	_, err := service.SetAttributes(ctx, locs["Ernakulam"].GeoID, map[string]any{"iso_code": "KL-EKM"})
+	require.NoError(t, err)
+
+	successors, err := service.SplitLocation(ctx, locs["Ernakulam"].GeoID, []string{"Ernakulam", "Aluva District"}, map[string]string{
+		locs["Kochi"].GeoID: "Ernakulam",
+	})
+	require.NoError(t, err)
+	require.Len(t, successors, 2)
+
+	for _, successor := range successors {
+		got, err := service.GetLocation(ctx, successor.GeoID)
+		require.NoError(t, err)
+		assert.Equal(t, got.Version, successor.Version)
+		assert.Equal(t, map[string]any{"iso_code": "KL-EKM"}, successor.Attributes)
+	}
+
+	// The returned version can be used for an optimistic update right away
+	versionCtx, err := WithExpectedVersion(ctx, successors[1].GeoID, successors[1].Version)
+	require.NoError(t, err)
+	_, err = service.UpdateLocation(versionCtx, successors[1].GeoID, stringPtr("Aluva"), nil)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,552 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xaults/platform/location/postgres"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span and metric attributes of the instrumented service
+const (
+	attrMethod    = attribute.Key("location.method")
+	attrGeoID     = attribute.Key("location.geo_id")
+	attrGeoIDs    = attribute.Key("location.geo_ids")
+	attrGeoLevel  = attribute.Key("location.geo_level")
+	attrParentID  = attribute.Key("location.parent_geo_id")
+	attrErrorType = attribute.Key("error.type")
+)
+
+// TelemetryOption configures the providers of an InstrumentedService
+type TelemetryOption func(*telemetryConfig)
+
+type telemetryConfig struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the tracer provider, defaulting to the global one
+func WithTracerProvider(provider trace.TracerProvider) TelemetryOption {
+	return func(config *telemetryConfig) {
+		config.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the meter provider, defaulting to the global one
+func WithMeterProvider(provider metric.MeterProvider) TelemetryOption {
+	return func(config *telemetryConfig) {
+		config.meterProvider = provider
+	}
+}
+
+// InstrumentedService is a LocationService recording a span, the request count and the
+// latency of every call of the wrapped service. Spans carry the geo_ids and geo levels of
+// the call; metrics carry the method and the class of the error, if any. The store queries
+// of a call are instrumented by postgres.Instrument and appear as children of its span.
+type InstrumentedService struct {
+	next     LocationService
+	tracer   trace.Tracer
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+	// within InTx, the span of the transaction and the span it was started under
+	txSpan   trace.Span
+	txParent trace.SpanContext
+}
+
+var _ LocationService = (*InstrumentedService)(nil)
+
+// NewInstrumentedService wraps the service with tracing and metrics
+func NewInstrumentedService(next LocationService, opts ...TelemetryOption) (*InstrumentedService, error) {
+	config := telemetryConfig{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	meter := config.meterProvider.Meter(postgres.InstrumentationName)
+	requests, err := meter.Int64Counter("location.service.requests",
+		metric.WithDescription("Number of location service calls"),
+		metric.WithUnit("{request}"))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("location.service.duration",
+		metric.WithDescription("Duration of location service calls"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstrumentedService{
+		next:     next,
+		tracer:   config.tracerProvider.Tracer(postgres.InstrumentationName),
+		requests: requests,
+		duration: duration,
+	}, nil
+}
+
+// serviceCall is an instrumented call in progress
+type serviceCall struct {
+	service *InstrumentedService
+	ctx     context.Context
+	span    trace.Span
+	method  string
+	start   time.Time
+}
+
+func (service *InstrumentedService) begin(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, *serviceCall) {
+	// Calls made in a transaction with the context given to InTx belong to the InTx span
+	if service.txSpan != nil && trace.SpanContextFromContext(ctx).Equal(service.txParent) {
+		ctx = trace.ContextWithSpan(ctx, service.txSpan)
+	}
+	ctx, span := service.tracer.Start(ctx, "LocationService."+method, trace.WithAttributes(attrs...))
+	return ctx, &serviceCall{service: service, ctx: ctx, span: span, method: method, start: time.Now()}
+}
+
+// end records the outcome of the call and ends its span
+func (call *serviceCall) end(err error) {
+	attrs := []attribute.KeyValue{attrMethod.String(call.method)}
+	if class := errorClass(err); class != "" {
+		attrs = append(attrs, attrErrorType.String(class))
+		call.span.SetAttributes(attrErrorType.String(class))
+		call.span.RecordError(err)
+		call.span.SetStatus(codes.Error, err.Error())
+	}
+	call.service.requests.Add(call.ctx, 1, metric.WithAttributes(attrs...))
+	call.service.duration.Record(call.ctx, time.Since(call.start).Seconds(), metric.WithAttributes(attrs...))
+	call.span.End()
+}
+
+// errorClassPermissionDenied is the error class of calls denied by AuthorizedService
+const errorClassPermissionDenied = "permission_denied"
+
+// errorClass groups an error of the service for metrics (see postgres.ErrorClass)
+func errorClass(err error) string {
+	if errors.Is(err, ErrPermissionDenied) {
+		return errorClassPermissionDenied
+	}
+	if errors.Is(err, ErrInvalidGeoID) {
+		return postgres.ErrorClassInvalid
+	}
+	return postgres.ErrorClass(err)
+}
+
+func optionalGeoID(geoID *string) []attribute.KeyValue {
+	if geoID == nil {
+		return nil
+	}
+	return []attribute.KeyValue{attrGeoID.String(*geoID)}
+}
+
+// InTx runs fn in a transaction of the wrapped service; the calls made through tx are
+// instrumented as children of the InTx span
+func (service *InstrumentedService) InTx(ctx context.Context, fn func(tx LocationService) error) (err error) {
+	parent := trace.SpanContextFromContext(ctx)
+	ctx, call := service.begin(ctx, "InTx")
+	defer func() { call.end(err) }()
+	return service.next.InTx(ctx, func(tx LocationService) error {
+		wrapped := *service
+		wrapped.next = tx
+		wrapped.txSpan, wrapped.txParent = call.span, parent
+		return fn(&wrapped)
+	})
+}
+
+func (service *InstrumentedService) AddLocation(ctx context.Context, geoID string, geoLevel string, name string) (loc Location, err error) {
+	ctx, call := service.begin(ctx, "AddLocation", attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	loc, err = service.next.AddLocation(ctx, geoID, geoLevel, name)
+	call.span.SetAttributes(attrGeoID.String(loc.GeoID))
+	return loc, err
+}
+
+func (service *InstrumentedService) UpdateLocation(ctx context.Context, geoID string, name *string, geoLevel *string) (loc Location, err error) {
+	ctx, call := service.begin(ctx, "UpdateLocation", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	if geoLevel != nil {
+		call.span.SetAttributes(attrGeoLevel.String(*geoLevel))
+	}
+	return service.next.UpdateLocation(ctx, geoID, name, geoLevel)
+}
+
+func (service *InstrumentedService) AddLocationWithAttributes(ctx context.Context, geoID string, geoLevel string, name string, attributes map[string]any) (loc Location, err error) {
+	ctx, call := service.begin(ctx, "AddLocationWithAttributes", attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	return service.next.AddLocationWithAttributes(ctx, geoID, geoLevel, name, attributes)
+}
+
+func (service *InstrumentedService) AddChildLocation(ctx context.Context, parentGeoID string, geoLevel string, name string, attributes map[string]any) (loc Location, err error) {
+	ctx, call := service.begin(ctx, "AddChildLocation", attrParentID.String(parentGeoID), attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	loc, err = service.next.AddChildLocation(ctx, parentGeoID, geoLevel, name, attributes)
+	call.span.SetAttributes(attrGeoID.String(loc.GeoID))
+	return loc, err
+}
+
+func (service *InstrumentedService) AddGeoLevel(ctx context.Context, name string, rank *float64) (err error) {
+	ctx, call := service.begin(ctx, "AddGeoLevel", attrGeoLevel.String(name))
+	defer func() { call.end(err) }()
+	return service.next.AddGeoLevel(ctx, name, rank)
+}
+
+func (service *InstrumentedService) UpdateGeoLevel(ctx context.Context, name string, newName *string, newRank *float64) (err error) {
+	ctx, call := service.begin(ctx, "UpdateGeoLevel", attrGeoLevel.String(name))
+	defer func() { call.end(err) }()
+	return service.next.UpdateGeoLevel(ctx, name, newName, newRank)
+}
+
+func (service *InstrumentedService) PreviewGeoLevelUpdate(ctx context.Context, name string, newRank *float64) (violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "PreviewGeoLevelUpdate", attrGeoLevel.String(name))
+	defer func() { call.end(err) }()
+	return service.next.PreviewGeoLevelUpdate(ctx, name, newRank)
+}
+
+func (service *InstrumentedService) PreviewLocationUpdate(ctx context.Context, geoID string, geoLevel string) (violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "PreviewLocationUpdate", attrGeoID.String(geoID), attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	return service.next.PreviewLocationUpdate(ctx, geoID, geoLevel)
+}
+
+func (service *InstrumentedService) GetGeoLevel(ctx context.Context, name string) (level *GeoLevel, err error) {
+	ctx, call := service.begin(ctx, "GetGeoLevel", attrGeoLevel.String(name))
+	defer func() { call.end(err) }()
+	return service.next.GetGeoLevel(ctx, name)
+}
+
+func (service *InstrumentedService) ListGeoLevels(ctx context.Context) (levels []GeoLevel, err error) {
+	ctx, call := service.begin(ctx, "ListGeoLevels")
+	defer func() { call.end(err) }()
+	return service.next.ListGeoLevels(ctx)
+}
+
+func (service *InstrumentedService) GetGeoLevelsByPattern(ctx context.Context, name string) (levels []GeoLevel, err error) {
+	ctx, call := service.begin(ctx, "GetGeoLevelsByPattern")
+	defer func() { call.end(err) }()
+	return service.next.GetGeoLevelsByPattern(ctx, name)
+}
+
+func (service *InstrumentedService) DeleteGeoLevel(ctx context.Context, name string, reassignTo *string) (err error) {
+	ctx, call := service.begin(ctx, "DeleteGeoLevel", attrGeoLevel.String(name))
+	defer func() { call.end(err) }()
+	return service.next.DeleteGeoLevel(ctx, name, reassignTo)
+}
+
+func (service *InstrumentedService) AddLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevels []string) (violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "AddLevelRule", attrGeoLevel.String(childGeoLevel))
+	defer func() { call.end(err) }()
+	return service.next.AddLevelRule(ctx, childGeoLevel, parentGeoLevels)
+}
+
+func (service *InstrumentedService) RemoveLevelRule(ctx context.Context, childGeoLevel string, parentGeoLevel string) (err error) {
+	ctx, call := service.begin(ctx, "RemoveLevelRule", attrGeoLevel.String(childGeoLevel))
+	defer func() { call.end(err) }()
+	return service.next.RemoveLevelRule(ctx, childGeoLevel, parentGeoLevel)
+}
+
+func (service *InstrumentedService) ListLevelRules(ctx context.Context) (rules []LevelRule, err error) {
+	ctx, call := service.begin(ctx, "ListLevelRules")
+	defer func() { call.end(err) }()
+	return service.next.ListLevelRules(ctx)
+}
+
+func (service *InstrumentedService) GetLevelRuleViolations(ctx context.Context) (violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "GetLevelRuleViolations")
+	defer func() { call.end(err) }()
+	return service.next.GetLevelRuleViolations(ctx)
+}
+
+func (service *InstrumentedService) AddAliasToLocation(ctx context.Context, geoID string, name string) (err error) {
+	ctx, call := service.begin(ctx, "AddAliasToLocation", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.AddAliasToLocation(ctx, geoID, name)
+}
+
+func (service *InstrumentedService) RemoveAlias(ctx context.Context, geoID string, name string) (err error) {
+	ctx, call := service.begin(ctx, "RemoveAlias", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.RemoveAlias(ctx, geoID, name)
+}
+
+func (service *InstrumentedService) AddParent(ctx context.Context, geoID string, parentGeoID string) (err error) {
+	ctx, call := service.begin(ctx, "AddParent", attrGeoID.String(geoID), attrParentID.String(parentGeoID))
+	defer func() { call.end(err) }()
+	return service.next.AddParent(ctx, geoID, parentGeoID)
+}
+
+func (service *InstrumentedService) RemoveParent(ctx context.Context, geoID string, parentGeoID string) (err error) {
+	ctx, call := service.begin(ctx, "RemoveParent", attrGeoID.String(geoID), attrParentID.String(parentGeoID))
+	defer func() { call.end(err) }()
+	return service.next.RemoveParent(ctx, geoID, parentGeoID)
+}
+
+func (service *InstrumentedService) MoveLocation(ctx context.Context, geoID string, newParentGeoID string, checkDescendants bool) (violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "MoveLocation", attrGeoID.String(geoID), attrParentID.String(newParentGeoID))
+	defer func() { call.end(err) }()
+	return service.next.MoveLocation(ctx, geoID, newParentGeoID, checkDescendants)
+}
+
+func (service *InstrumentedService) AddChildren(ctx context.Context, geoID string, childGeoIDs []string) (err error) {
+	ctx, call := service.begin(ctx, "AddChildren", attrGeoID.String(geoID), attrGeoIDs.StringSlice(childGeoIDs))
+	defer func() { call.end(err) }()
+	return service.next.AddChildren(ctx, geoID, childGeoIDs)
+}
+
+func (service *InstrumentedService) RemoveChildren(ctx context.Context, geoID string, childGeoIDs []string) (err error) {
+	ctx, call := service.begin(ctx, "RemoveChildren", attrGeoID.String(geoID), attrGeoIDs.StringSlice(childGeoIDs))
+	defer func() { call.end(err) }()
+	return service.next.RemoveChildren(ctx, geoID, childGeoIDs)
+}
+
+func (service *InstrumentedService) DeleteLocation(ctx context.Context, geoID string) (err error) {
+	ctx, call := service.begin(ctx, "DeleteLocation", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.DeleteLocation(ctx, geoID)
+}
+
+func (service *InstrumentedService) MergeLocations(ctx context.Context, survivorGeoID string, duplicateGeoIDs []string) (survivor *Location, violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "MergeLocations", attrGeoID.String(survivorGeoID), attrGeoIDs.StringSlice(duplicateGeoIDs))
+	defer func() { call.end(err) }()
+	return service.next.MergeLocations(ctx, survivorGeoID, duplicateGeoIDs)
+}
+
+func (service *InstrumentedService) SplitLocation(ctx context.Context, geoID string, successorNames []string, childAssignments map[string]string) (successors []Location, err error) {
+	ctx, call := service.begin(ctx, "SplitLocation", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.SplitLocation(ctx, geoID, successorNames, childAssignments)
+}
+
+func (service *InstrumentedService) RestoreLocation(ctx context.Context, geoID string) (loc *Location, violations []RelationViolation, err error) {
+	ctx, call := service.begin(ctx, "RestoreLocation", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.RestoreLocation(ctx, geoID)
+}
+
+func (service *InstrumentedService) ListDeletedLocations(ctx context.Context) (deleted []DeletedLocation, err error) {
+	ctx, call := service.begin(ctx, "ListDeletedLocations")
+	defer func() { call.end(err) }()
+	return service.next.ListDeletedLocations(ctx)
+}
+
+func (service *InstrumentedService) PurgeDeleted(ctx context.Context, retention time.Duration) (result PurgeResult, err error) {
+	ctx, call := service.begin(ctx, "PurgeDeleted")
+	defer func() { call.end(err) }()
+	return service.next.PurgeDeleted(ctx, retention)
+}
+
+func (service *InstrumentedService) Check(ctx context.Context, repair bool) (report CheckReport, err error) {
+	ctx, call := service.begin(ctx, "Check")
+	defer func() { call.end(err) }()
+	return service.next.Check(ctx, repair)
+}
+
+func (service *InstrumentedService) ExportTree(ctx context.Context, rootGeoID *string) (tree *Tree, err error) {
+	ctx, call := service.begin(ctx, "ExportTree", optionalGeoID(rootGeoID)...)
+	defer func() { call.end(err) }()
+	return service.next.ExportTree(ctx, rootGeoID)
+}
+
+func (service *InstrumentedService) ImportTree(ctx context.Context, tree Tree) (result ImportResult, err error) {
+	ctx, call := service.begin(ctx, "ImportTree")
+	defer func() { call.end(err) }()
+	return service.next.ImportTree(ctx, tree)
+}
+
+func (service *InstrumentedService) DiffLive(ctx context.Context, tree Tree, rootGeoID *string) (changeSet ChangeSet, err error) {
+	ctx, call := service.begin(ctx, "DiffLive", optionalGeoID(rootGeoID)...)
+	defer func() { call.end(err) }()
+	return service.next.DiffLive(ctx, tree, rootGeoID)
+}
+
+func (service *InstrumentedService) ApplyChangeSet(ctx context.Context, changeSet ChangeSet) (err error) {
+	ctx, call := service.begin(ctx, "ApplyChangeSet")
+	defer func() { call.end(err) }()
+	return service.next.ApplyChangeSet(ctx, changeSet)
+}
+
+func (service *InstrumentedService) GetLocation(ctx context.Context, geoID string) (loc *Location, err error) {
+	ctx, call := service.begin(ctx, "GetLocation", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	loc, err = service.next.GetLocation(ctx, geoID)
+	if loc != nil {
+		call.span.SetAttributes(attrGeoLevel.String(loc.GeoLevel))
+	}
+	return loc, err
+}
+
+func (service *InstrumentedService) GetLocations(ctx context.Context, geoIDs []string) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "GetLocations", attrGeoIDs.StringSlice(geoIDs))
+	defer func() { call.end(err) }()
+	return service.next.GetLocations(ctx, geoIDs)
+}
+
+func (service *InstrumentedService) GetLocationsByPattern(ctx context.Context, name string, geoLevel *string) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "GetLocationsByPattern")
+	defer func() { call.end(err) }()
+	if geoLevel != nil {
+		call.span.SetAttributes(attrGeoLevel.String(*geoLevel))
+	}
+	return service.next.GetLocationsByPattern(ctx, name, geoLevel)
+}
+
+func (service *InstrumentedService) SearchLocations(ctx context.Context, pattern string, opts SearchOptions) (results []SearchResult, err error) {
+	ctx, call := service.begin(ctx, "SearchLocations")
+	defer func() { call.end(err) }()
+	if opts.GeoLevel != nil {
+		call.span.SetAttributes(attrGeoLevel.String(*opts.GeoLevel))
+	}
+	return service.next.SearchLocations(ctx, pattern, opts)
+}
+
+func (service *InstrumentedService) Autocomplete(ctx context.Context, prefix string, opts AutocompleteOptions) (suggestions []Suggestion, err error) {
+	ctx, call := service.begin(ctx, "Autocomplete", optionalGeoID(opts.WithinGeoID)...)
+	defer func() { call.end(err) }()
+	return service.next.Autocomplete(ctx, prefix, opts)
+}
+
+func (service *InstrumentedService) ListLocations(ctx context.Context, opts ListOptions) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "ListLocations")
+	defer func() { call.end(err) }()
+	if opts.GeoLevel != nil {
+		call.span.SetAttributes(attrGeoLevel.String(*opts.GeoLevel))
+	}
+	return service.next.ListLocations(ctx, opts)
+}
+
+func (service *InstrumentedService) GetAttributes(ctx context.Context, geoID string) (attributes map[string]any, err error) {
+	ctx, call := service.begin(ctx, "GetAttributes", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.GetAttributes(ctx, geoID)
+}
+
+func (service *InstrumentedService) SetAttributes(ctx context.Context, geoID string, attributes map[string]any) (result map[string]any, err error) {
+	ctx, call := service.begin(ctx, "SetAttributes", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.SetAttributes(ctx, geoID, attributes)
+}
+
+func (service *InstrumentedService) PatchAttributes(ctx context.Context, geoID string, patch map[string]any) (result map[string]any, err error) {
+	ctx, call := service.begin(ctx, "PatchAttributes", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.PatchAttributes(ctx, geoID, patch)
+}
+
+func (service *InstrumentedService) IndexAttribute(ctx context.Context, key string) (err error) {
+	ctx, call := service.begin(ctx, "IndexAttribute")
+	defer func() { call.end(err) }()
+	return service.next.IndexAttribute(ctx, key)
+}
+
+func (service *InstrumentedService) SetAttributeSchema(ctx context.Context, geoLevel string, schema AttributeSchema) (violations []AttributeViolation, err error) {
+	ctx, call := service.begin(ctx, "SetAttributeSchema", attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	return service.next.SetAttributeSchema(ctx, geoLevel, schema)
+}
+
+func (service *InstrumentedService) GetAttributeViolations(ctx context.Context) (violations []AttributeViolation, err error) {
+	ctx, call := service.begin(ctx, "GetAttributeViolations")
+	defer func() { call.end(err) }()
+	return service.next.GetAttributeViolations(ctx)
+}
+
+func (service *InstrumentedService) GetEffectiveAttributes(ctx context.Context, geoID string) (attributes map[string]EffectiveAttribute, err error) {
+	ctx, call := service.begin(ctx, "GetEffectiveAttributes", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.GetEffectiveAttributes(ctx, geoID)
+}
+
+func (service *InstrumentedService) GetAllParents(ctx context.Context, geoID string) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "GetAllParents", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.GetAllParents(ctx, geoID)
+}
+
+func (service *InstrumentedService) GetParentAtLevel(ctx context.Context, geoID string, geoLevel string) (loc *Location, err error) {
+	ctx, call := service.begin(ctx, "GetParentAtLevel", attrGeoID.String(geoID), attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	return service.next.GetParentAtLevel(ctx, geoID, geoLevel)
+}
+
+func (service *InstrumentedService) GetAllChildren(ctx context.Context, geoID string) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "GetAllChildren", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.GetAllChildren(ctx, geoID)
+}
+
+func (service *InstrumentedService) GetChildrenAtLevel(ctx context.Context, geoID string, geoLevel string) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "GetChildrenAtLevel", attrGeoID.String(geoID), attrGeoLevel.String(geoLevel))
+	defer func() { call.end(err) }()
+	return service.next.GetChildrenAtLevel(ctx, geoID, geoLevel)
+}
+
+func (service *InstrumentedService) GetSiblings(ctx context.Context, geoID string, parentLevel string) (locs []Location, err error) {
+	ctx, call := service.begin(ctx, "GetSiblings", attrGeoID.String(geoID), attrGeoLevel.String(parentLevel))
+	defer func() { call.end(err) }()
+	return service.next.GetSiblings(ctx, geoID, parentLevel)
+}
+
+func (service *InstrumentedService) GetNeighborhood(ctx context.Context, geoID string, parentLevel string) (neighborhood *Neighborhood, err error) {
+	ctx, call := service.begin(ctx, "GetNeighborhood", attrGeoID.String(geoID), attrGeoLevel.String(parentLevel))
+	defer func() { call.end(err) }()
+	return service.next.GetNeighborhood(ctx, geoID, parentLevel)
+}
+
+func (service *InstrumentedService) CommonAncestor(ctx context.Context, geoLevel *string, geoIDs ...string) (loc *Location, err error) {
+	ctx, call := service.begin(ctx, "CommonAncestor", attrGeoIDs.StringSlice(geoIDs))
+	defer func() { call.end(err) }()
+	if geoLevel != nil {
+		call.span.SetAttributes(attrGeoLevel.String(*geoLevel))
+	}
+	loc, err = service.next.CommonAncestor(ctx, geoLevel, geoIDs...)
+	if loc != nil {
+		call.span.SetAttributes(attrGeoID.String(loc.GeoID))
+	}
+	return loc, err
+}
+
+func (service *InstrumentedService) TreeDistance(ctx context.Context, geoID string, otherGeoID string) (distance int, err error) {
+	ctx, call := service.begin(ctx, "TreeDistance", attrGeoIDs.StringSlice([]string{geoID, otherGeoID}))
+	defer func() { call.end(err) }()
+	return service.next.TreeDistance(ctx, geoID, otherGeoID)
+}
+
+func (service *InstrumentedService) GetAncestry(ctx context.Context, geoIDs []string) (ancestry map[string]Ancestry, err error) {
+	ctx, call := service.begin(ctx, "GetAncestry", attrGeoIDs.StringSlice(geoIDs))
+	defer func() { call.end(err) }()
+	return service.next.GetAncestry(ctx, geoIDs)
+}
+
+func (service *InstrumentedService) AddLocalizedName(ctx context.Context, geoID string, language string, name string) (err error) {
+	ctx, call := service.begin(ctx, "AddLocalizedName", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.AddLocalizedName(ctx, geoID, language, name)
+}
+
+func (service *InstrumentedService) GetPath(ctx context.Context, geoID string, opts PathOptions) (path string, err error) {
+	ctx, call := service.begin(ctx, "GetPath", attrGeoID.String(geoID))
+	defer func() { call.end(err) }()
+	return service.next.GetPath(ctx, geoID, opts)
+}
+
+func (service *InstrumentedService) ResolvePath(ctx context.Context, path string) (loc *Location, err error) {
+	ctx, call := service.begin(ctx, "ResolvePath")
+	defer func() { call.end(err) }()
+	loc, err = service.next.ResolvePath(ctx, path)
+	if loc != nil {
+		call.span.SetAttributes(attrGeoID.String(loc.GeoID))
+	}
+	return loc, err
+}
+
+func (service *InstrumentedService) ResolvePlace(ctx context.Context, text string, limit int) (candidates []PlaceCandidate, err error) {
+	ctx, call := service.begin(ctx, "ResolvePlace")
+	defer func() { call.end(err) }()
+	return service.next.ResolvePlace(ctx, text, limit)
+}
@@ -0,0 +1,208 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubService answers GetLocation and InTx; other calls panic
+type stubService struct {
+	LocationService
+	locations map[string]Location
+}
+
+func (stub *stubService) GetLocation(_ context.Context, geoID string) (*Location, error) {
+	loc, ok := stub.locations[geoID]
+	if !ok {
+		return nil, postgres.ErrLocationNotFound
+	}
+	return &loc, nil
+}
+
+func (stub *stubService) InTx(_ context.Context, fn func(tx LocationService) error) error {
+	return fn(stub)
+}
+
+func setupTelemetry(t *testing.T, next LocationService) (*InstrumentedService, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	service, err := NewInstrumentedService(next,
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))))
+	require.NoError(t, err)
+	return service, spans, reader
+}
+
+// collectMetric returns the data points of the named metric
+func collectMetric(t *testing.T, reader *sdkmetric.ManualReader, name string) metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m.Data
+			}
+		}
+	}
+	t.Fatalf("metric %s not recorded", name)
+	return nil
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestInstrumentedService(t *testing.T) {
+	ctx := context.Background()
+	kochi := Location{GeoID: "8f3c8bb6-60a4-4b8e-9d3a-3f1f3b9b1e11", GeoLevel: "CITY", Name: "Kochi"}
+	service, spans, reader := setupTelemetry(t, &stubService{locations: map[string]Location{kochi.GeoID: kochi}})
+
+	_, err := service.GetLocation(ctx, kochi.GeoID)
+	require.NoError(t, err)
+	_, err = service.GetLocation(ctx, "e4b7f3a2-0000-4000-8000-000000000000")
+	require.ErrorIs(t, err, postgres.ErrLocationNotFound)
+
+	t.Run("spans carry geo_id, geo level and error", func(t *testing.T) {
+		ended := spans.Ended()
+		require.Len(t, ended, 2)
+		assert.Equal(t, "LocationService.GetLocation", ended[0].Name())
+
+		geoID, ok := spanAttr(ended[0], attrGeoID)
+		require.True(t, ok)
+		assert.Equal(t, kochi.GeoID, geoID.AsString())
+		level, ok := spanAttr(ended[0], attrGeoLevel)
+		require.True(t, ok)
+		assert.Equal(t, "CITY", level.AsString())
+		assert.Equal(t, codes.Unset, ended[0].Status().Code)
+
+		assert.Equal(t, codes.Error, ended[1].Status().Code)
+		class, ok := spanAttr(ended[1], attrErrorType)
+		require.True(t, ok)
+		assert.Equal(t, postgres.ErrorClassNotFound, class.AsString())
+	})
+
+	t.Run("metrics count calls by method and error class", func(t *testing.T) {
+		sum, ok := collectMetric(t, reader, "location.service.requests").(metricdata.Sum[int64])
+		require.True(t, ok)
+		counts := map[string]int64{}
+		for _, point := range sum.DataPoints {
+			class, _ := point.Attributes.Value(attrErrorType)
+			method, _ := point.Attributes.Value(attrMethod)
+			counts[method.AsString()+"/"+class.AsString()] += point.Value
+		}
+		assert.Equal(t, map[string]int64{"GetLocation/": 1, "GetLocation/not_found": 1}, counts)
+
+		histogram, ok := collectMetric(t, reader, "location.service.duration").(metricdata.Histogram[float64])
+		require.True(t, ok)
+		var total uint64
+		for _, point := range histogram.DataPoints {
+			total += point.Count
+		}
+		assert.Equal(t, uint64(2), total)
+	})
+
+	t.Run("calls in a transaction are children of the InTx span", func(t *testing.T) {
+		errAbort := errors.New("abort")
+		err := service.InTx(ctx, func(tx LocationService) error {
+			if _, err := tx.GetLocation(ctx, kochi.GeoID); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		ended := spans.Ended()
+		require.Len(t, ended, 4)
+		inner, outer := ended[2], ended[3]
+		assert.Equal(t, "LocationService.InTx", outer.Name())
+		assert.Equal(t, outer.SpanContext().SpanID(), inner.Parent().SpanID())
+		class, _ := spanAttr(outer, attrErrorType)
+		assert.Equal(t, postgres.ErrorClassInternal, class.AsString())
+	})
+}
+
+func TestErrorClass(t *testing.T) {
+	_, invalidID := uuidFromString("not-a-uuid")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"invalid geo_id", invalidID, postgres.ErrorClassInvalid},
+		{"not found", postgres.ErrLocationNotFound, postgres.ErrorClassNotFound},
+		{"version conflict", postgres.ErrVersionConflict, postgres.ErrorClassConflict},
+		{"hierarchy violation", &postgres.HierarchyViolationError{}, postgres.ErrorClassInvalid},
+		{"canceled", context.Canceled, postgres.ErrorClassCanceled},
+		{"permission denied", fmt.Errorf("%w: admin cannot AddParent", ErrPermissionDenied), errorClassPermissionDenied},
+		{"other", errors.New("boom"), postgres.ErrorClassInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, errorClass(tt.err))
+		})
+	}
+}
+
+func TestInstrumentedService_StoreSpans(t *testing.T) {
+	ctx := context.Background()
+	base := setupTestDB(t)
+	service, spans, reader := setupTelemetry(t, base)
+	storeSpans := tracetest.NewSpanRecorder()
+	storeReader := sdkmetric.NewManualReader()
+	require.NoError(t, postgres.Instrument(base.db.DB,
+		sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(storeSpans), sdktrace.WithSpanProcessor(spans)),
+		sdkmetric.NewMeterProvider(sdkmetric.WithReader(storeReader))))
+
+	createTestGeoLevel(t, base, "CITY", nil)
+	spans.Reset()
+	storeSpans.Reset()
+
+	loc, err := service.AddLocation(ctx, "", "CITY", "Kochi")
+	require.NoError(t, err)
+
+	var serviceSpan sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		if span.Name() == "LocationService.AddLocation" {
+			serviceSpan = span
+		}
+	}
+	require.NotNil(t, serviceSpan)
+	geoID, _ := spanAttr(serviceSpan, attrGeoID)
+	assert.Equal(t, loc.GeoID, geoID.AsString())
+
+	queries := storeSpans.Ended()
+	require.NotEmpty(t, queries)
+	tables := map[string]bool{}
+	for _, query := range queries {
+		assert.Equal(t, serviceSpan.SpanContext().TraceID(), query.SpanContext().TraceID())
+		table, _ := spanAttr(query, "db.collection.name")
+		tables[table.AsString()] = true
+	}
+	assert.True(t, tables["locations"])
+	assert.True(t, tables["name_maps"])
+
+	_, ok := collectMetric(t, storeReader, "location.store.queries").(metricdata.Sum[int64])
+	assert.True(t, ok)
+	_, ok = collectMetric(t, reader, "location.service.requests").(metricdata.Sum[int64])
+	assert.True(t, ok)
+}
@@ -0,0 +1,19 @@
+package location
+
+import (
+	"context"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// WithTenant returns a context scoping all service calls to the given tenant namespace.
+// Geo levels, locations, names and relations of one tenant are invisible to the others.
+// Calls with a context without a tenant operate on postgres.DefaultTenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return postgres.WithTenant(ctx, tenant)
+}
+
+// TenantFromContext returns the tenant namespace carried by the context
+func TenantFromContext(ctx context.Context) string {
+	return postgres.TenantFromContext(ctx)
+}
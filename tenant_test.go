@@ -0,0 +1,50 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_Tenants(t *testing.T) {
+	service := setupTestDB(t)
+	north := WithTenant(context.Background(), "bu-north")
+	south := WithTenant(context.Background(), "bu-south")
+
+	require.NoError(t, service.AddGeoLevel(north, "TERRITORY", float64Ptr(1.0)))
+	require.NoError(t, service.AddGeoLevel(north, "AREA", float64Ptr(2.0)))
+	require.NoError(t, service.AddGeoLevel(south, "TERRITORY", float64Ptr(1.0)))
+
+	territory, err := service.AddLocation(north, "", "TERRITORY", "Kerala")
+	require.NoError(t, err)
+	area, err := service.AddLocation(north, "", "AREA", "Kochi")
+	require.NoError(t, err)
+	southTerritory, err := service.AddLocation(south, "", "TERRITORY", "Kerala")
+	require.NoError(t, err)
+
+	require.NoError(t, service.AddParent(north, area.GeoID, territory.GeoID))
+
+	err = service.AddParent(north, area.GeoID, southTerritory.GeoID)
+	assert.ErrorIs(t, err, postgres.ErrLocationNotFound, "cross-tenant parent must be rejected")
+
+	_, err = service.GetLocation(south, territory.GeoID)
+	assert.ErrorIs(t, err, postgres.ErrLocationNotFound)
+
+	locs, err := service.GetLocationsByPattern(south, "Kerala", nil)
+	require.NoError(t, err)
+	require.Len(t, locs, 1)
+	assert.Equal(t, southTerritory.GeoID, locs[0].GeoID)
+
+	parents, err := service.GetAllParents(north, area.GeoID)
+	require.NoError(t, err)
+	require.Len(t, parents, 1)
+	assert.Equal(t, territory.GeoID, parents[0].GeoID)
+
+	levels, err := service.ListGeoLevels(south)
+	require.NoError(t, err)
+	require.Len(t, levels, 1)
+	assert.Equal(t, int64(1), levels[0].LocationCount)
+}
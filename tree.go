@@ -0,0 +1,255 @@
+package location
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/xaults/platform/location/postgres"
+)
+
+// ExportTree exports the hierarchy as a nested document. With rootGeoID, only the subtree
+// rooted at that location is exported; otherwise every location without a parent is a root.
+// Children are ordered by geo level rank and name.
+func (service *ServiceOnPostgres) ExportTree(ctx context.Context, rootGeoID *string) (*Tree, error) {
+	var rootID *uuid.UUID
+	if rootGeoID != nil {
+		id, err := uuidFromString(*rootGeoID)
+		if err != nil {
+			return nil, err
+		}
+		rootID = &id
+	}
+	snapshot, err := service.db.LoadSnapshot(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+	return buildTree(snapshot, rootID), nil
+}
+
+// ImportTree imports a nested document in one transaction. Missing geo levels are created
+// with their rank and attribute schema. A node whose geo_id exists is matched and left
+// unchanged; other nodes are created with their geo_id (or a new one if empty), primary name,
+// aliases (localized names keep their language) and attributes. Missing relations between a
+// node and its children are created and validated as by AddParent.
+func (service *ServiceOnPostgres) ImportTree(ctx context.Context, tree Tree) (ImportResult, error) {
+	var result ImportResult
+	err := service.transaction(ctx, func(store *postgres.Store) error {
+		importer := &treeImporter{
+			store: store,
+			seen:  make(map[uuid.UUID]bool),
+		}
+		if err := importer.importTree(ctx, tree); err != nil {
+			return err
+		}
+		result = importer.result
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+type treeImporter struct {
+	store  *postgres.Store
+	seen   map[uuid.UUID]bool
+	result ImportResult
+}
+
+func (importer *treeImporter) importTree(ctx context.Context, tree Tree) error {
+	for _, level := range tree.GeoLevels {
+		_, err := importer.store.GetGeoLevelByName(ctx, level.Name)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, postgres.ErrGeoLevelNotFound) {
+			return err
+		}
+		if _, err := importer.store.InsertGeoLevel(ctx, level.Name, level.Rank); err != nil {
+			return err
+		}
+		if len(level.AttributeSchema) > 0 {
+			if _, err := importer.store.SetAttributeSchema(ctx, level.Name, toStoreSchema(level.AttributeSchema)); err != nil {
+				return fmt.Errorf("failed to import attribute schema of %q: %w", level.Name, err)
+			}
+		}
+		importer.result.GeoLevels++
+	}
+
+	for _, root := range tree.Roots {
+		if err := importer.importNode(ctx, root, uuid.Nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (importer *treeImporter) importNode(ctx context.Context, node TreeNode, parentID uuid.UUID) error {
+	id, err := importer.matchOrCreate(ctx, node)
+	if err != nil {
+		return err
+	}
+
+	if parentID != uuid.Nil {
+		parents, err := importer.store.GetParents(ctx, id)
+		if err != nil {
+			return err
+		}
+		exists := slices.ContainsFunc(parents, func(rel postgres.Relation) bool {
+			return rel.ParentID == parentID
+		})
+		if !exists {
+			if _, err := importer.store.InsertRelation(ctx, parentID, id); err != nil {
+				return fmt.Errorf("failed to import relation of %q: %w", node.Name, err)
+			}
+			importer.result.Relations++
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := importer.importNode(ctx, child, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchOrCreate returns the id of the existing location with the node's geo_id,
+// or creates the location of the node
+func (importer *treeImporter) matchOrCreate(ctx context.Context, node TreeNode) (uuid.UUID, error) {
+	var id uuid.UUID
+	if node.GeoID != "" {
+		var err error
+		id, err = uuidFromString(node.GeoID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		loc, err := importer.store.GetLocation(ctx, id)
+		if err == nil {
+			if !importer.seen[loc.Id] {
+				importer.seen[loc.Id] = true
+				importer.result.Matched++
+			}
+			return loc.Id, nil
+		}
+		if !errors.Is(err, postgres.ErrLocationNotFound) {
+			return uuid.Nil, err
+		}
+	}
+
+	loc, err := importer.store.InsertLocationWithAttributes(ctx, id, node.GeoLevel, node.Name, node.Attributes)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to import location %q: %w", node.Name, err)
+	}
+	var aliases []string
+	for _, alias := range node.Aliases {
+		language, localized := node.AliasLanguages[alias]
+		if !localized {
+			aliases = append(aliases, alias)
+			continue
+		}
+		if err := importer.store.InsertLocalizedName(ctx, loc.Id, language, alias); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to import localized name %q of %q: %w", alias, node.Name, err)
+		}
+	}
+	if err := importer.store.InsertNames(ctx, loc.Id, aliases); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to import aliases of %q: %w", node.Name, err)
+	}
+	importer.seen[loc.Id] = true
+	importer.result.Created++
+	return loc.Id, nil
+}
+
+// buildTree nests the snapshot's locations under their parents
+func buildTree(snapshot *postgres.Snapshot, rootID *uuid.UUID) *Tree {
+	ranks := make(map[string]*float64, len(snapshot.GeoLevels))
+	for _, level := range snapshot.GeoLevels {
+		ranks[level.Name] = level.Rank
+	}
+
+	locations := make(map[uuid.UUID]postgres.LocationWithNames, len(snapshot.Locations))
+	counts := make(map[string]int64)
+	for _, loc := range snapshot.Locations {
+		locations[loc.Id] = loc
+		counts[loc.GeoLevel]++
+	}
+
+	children := make(map[uuid.UUID][]uuid.UUID)
+	hasParent := make(map[uuid.UUID]bool)
+	for _, rel := range snapshot.Relations {
+		children[rel.ParentID] = append(children[rel.ParentID], rel.ChildID)
+		hasParent[rel.ChildID] = true
+	}
+
+	byRankAndName := func(a, b uuid.UUID) int {
+		la, lb := locations[a], locations[b]
+		ra, rb := ranks[la.GeoLevel], ranks[lb.GeoLevel]
+		switch {
+		case ra == nil && rb != nil:
+			return 1
+		case ra != nil && rb == nil:
+			return -1
+		case ra != nil && rb != nil && *ra != *rb:
+			return cmp.Compare(*ra, *rb)
+		}
+		return cmp.Or(cmp.Compare(la.Name, lb.Name), cmp.Compare(a.String(), b.String()))
+	}
+
+	// path guards against cycles in inconsistent data
+	path := make(map[uuid.UUID]bool)
+	var build func(id uuid.UUID) TreeNode
+	build = func(id uuid.UUID) TreeNode {
+		loc := locations[id]
+		node := TreeNode{
+			GeoID:          loc.Id.String(),
+			GeoLevel:       loc.GeoLevel,
+			Name:           loc.Name,
+			Aliases:        loc.Aliases,
+			AliasLanguages: snapshot.Languages[id],
+			Attributes:     loc.Attributes,
+		}
+		path[id] = true
+		childIDs := slices.Clone(children[id])
+		slices.SortFunc(childIDs, byRankAndName)
+		for _, childID := range childIDs {
+			if !path[childID] {
+				node.Children = append(node.Children, build(childID))
+			}
+		}
+		delete(path, id)
+		return node
+	}
+
+	var rootIDs []uuid.UUID
+	if rootID != nil {
+		rootIDs = []uuid.UUID{*rootID}
+	} else {
+		for _, loc := range snapshot.Locations {
+			if !hasParent[loc.Id] {
+				rootIDs = append(rootIDs, loc.Id)
+			}
+		}
+		slices.SortFunc(rootIDs, byRankAndName)
+	}
+
+	tree := &Tree{
+		GeoLevels: make([]GeoLevel, 0, len(snapshot.GeoLevels)),
+		Roots:     make([]TreeNode, 0, len(rootIDs)),
+	}
+	for _, level := range snapshot.GeoLevels {
+		tree.GeoLevels = append(tree.GeoLevels, GeoLevel{
+			Name:            level.Name,
+			Rank:            level.Rank,
+			LocationCount:   counts[level.Name],
+			AttributeSchema: fromStoreSchema(level.AttributeSchema),
+		})
+	}
+	for _, id := range rootIDs {
+		tree.Roots = append(tree.Roots, build(id))
+	}
+	return tree
+}
@@ -0,0 +1,188 @@
+package location
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceOnPostgres_ExportTree(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	require.NoError(t, service.AddAliasToLocation(ctx, locs["India"].GeoID, "Bharat"))
+	tamilNadu := createTestLocation(t, service, "STATE", "Tamil Nadu")
+	require.NoError(t, service.AddParent(ctx, tamilNadu.GeoID, locs["India"].GeoID))
+
+	t.Run("whole hierarchy", func(t *testing.T) {
+		tree, err := service.ExportTree(ctx, nil)
+		require.NoError(t, err)
+
+		require.Len(t, tree.Roots, 1)
+		india := tree.Roots[0]
+		assert.Equal(t, locs["India"].GeoID, india.GeoID)
+		assert.Equal(t, "COUNTRY", india.GeoLevel)
+		assert.Equal(t, []string{"Bharat"}, india.Aliases)
+
+		require.Len(t, india.Children, 2)
+		assert.Equal(t, "Kerala", india.Children[0].Name)
+		assert.Equal(t, "Tamil Nadu", india.Children[1].Name)
+		assert.Equal(t, "Kochi", india.Children[0].Children[0].Children[0].Name)
+
+		require.Len(t, tree.GeoLevels, 4)
+		assert.Equal(t, "STATE", tree.GeoLevels[1].Name)
+		assert.Equal(t, int64(2), tree.GeoLevels[1].LocationCount)
+	})
+
+	t.Run("subtree", func(t *testing.T) {
+		root := locs["Kerala"].GeoID
+		tree, err := service.ExportTree(ctx, &root)
+		require.NoError(t, err)
+		require.Len(t, tree.Roots, 1)
+		assert.Equal(t, "Kerala", tree.Roots[0].Name)
+		assert.Equal(t, "Ernakulam", tree.Roots[0].Children[0].Name)
+		assert.Equal(t, int64(0), tree.GeoLevels[0].LocationCount, "COUNTRY is above the subtree")
+	})
+
+	t.Run("invalid root", func(t *testing.T) {
+		root := "not-a-uuid"
+		_, err := service.ExportTree(ctx, &root)
+		assert.Error(t, err)
+	})
+}
+
+func TestServiceOnPostgres_ImportTree(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+	tree, err := service.ExportTree(ctx, nil)
+	require.NoError(t, err)
+
+	t.Run("re-import matches every location", func(t *testing.T) {
+		result, err := service.ImportTree(ctx, *tree)
+		require.NoError(t, err)
+		assert.Equal(t, ImportResult{Matched: 4}, result)
+	})
+
+	t.Run("import into another tenant", func(t *testing.T) {
+		// Locations of another tenant need new geo_ids
+		data, err := json.Marshal(tree)
+		require.NoError(t, err)
+		var fixture Tree
+		require.NoError(t, json.Unmarshal(data, &fixture))
+		var clearIDs func(nodes []TreeNode)
+		clearIDs = func(nodes []TreeNode) {
+			for i := range nodes {
+				nodes[i].GeoID = ""
+				clearIDs(nodes[i].Children)
+			}
+		}
+		clearIDs(fixture.Roots)
+
+		staging := WithTenant(ctx, "staging")
+		result, err := service.ImportTree(staging, fixture)
+		require.NoError(t, err)
+		assert.Equal(t, ImportResult{GeoLevels: 4, Created: 4, Relations: 3}, result)
+
+		imported, err := service.ExportTree(staging, nil)
+		require.NoError(t, err)
+		require.Len(t, imported.Roots, 1)
+		assert.Equal(t, "India", imported.Roots[0].Name)
+		assert.NotEqual(t, locs["India"].GeoID, imported.Roots[0].GeoID)
+		assert.Equal(t, "Kochi", imported.Roots[0].Children[0].Children[0].Children[0].Name)
+	})
+
+	t.Run("new nodes under existing locations", func(t *testing.T) {
+		doc := Tree{Roots: []TreeNode{{
+			GeoID: locs["Kerala"].GeoID,
+			Children: []TreeNode{{
+				GeoLevel: "DISTRICT",
+				Name:     "Thrissur",
+				Aliases:  []string{"Trichur"},
+			}},
+		}}}
+		result, err := service.ImportTree(ctx, doc)
+		require.NoError(t, err)
+		assert.Equal(t, ImportResult{Created: 1, Matched: 1, Relations: 1}, result)
+
+		children, err := service.GetChildrenAtLevel(ctx, locs["Kerala"].GeoID, "DISTRICT")
+		require.NoError(t, err)
+		assert.Len(t, children, 2)
+	})
+
+	t.Run("attributes and schemas round-trip", func(t *testing.T) {
+		_, err := service.SetAttributeSchema(ctx, "CITY", AttributeSchema{"pin": {Type: AttributeString, Required: true}})
+		require.NoError(t, err)
+		_, err = service.SetAttributes(ctx, locs["Kochi"].GeoID, map[string]any{"pin": "682001"})
+		require.NoError(t, err)
+		doc, err := service.ExportTree(ctx, stringPtr(locs["India"].GeoID))
+		require.NoError(t, err)
+		var clearIDs func(nodes []TreeNode)
+		clearIDs = func(nodes []TreeNode) {
+			for i := range nodes {
+				nodes[i].GeoID = ""
+				clearIDs(nodes[i].Children)
+			}
+		}
+		clearIDs(doc.Roots)
+
+		qa := WithTenant(ctx, "qa")
+		_, err = service.ImportTree(qa, *doc)
+		require.NoError(t, err)
+		level, err := service.GetGeoLevel(qa, "CITY")
+		require.NoError(t, err)
+		assert.Equal(t, AttributeSchema{"pin": {Type: AttributeString, Required: true}}, level.AttributeSchema)
+		kochi, err := service.ResolvePath(qa, "India/Kerala/Ernakulam/Kochi")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"pin": "682001"}, kochi.Attributes)
+	})
+
+	t.Run("localized names keep their language", func(t *testing.T) {
+		require.NoError(t, service.AddLocalizedName(ctx, locs["Kerala"].GeoID, "ml", "Keralam"))
+		doc, err := service.ExportTree(ctx, stringPtr(locs["India"].GeoID))
+		require.NoError(t, err)
+		kerala := doc.Roots[0].Children[0]
+		assert.Equal(t, map[string]string{"Keralam": "ml"}, kerala.AliasLanguages)
+		var clearIDs func(nodes []TreeNode)
+		clearIDs = func(nodes []TreeNode) {
+			for i := range nodes {
+				nodes[i].GeoID = ""
+				clearIDs(nodes[i].Children)
+			}
+		}
+		clearIDs(doc.Roots)
+
+		i18n := WithTenant(ctx, "i18n")
+		_, err = service.ImportTree(i18n, *doc)
+		require.NoError(t, err)
+		imported, err := service.ExportTree(i18n, nil)
+		require.NoError(t, err)
+		require.Len(t, imported.Roots, 1)
+		assert.Equal(t, map[string]string{"Keralam": "ml"}, imported.Roots[0].Children[0].AliasLanguages)
+
+		changes, err := service.DiffLive(i18n, *imported, nil)
+		require.NoError(t, err)
+		assert.Empty(t, changes.Changes)
+	})
+
+	t.Run("invalid relation rolls back the import", func(t *testing.T) {
+		doc := Tree{Roots: []TreeNode{{
+			GeoID: locs["Kochi"].GeoID,
+			Children: []TreeNode{{
+				GeoLevel: "STATE",
+				Name:     "Upside Down",
+			}},
+		}}}
+		_, err := service.ImportTree(ctx, doc)
+		assert.Error(t, err)
+
+		matches, err := service.GetLocationsByPattern(ctx, "Upside Down", nil)
+		require.NoError(t, err)
+		assert.Empty(t, matches)
+	})
+}
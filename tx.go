@@ -0,0 +1,26 @@
+package location
+
+import (
+	"context"
+
+	"github.com/xaults/platform/location/postgres"
+	"gorm.io/gorm"
+)
+
+// InTx runs fn with a service whose calls all share one database transaction: the
+// transaction commits if fn returns nil and rolls back otherwise. Calls made through tx,
+// including nested InTx calls, reuse the outer transaction; a failing call rolls back only
+// its own changes (to a savepoint), so fn decides whether the group fails.
+func (service *ServiceOnPostgres) InTx(ctx context.Context, fn func(tx LocationService) error) error {
+	return service.transaction(ctx, func(store *postgres.Store) error {
+		return fn(&ServiceOnPostgres{db: *store})
+	})
+}
+
+// transaction runs fn on a store in a transaction, or in a savepoint of the enclosing
+// transaction when called within InTx
+func (service *ServiceOnPostgres) transaction(ctx context.Context, fn func(store *postgres.Store) error) error {
+	return service.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&postgres.Store{DB: tx})
+	})
+}
@@ -0,0 +1,212 @@
+package location
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_InTx(t *testing.T) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	t.Run("commits all calls", func(t *testing.T) {
+		service := setupTestDB(t)
+		locs := createTestHierarchy(t, service)
+
+		var created Location
+		err := service.InTx(ctx, func(tx LocationService) error {
+			var err error
+			created, err = tx.AddLocation(ctx, "", "CITY", "Aluva")
+			if err != nil {
+				return err
+			}
+			if err := tx.AddAliasToLocation(ctx, created.GeoID, "Alwaye"); err != nil {
+				return err
+			}
+			return tx.AddParent(ctx, created.GeoID, locs["Ernakulam"].GeoID)
+		})
+		require.NoError(t, err)
+
+		got, err := service.GetLocation(ctx, created.GeoID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alwaye"}, got.Aliases)
+		parent, err := service.GetParentAtLevel(ctx, created.GeoID, "DISTRICT")
+		require.NoError(t, err)
+		assert.Equal(t, locs["Ernakulam"].GeoID, parent.GeoID)
+	})
+
+	t.Run("rolls back all calls when fn fails", func(t *testing.T) {
+		service := setupTestDB(t)
+		locs := createTestHierarchy(t, service)
+
+		var created Location
+		err := service.InTx(ctx, func(tx LocationService) error {
+			var err error
+			created, err = tx.AddLocation(ctx, "", "CITY", "Aluva")
+			if err != nil {
+				return err
+			}
+			if err := tx.AddAliasToLocation(ctx, locs["Kochi"].GeoID, "Cochin"); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = service.GetLocation(ctx, created.GeoID)
+		assert.ErrorIs(t, err, postgres.ErrLocationNotFound)
+		kochi, err := service.GetLocation(ctx, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Empty(t, kochi.Aliases)
+	})
+
+	t.Run("rolls back when a failing call is returned", func(t *testing.T) {
+		service := setupTestDB(t)
+		locs := createTestHierarchy(t, service)
+
+		err := service.InTx(ctx, func(tx LocationService) error {
+			if err := tx.AddAliasToLocation(ctx, locs["Kochi"].GeoID, "Cochin"); err != nil {
+				return err
+			}
+			// A country cannot be the child of a city
+			return tx.AddParent(ctx, locs["India"].GeoID, locs["Kochi"].GeoID)
+		})
+		assert.ErrorIs(t, err, postgres.ErrInvalidHierarchy)
+
+		kochi, err := service.GetLocation(ctx, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Empty(t, kochi.Aliases)
+	})
+
+	t.Run("nested calls reuse the outer transaction", func(t *testing.T) {
+		service := setupTestDB(t)
+		locs := createTestHierarchy(t, service)
+
+		err := service.InTx(ctx, func(tx LocationService) error {
+			if err := tx.AddAliasToLocation(ctx, locs["Kochi"].GeoID, "Cochin"); err != nil {
+				return err
+			}
+			// The inner transaction sees the alias and its own failure stays local
+			innerErr := tx.InTx(ctx, func(inner LocationService) error {
+				kochi, err := inner.GetLocation(ctx, locs["Kochi"].GeoID)
+				if err != nil {
+					return err
+				}
+				assert.Equal(t, []string{"Cochin"}, kochi.Aliases)
+				if err := inner.AddAliasToLocation(ctx, locs["Kerala"].GeoID, "Keralam"); err != nil {
+					return err
+				}
+				return errAbort
+			})
+			assert.ErrorIs(t, innerErr, errAbort)
+			return tx.AddAliasToLocation(ctx, locs["Ernakulam"].GeoID, "Ernakulam District")
+		})
+		require.NoError(t, err)
+
+		kochi, err := service.GetLocation(ctx, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Cochin"}, kochi.Aliases)
+		kerala, err := service.GetLocation(ctx, locs["Kerala"].GeoID)
+		require.NoError(t, err)
+		assert.Empty(t, kerala.Aliases)
+		ernakulam, err := service.GetLocation(ctx, locs["Ernakulam"].GeoID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Ernakulam District"}, ernakulam.Aliases)
+	})
+
+	t.Run("rolls back when the outer fn fails after nested calls", func(t *testing.T) {
+		service := setupTestDB(t)
+		locs := createTestHierarchy(t, service)
+
+		err := service.InTx(ctx, func(tx LocationService) error {
+			if err := tx.InTx(ctx, func(inner LocationService) error {
+				return inner.AddAliasToLocation(ctx, locs["Kochi"].GeoID, "Cochin")
+			}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		kochi, err := service.GetLocation(ctx, locs["Kochi"].GeoID)
+		require.NoError(t, err)
+		assert.Empty(t, kochi.Aliases)
+	})
+
+	t.Run("rolls back geo level, relation and deletion changes", func(t *testing.T) {
+		service := setupTestDB(t)
+		locs := createTestHierarchy(t, service)
+		createTestGeoLevel(t, service, "VILLAGE", float64Ptr(5.0))
+		thrissur := createTestLocation(t, service, "DISTRICT", "Thrissur")
+		require.NoError(t, service.AddParent(ctx, thrissur.GeoID, locs["Kerala"].GeoID))
+		_, err := service.AddLevelRule(ctx, "CITY", []string{"DISTRICT"})
+		require.NoError(t, err)
+
+		err = service.InTx(ctx, func(tx LocationService) error {
+			if err := tx.UpdateGeoLevel(ctx, "STATE", stringPtr("PROVINCE"), nil); err != nil {
+				return err
+			}
+			if err := tx.DeleteGeoLevel(ctx, "VILLAGE", nil); err != nil {
+				return err
+			}
+			if err := tx.RemoveLevelRule(ctx, "CITY", "DISTRICT"); err != nil {
+				return err
+			}
+			if _, err := tx.MoveLocation(ctx, locs["Kochi"].GeoID, thrissur.GeoID, false); err != nil {
+				return err
+			}
+			if err := tx.RemoveParent(ctx, locs["Ernakulam"].GeoID, locs["Kerala"].GeoID); err != nil {
+				return err
+			}
+			if err := tx.RemoveChildren(ctx, locs["India"].GeoID, []string{locs["Kerala"].GeoID}); err != nil {
+				return err
+			}
+			if err := tx.DeleteLocation(ctx, locs["Kochi"].GeoID); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = service.GetGeoLevel(ctx, "STATE")
+		assert.NoError(t, err)
+		_, err = service.GetGeoLevel(ctx, "VILLAGE")
+		assert.NoError(t, err)
+		rules, err := service.ListLevelRules(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []LevelRule{{ChildGeoLevel: "CITY", ParentGeoLevels: []string{"DISTRICT"}}}, rules)
+
+		parent, err := service.GetParentAtLevel(ctx, locs["Kochi"].GeoID, "DISTRICT")
+		require.NoError(t, err)
+		assert.Equal(t, locs["Ernakulam"].GeoID, parent.GeoID)
+		parent, err = service.GetParentAtLevel(ctx, locs["Ernakulam"].GeoID, "STATE")
+		require.NoError(t, err)
+		assert.Equal(t, locs["Kerala"].GeoID, parent.GeoID)
+		parent, err = service.GetParentAtLevel(ctx, locs["Kerala"].GeoID, "COUNTRY")
+		require.NoError(t, err)
+		assert.Equal(t, locs["India"].GeoID, parent.GeoID)
+	})
+
+	t.Run("a failing geo level change rolls back only itself", func(t *testing.T) {
+		service := setupTestDB(t)
+		createTestHierarchy(t, service)
+		createTestGeoLevel(t, service, "VILLAGE", float64Ptr(5.0))
+
+		err := service.InTx(ctx, func(tx LocationService) error {
+			// COUNTRY is still in use
+			assert.Error(t, tx.DeleteGeoLevel(ctx, "COUNTRY", nil))
+			return tx.UpdateGeoLevel(ctx, "VILLAGE", stringPtr("HAMLET"), nil)
+		})
+		require.NoError(t, err)
+
+		_, err = service.GetGeoLevel(ctx, "COUNTRY")
+		assert.NoError(t, err)
+		_, err = service.GetGeoLevel(ctx, "HAMLET")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,43 @@
+package location
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// PreviewGeoLevelUpdate is the dry run of a rank change with UpdateGeoLevel.
+// It returns the existing relations the new rank would violate, without changing anything.
+func (service *ServiceOnPostgres) PreviewGeoLevelUpdate(ctx context.Context, name string, newRank *float64) ([]RelationViolation, error) {
+	conflicts, err := service.db.ValidateGeoLevelUpdate(ctx, name, newRank)
+	if err != nil {
+		return nil, err
+	}
+	return relationConflictViolations(conflicts), nil
+}
+
+// PreviewLocationUpdate is the dry run of a geo level change with UpdateLocation.
+// It returns the relations of the location the new geo level would violate, without changing anything.
+func (service *ServiceOnPostgres) PreviewLocationUpdate(ctx context.Context, geoID string, geoLevel string) ([]RelationViolation, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := service.db.ValidateLocationUpdate(ctx, id, geoLevel)
+	if err != nil {
+		return nil, err
+	}
+	return relationConflictViolations(conflicts), nil
+}
+
+// HierarchyViolations returns the relations listed by an error of UpdateGeoLevel,
+// UpdateLocation or DeleteGeoLevel that was rejected for breaking the hierarchy rules,
+// or nil for any other error
+func HierarchyViolations(err error) []RelationViolation {
+	var violationErr *postgres.HierarchyViolationError
+	if !errors.As(err, &violationErr) {
+		return nil
+	}
+	return relationConflictViolations(violationErr.Conflicts)
+}
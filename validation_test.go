@@ -0,0 +1,66 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_PreviewGeoLevelUpdate(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+
+	violations, err := service.PreviewGeoLevelUpdate(ctx, "STATE", float64Ptr(3.5))
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, RelationViolation{
+		ParentGeoID:    locs["Kerala"].GeoID,
+		ParentGeoLevel: "STATE",
+		ChildGeoID:     locs["Ernakulam"].GeoID,
+		ChildGeoLevel:  "DISTRICT",
+		Reason:         postgres.ErrInvalidHierarchy.Error(),
+	}, violations[0])
+
+	level, err := service.GetGeoLevel(ctx, "STATE")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, *level.Rank, "a preview does not change the rank")
+
+	err = service.UpdateGeoLevel(ctx, "STATE", nil, float64Ptr(3.5))
+	assert.ErrorIs(t, err, postgres.ErrHierarchyViolation)
+	assert.Equal(t, violations, HierarchyViolations(err))
+
+	violations, err = service.PreviewGeoLevelUpdate(ctx, "STATE", float64Ptr(1.5))
+	require.NoError(t, err)
+	assert.Empty(t, violations)
+	assert.NoError(t, service.UpdateGeoLevel(ctx, "STATE", nil, float64Ptr(1.5)))
+}
+
+func TestServiceOnPostgres_PreviewLocationUpdate(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	locs := createTestHierarchy(t, service)
+
+	violations, err := service.PreviewLocationUpdate(ctx, locs["Kochi"].GeoID, "STATE")
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	assert.Equal(t, locs["Ernakulam"].GeoID, violations[0].ParentGeoID)
+	assert.Equal(t, "STATE", violations[0].ChildGeoLevel)
+
+	_, err = service.UpdateLocation(ctx, locs["Kochi"].GeoID, nil, stringPtr("STATE"))
+	assert.ErrorIs(t, err, postgres.ErrHierarchyViolation)
+	assert.Len(t, HierarchyViolations(err), 1)
+
+	loc, err := service.GetLocation(ctx, locs["Kochi"].GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, "CITY", loc.GeoLevel)
+
+	_, err = service.PreviewLocationUpdate(ctx, "not-a-uuid", "STATE")
+	assert.Error(t, err)
+	assert.Nil(t, HierarchyViolations(err))
+}
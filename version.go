@@ -0,0 +1,20 @@
+package location
+
+import (
+	"context"
+
+	"github.com/xaults/platform/location/postgres"
+)
+
+// WithExpectedVersion returns a context in which a change to the location fails with
+// postgres.ErrVersionConflict unless the location is still at the given version, as
+// returned by GetLocation. The check applies to every mutating call that changes the
+// location, its names or its relations; wrap the context again to guard several locations.
+// An invalid geoID returns ErrInvalidGeoID rather than a context without the check.
+func WithExpectedVersion(ctx context.Context, geoID string, version int64) (context.Context, error) {
+	id, err := uuidFromString(geoID)
+	if err != nil {
+		return ctx, err
+	}
+	return postgres.WithExpectedVersion(ctx, id, version), nil
+}
@@ -0,0 +1,47 @@
+package location
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xaults/platform/location/postgres"
+)
+
+func TestServiceOnPostgres_ExpectedVersion(t *testing.T) {
+	service := setupTestDB(t)
+	ctx := context.Background()
+
+	loc := createTestLocation(t, service, "COUNTRY", "India")
+	assert.Equal(t, int64(1), loc.Version)
+
+	// Two admins read the same version
+	first, err := service.GetLocation(ctx, loc.GeoID)
+	require.NoError(t, err)
+	second, err := service.GetLocation(ctx, loc.GeoID)
+	require.NoError(t, err)
+
+	firstCtx, err := WithExpectedVersion(ctx, first.GeoID, first.Version)
+	require.NoError(t, err)
+	updated, err := service.UpdateLocation(firstCtx, first.GeoID, stringPtr("Bharat"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, first.Version+1, updated.Version)
+
+	secondCtx, err := WithExpectedVersion(ctx, second.GeoID, second.Version)
+	require.NoError(t, err)
+	_, err = service.UpdateLocation(secondCtx, second.GeoID, stringPtr("Hindustan"), nil)
+	assert.ErrorIs(t, err, postgres.ErrVersionConflict)
+
+	err = service.AddAliasToLocation(secondCtx, second.GeoID, "Hindustan")
+	assert.ErrorIs(t, err, postgres.ErrVersionConflict)
+
+	_, err = WithExpectedVersion(ctx, "not-a-geo-id", 1)
+	assert.ErrorIs(t, err, ErrInvalidGeoID)
+
+	got, err := service.GetLocation(ctx, loc.GeoID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bharat", got.Name)
+	assert.Empty(t, got.Aliases)
+	assert.Equal(t, updated.Version, got.Version)
+}